@@ -0,0 +1,31 @@
+package command
+
+import (
+	"github.com/mprot/mprotc/internal/cli"
+)
+
+var buildCommand = cli.Command{
+	Usage: "[options]",
+	Help:  "Run all targets of a project file.",
+
+	Options: func(opts *cli.Opts) {
+		opts.AddString("--project <file>", cli.ProjectFilename, "Specify the project file.")
+		opts.AddInt("--jobs <n>", 0, "Specify the number of files parsed and generated concurrently (default: number of CPUs).")
+		opts.AddBool("--dryrun", false, "Print the names of the generated files only.")
+		opts.AddBool("--check", false, "Check that the generated files are up to date without writing them.")
+		opts.AddBool("-v, --verbose", false, "Print whether each generated file was created, updated, or unchanged.")
+	},
+
+	Run: func(opts *cli.Opts, args []string) error {
+		p, err := cli.ReadProject(opts.String("project"))
+		if err != nil {
+			return err
+		}
+		return commands.Build(p, cli.BuildOptions{
+			Jobs:    opts.Int("jobs"),
+			DryRun:  opts.Bool("dryrun"),
+			Check:   opts.Bool("check"),
+			Verbose: opts.Bool("verbose"),
+		})
+	},
+}
@@ -0,0 +1,90 @@
+// Package command implements the mprotc command line interface. It allows
+// building a custom mprotc binary with additional generators registered by
+// generator.Register:
+//
+//	func main() {
+//		generator.Register("mylang", generator.Factory{...})
+//		command.Main()
+//	}
+package command
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/mprot/mprotc/generator"
+	"github.com/mprot/mprotc/internal/cli"
+)
+
+var commands = cli.Commands{
+	"compat":     compatCommand,
+	"descriptor": descriptorCommand,
+	"fmt":        formatCommand,
+	"lsp":        lspCommand,
+	"go": cli.Command{
+		Options: func(opts *cli.Opts) {
+			opts.AddString("--import-root", "", "Import root path for all schema imports.")
+			opts.AddBool("--scoped-enums", false, "Scope the enumerators of the generated enums.")
+			opts.AddBool("--unwrap-unions", false, "Unwrap union types of the generated struct fields.")
+			opts.AddBool("--typeid", false, "Generate methods for retrieving a type id.")
+			opts.AddBool("--registry", false, "Register the generated types at the runtime type registry.")
+		},
+
+		Generator: func(opts *cli.Opts) *generator.Generator {
+			return generator.NewGolang(generator.GolangOptions{
+				ImportRoot:   opts.String("import-root"),
+				ScopedEnums:  opts.Bool("scoped-enums"),
+				UnwrapUnions: opts.Bool("unwrap-unions"),
+				TypeID:       opts.Bool("typeid"),
+				Registry:     opts.Bool("registry"),
+			})
+		},
+	},
+	"js": cli.Command{
+		Options: func(opts *cli.Opts) {
+			opts.AddBool("--typedecls", false, "Generate type declarations in a separate .d.ts file.")
+		},
+
+		Generator: func(opts *cli.Opts) *generator.Generator {
+			return generator.NewJavascript(generator.JavascriptOptions{
+				TypeDeclarations: opts.Bool("typedecls"),
+			})
+		},
+	},
+	"template": cli.Command{
+		Options: func(opts *cli.Opts) {
+			opts.AddString("--tmpl <file>", "", "Specify the text/template file.")
+			opts.AddString("--ext <ext>", "", "Specify the file extension of the outputs per schema file (default: derived from the template filename).")
+			opts.AddString("--aggregate <file>", "", "Execute the template once for all schema files and write the given output file.")
+		},
+
+		Generator: func(opts *cli.Opts) *generator.Generator {
+			return generator.NewTemplate(generator.TemplateOptions{
+				Template:  opts.String("tmpl"),
+				Extension: opts.String("ext"),
+				Aggregate: opts.String("aggregate"),
+			})
+		},
+	},
+}
+
+func init() {
+	commands["build"] = buildCommand // refers to the commands
+}
+
+// Main runs the command line interface with the arguments of the process. If
+// the command fails, the process exits with a non-zero status.
+func Main() {
+	if len(os.Args) < 2 {
+		commands.Exec("help", nil)
+		os.Exit(0)
+	}
+
+	err := commands.Exec(os.Args[1], os.Args[2:])
+	if err != nil {
+		if err != cli.ErrReported {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,36 @@
+package command
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mprot/mprotc/generator"
+	"github.com/mprot/mprotc/internal/cli"
+	"github.com/mprot/mprotc/schema"
+)
+
+func TestGolangOptions(t *testing.T) {
+	s, err := schema.ParseSources(map[string]string{
+		"a.mprot": "package a\n\nstruct S {\n\tU U \"1\"\n}\n\nunion U {\n\tstring \"1\"\n}\n",
+	})
+	if err != nil {
+		t.Fatalf("unexpected parse error: %v", err)
+	}
+
+	cmd := commands["go"]
+	opts := cli.NewOpts()
+	cmd.Options(opts)
+	if err := opts.Set("unwrap-unions", "true"); err != nil {
+		t.Fatal(err)
+	}
+
+	gen := cmd.Generator(opts)
+	if err := gen.GenerateSchema(s, generator.Options{OutputDirectory: t.TempDir()}); err != nil {
+		t.Fatalf("unexpected generate error: %v", err)
+	}
+	gen.IterateContents(func(filename string, content []byte) {
+		if !strings.Contains(string(content), "U interface{} // U") {
+			t.Errorf("union field of %s not unwrapped:\n%s", filename, content)
+		}
+	})
+}
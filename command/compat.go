@@ -0,0 +1,73 @@
+package command
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/mprot/mprotc/internal/cli"
+	"github.com/mprot/mprotc/internal/compat"
+	"github.com/mprot/mprotc/schema"
+)
+
+var compatCommand = cli.Command{
+	Usage: "[options] <old-root> <new-root> [schema-file ...]",
+	Help:  "Report wire-incompatible changes between two schema versions.",
+
+	Options: func(opts *cli.Opts) {
+		opts.AddString("--allow <file>", "", "Specify a file with acknowledged changes (one change key per line).")
+		opts.AddStrings("-I, --include <dir>", "Add a directory to search for imported schema files (repeatable).")
+	},
+
+	Run: func(opts *cli.Opts, args []string) error {
+		if len(args) < 2 {
+			return fmt.Errorf("missing schema root directories")
+		}
+
+		globPatterns := args[2:]
+		if len(globPatterns) == 0 {
+			globPatterns = []string{"**/*.mprot"}
+		}
+
+		includeDirs := opts.Strings("include")
+		old, err := schema.Parse(args[0], globPatterns, includeDirs...)
+		if err != nil {
+			return err
+		}
+		new, err := schema.Parse(args[1], globPatterns, includeDirs...)
+		if err != nil {
+			return err
+		}
+
+		allowed := compat.Allowlist{}
+		if filename := opts.String("allow"); filename != "" {
+			f, err := os.Open(filename)
+			if err != nil {
+				return err
+			}
+			allowed, err = compat.ReadAllowlist(f)
+			f.Close()
+			if err != nil {
+				return err
+			}
+		}
+
+		var errs schema.ErrorList
+		for _, c := range compat.Compare(old, new) {
+			if allowed.Allows(c) {
+				fmt.Fprintf(os.Stderr, "%s: %s (allowed)\n", c.Pos, c.Text)
+				continue
+			}
+			errs = append(errs, schema.Error{
+				Pos:  c.Pos,
+				End:  c.Pos,
+				Code: schema.ErrorCode(c.Kind),
+				Text: c.Text + " [" + c.Key() + "]",
+			})
+		}
+
+		if len(errs) != 0 {
+			return errs
+		}
+		return nil
+	},
+}
@@ -0,0 +1,69 @@
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/mprot/mprotc/internal/cli"
+	"github.com/mprot/mprotc/schema"
+)
+
+var descriptorCommand = cli.Command{
+	Usage: "[options] [schema-file ...]",
+	Help:  "Write the compiled schema descriptor.",
+
+	Options: func(opts *cli.Opts) {
+		opts.AddString("--root <path>", ".", "Specify the root path of the mprot schema files.")
+		opts.AddStrings("-I, --include <dir>", "Add a directory to search for imported schema files (repeatable).")
+		opts.AddString("--out <file>", "", "Specify the output file (default stdout).")
+		opts.AddString("--format <format>", "", "Specify the descriptor format (msgpack, json). Defaults to json for .json output files, msgpack otherwise.")
+		opts.AddBool("--deprecated", false, "Include the deprecated fields in the descriptor.")
+	},
+
+	Run: func(opts *cli.Opts, args []string) error {
+		globPatterns := args
+		if len(globPatterns) == 0 {
+			globPatterns = []string{"**/*.mprot"}
+		}
+
+		outFile := opts.String("out")
+		format := opts.String("format")
+		if format == "" {
+			format = "msgpack"
+			if filepath.Ext(outFile) == ".json" {
+				format = "json"
+			}
+		}
+
+		s, err := schema.Parse(opts.String("root"), globPatterns, opts.Strings("include")...)
+		if err != nil {
+			return err
+		}
+		if !opts.Bool("deprecated") {
+			s.RemoveDeprecated()
+		}
+
+		d := schema.NewDescriptor(s)
+		var data []byte
+		switch format {
+		case "msgpack":
+			data, err = d.MarshalMsgpack()
+		case "json":
+			data, err = json.MarshalIndent(d, "", "\t")
+			data = append(data, '\n')
+		default:
+			return fmt.Errorf("unknown descriptor format %q", format)
+		}
+		if err != nil {
+			return err
+		}
+
+		if outFile == "" {
+			_, err = os.Stdout.Write(data)
+			return err
+		}
+		return os.WriteFile(outFile, data, 0666)
+	},
+}
@@ -0,0 +1,110 @@
+package command
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/mprot/mprotc/internal/cli"
+	"github.com/mprot/mprotc/internal/diff"
+	"github.com/mprot/mprotc/schema"
+)
+
+var formatCommand = cli.Command{
+	Usage: "[options] [schema-file|directory ...]",
+	Help:  "Format schema files in the canonical layout.",
+
+	Options: func(opts *cli.Opts) {
+		opts.AddBool("-w", false, "Write the result to the source file instead of stdout.")
+		opts.AddBool("-d", false, "Print a diff instead of the formatted source.")
+	},
+
+	Run: func(opts *cli.Opts, args []string) error {
+		write, printDiff := opts.Bool("w"), opts.Bool("d")
+
+		if len(args) == 0 {
+			src, err := io.ReadAll(os.Stdin)
+			if err != nil {
+				return err
+			}
+			return formatSource("<standard input>", src, false, printDiff)
+		}
+
+		var errs schema.ErrorList
+		for _, arg := range args {
+			filenames, err := schemaFiles(arg)
+			if err != nil {
+				return err
+			}
+
+			for _, filename := range filenames {
+				src, err := os.ReadFile(filename)
+				if err != nil {
+					return err
+				}
+				err = formatSource(filename, src, write, printDiff)
+				if el, ok := err.(schema.ErrorList); ok {
+					errs = append(errs, el...)
+				} else if err != nil {
+					return err
+				}
+			}
+		}
+
+		if len(errs) != 0 {
+			return errs
+		}
+		return nil
+	},
+}
+
+// schemaFiles returns the schema files of the given directory and all of its
+// subdirectories, which are not listed in a .mprotignore file. If the given
+// path is not a directory, the path itself is returned.
+func schemaFiles(pathname string) ([]string, error) {
+	info, err := os.Stat(pathname)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{pathname}, nil
+	}
+
+	filenames, err := schema.Glob(os.DirFS(pathname), []string{"**/*.mprot"})
+	if err != nil {
+		return nil, err
+	}
+	for i, filename := range filenames {
+		filenames[i] = filepath.Join(pathname, filepath.FromSlash(filename))
+	}
+	return filenames, nil
+}
+
+func formatSource(filename string, src []byte, write bool, printDiff bool) error {
+	res, err := schema.Format(filename, src)
+	if err != nil {
+		return err
+	}
+
+	if printDiff {
+		os.Stdout.Write(diff.Unified(filename+".orig", filename, src, res))
+	}
+
+	switch {
+	case write:
+		if bytes.Equal(src, res) {
+			return nil
+		}
+		info, err := os.Stat(filename)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(filename, res, info.Mode().Perm())
+	case !printDiff:
+		_, err = os.Stdout.Write(res)
+		return err
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,23 @@
+package command
+
+import (
+	"os"
+
+	"github.com/mprot/mprotc/internal/cli"
+	"github.com/mprot/mprotc/internal/lsp"
+)
+
+var lspCommand = cli.Command{
+	Usage: "[options]",
+	Help:  "Run the language server for schema files over stdio.",
+
+	Options: func(opts *cli.Opts) {
+		opts.AddStrings("-I, --include <dir>", "Add a directory to search for imported schema files (repeatable).")
+	},
+
+	Run: func(opts *cli.Opts, args []string) error {
+		return lsp.Serve(os.Stdin, os.Stdout, lsp.Options{
+			IncludeDirs: opts.Strings("include"),
+		})
+	},
+}
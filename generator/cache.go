@@ -0,0 +1,279 @@
+package generator
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/mprot/mprotc/internal/gen"
+	"github.com/mprot/mprotc/schema"
+)
+
+const (
+	cacheFilename = ".mprotc-cache"
+	cacheVersion  = 1 // version of the cache file format
+	modulePath    = "github.com/mprot/mprotc"
+)
+
+// buildVersion identifies the build of mprotc. It is part of the cache keys,
+// so that the outputs are regenerated after mprotc was updated. Released
+// versions are identified by their module version, development builds by the
+// hash of the executable.
+var buildVersion = sync.OnceValue(func() string {
+	if info, ok := debug.ReadBuildInfo(); ok {
+		mod := &info.Main
+		if mod.Path != modulePath {
+			mod = nil
+			for _, dep := range info.Deps {
+				if dep.Path == modulePath {
+					mod = dep
+					break
+				}
+			}
+		}
+		if mod != nil && mod.Replace == nil && mod.Version != "" && mod.Version != "(devel)" && !strings.HasSuffix(mod.Version, "+dirty") {
+			return mod.Version
+		}
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		return "unknown"
+	}
+	content, err := os.ReadFile(executable)
+	if err != nil {
+		return "unknown"
+	}
+	return "devel " + contentHash(content)
+})
+
+// cache holds the keys and the generated outputs of all schema files of the
+// previous run. A schema file, whose key did not change and whose outputs
+// are unchanged on disk, is neither regenerated nor rewritten.
+type cache struct {
+	Version int                   `json:"version"`
+	Files   map[string]cacheEntry `json:"files"` // slash-separated schema filename => entry
+}
+
+type cacheEntry struct {
+	Key     string            `json:"key"`
+	Outputs map[string]string `json:"outputs"` // slash-separated output filename => content hash
+}
+
+// readCache reads the cache of the given output directory. An empty cache is
+// returned, if the cache file does not exist or is invalid.
+func readCache(outDir string) *cache {
+	c := &cache{Version: cacheVersion, Files: make(map[string]cacheEntry)}
+
+	data, err := os.ReadFile(filepath.Join(outDir, cacheFilename))
+	if err != nil {
+		return c
+	}
+
+	var stored cache
+	if err := json.Unmarshal(data, &stored); err != nil || stored.Version != cacheVersion || stored.Files == nil {
+		return c
+	}
+	return &stored
+}
+
+func (c *cache) write(outDir string) error {
+	data, err := json.MarshalIndent(c, "", "\t")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(outDir, cacheFilename), append(data, '\n'), 0666)
+}
+
+// upToDate reports whether the entry has the given key and all of its outputs
+// exist unchanged in the output directory.
+func (e cacheEntry) upToDate(key string, outDir string) bool {
+	if e.Key != key || len(e.Outputs) == 0 {
+		return false
+	}
+	for filename, hash := range e.Outputs {
+		content, err := os.ReadFile(filepath.Join(outDir, filepath.FromSlash(filename)))
+		if err != nil || contentHash(content) != hash {
+			return false
+		}
+	}
+	return true
+}
+
+// cacheState holds the cache during a single run.
+type cacheState struct {
+	outDir  string
+	cache   *cache
+	updated map[string]string // schema filename => key of the regenerated files
+}
+
+// filter returns the files of the schema, which have to be regenerated. The
+// key of a schema file is derived from the build of mprotc, the generator,
+// its options, and the contents of the schema file and all of its transitive
+// imports.
+func (cs *cacheState) filter(s schema.Schema, fingerprint string, opts *Options, fsys fs.FS) schema.Schema {
+	h := hasher{
+		fsys:        fsys,
+		includeDirs: opts.IncludeDirectories,
+		hashes:      make(map[string]string),
+	}
+
+	changed := make(schema.Schema, 0, len(s))
+	for _, f := range s {
+		name := f.Name
+		if fsys == nil {
+			name = filepath.Join(opts.RootDirectory, f.Name)
+		} else {
+			name = filepath.ToSlash(name)
+		}
+
+		sha := sha256.New()
+		io.WriteString(sha, buildVersion()+"\x00"+fingerprint+"\x00")
+		if opts.RemoveDeprecated {
+			io.WriteString(sha, "remove-deprecated\x00")
+		}
+		io.WriteString(sha, filepath.ToSlash(f.Name)+"\x00"+h.hash(name, f.Imports))
+		key := hex.EncodeToString(sha.Sum(nil))
+
+		filename := filepath.ToSlash(f.Name)
+		if cs.cache.Files[filename].upToDate(key, cs.outDir) {
+			continue
+		}
+		cs.updated[filename] = key
+		changed = append(changed, f)
+	}
+
+	// drop the entries of removed schema files
+	filenames := make(map[string]struct{}, len(s))
+	for _, f := range s {
+		filenames[filepath.ToSlash(f.Name)] = struct{}{}
+	}
+	for filename := range cs.cache.Files {
+		if _, has := filenames[filename]; !has {
+			delete(cs.cache.Files, filename)
+		}
+	}
+	return changed
+}
+
+// update stores the outputs of the regenerated files in the cache and writes
+// the cache file.
+func (cs *cacheState) update(w *gen.FileWriter) error {
+	absOutDir, err := filepath.Abs(cs.outDir)
+	if err != nil {
+		return err
+	}
+
+	for filename, key := range cs.updated {
+		cs.cache.Files[filename] = cacheEntry{Key: key, Outputs: make(map[string]string)}
+	}
+
+	var walkErr error
+	w.WalkContents(func(filename string, content []byte) {
+		entry, has := cs.cache.Files[filepath.ToSlash(w.Source(filename))]
+		if !has {
+			return
+		}
+		rel, err := filepath.Rel(absOutDir, filename)
+		if err != nil {
+			walkErr = err
+			return
+		}
+		entry.Outputs[filepath.ToSlash(rel)] = contentHash(content)
+	})
+	if walkErr != nil {
+		return walkErr
+	}
+	return cs.cache.write(cs.outDir)
+}
+
+func contentHash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// hasher computes the hashes of schema files including all of their
+// transitive imports.
+type hasher struct {
+	fsys        fs.FS // nil for the local file system
+	includeDirs []string
+	hashes      map[string]string // file location => hash
+}
+
+// hash returns the hash of the schema file at the given location. If the
+// imports of the file are not known, the file is parsed to resolve them.
+func (h *hasher) hash(location string, imports map[string]*schema.Import) string {
+	if hash, has := h.hashes[location]; has {
+		return hash
+	}
+	h.hashes[location] = "" // break import cycles
+
+	sha := sha256.New()
+	content, err := h.readFile(location)
+	if err != nil {
+		io.WriteString(sha, "error: "+err.Error())
+	}
+	sha.Write(content)
+
+	if imports == nil && err == nil {
+		imports = h.imports(location)
+	}
+	names := make([]string, 0, len(imports))
+	for name := range imports {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		imp := imports[name]
+		io.WriteString(sha, "\x00"+imp.Path+"\x00"+h.hash(h.importLocation(location, imp), nil))
+	}
+
+	hash := hex.EncodeToString(sha.Sum(nil))
+	h.hashes[location] = hash
+	return hash
+}
+
+func (h *hasher) readFile(location string) ([]byte, error) {
+	if h.fsys == nil {
+		return os.ReadFile(location)
+	}
+	return fs.ReadFile(h.fsys, location)
+}
+
+func (h *hasher) imports(location string) map[string]*schema.Import {
+	config := schema.Config{IncludeDirs: h.includeDirs, Jobs: 1}
+
+	var s schema.Schema
+	if h.fsys == nil {
+		s, _ = config.Parse(filepath.Dir(location), []string{filepath.Base(location)})
+	} else {
+		s, _ = config.ParseFS(h.fsys, []string{location})
+	}
+	if len(s) == 0 {
+		return nil
+	}
+	return s[0].Imports
+}
+
+func (h *hasher) importLocation(from string, imp *schema.Import) string {
+	if h.fsys == nil {
+		if imp.IncludeDir != "" {
+			return filepath.Join(imp.IncludeDir, filepath.FromSlash(imp.Path))
+		}
+		return filepath.Join(filepath.Dir(from), filepath.FromSlash(imp.Path))
+	}
+
+	if imp.IncludeDir != "" {
+		return path.Join(filepath.ToSlash(imp.IncludeDir), imp.Path)
+	}
+	return path.Join(path.Dir(from), imp.Path)
+}
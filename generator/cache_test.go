@@ -0,0 +1,99 @@
+package generator
+
+import (
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"testing/fstest"
+)
+
+func TestCacheInvalidation(t *testing.T) {
+	outDir := t.TempDir()
+	fsys := fstest.MapFS{
+		"a.mprot":     {Data: []byte("package a\n\nimport \"b.mprot\"\n\nstruct A {\n\tB b.B \"1\"\n}\n")},
+		"b.mprot":     {Data: []byte("package a\n\nimport \"sub/c.mprot\"\n\nstruct B {\n\tC c.C \"1\"\n}\n")},
+		"sub/c.mprot": {Data: []byte("package c\n\nstruct C {\n\tX int \"1\"\n}\n")},
+		"d.mprot":     {Data: []byte("package a\n\nstruct D {\n\tX int \"1\"\n}\n")},
+	}
+
+	// generate runs the generator with the cache and returns the names of the
+	// regenerated files
+	generate := func(g *Generator) []string {
+		t.Helper()
+		err := g.Generate(Options{
+			FileSystem:      fsys,
+			GlobPatterns:    []string{"**/*.mprot"},
+			OutputDirectory: outDir,
+			UseCache:        true,
+		})
+		if err != nil {
+			t.Fatalf("unexpected generate error: %v", err)
+		}
+		if err := g.Dump(); err != nil {
+			t.Fatalf("unexpected dump error: %v", err)
+		}
+
+		var names []string
+		g.IterateFiles(func(filename string) {
+			rel, err := filepath.Rel(outDir, filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			names = append(names, filepath.ToSlash(rel))
+		})
+		sort.Strings(names)
+		return names
+	}
+
+	tests := []struct {
+		change   func()
+		gen      *Generator
+		expected []string
+	}{
+		{
+			gen:      NewGolang(GolangOptions{}),
+			expected: []string{"a.go", "b.go", "d.go", "sub/c.go"},
+		},
+		{
+			gen:      NewGolang(GolangOptions{}),
+			expected: nil,
+		},
+		{ // transitive import changed
+			change: func() {
+				fsys["sub/c.mprot"] = &fstest.MapFile{Data: []byte("package c\n\nstruct C {\n\tY int \"2\"\n}\n")}
+			},
+			gen:      NewGolang(GolangOptions{}),
+			expected: []string{"a.go", "b.go", "sub/c.go"},
+		},
+		{ // direct import changed
+			change: func() {
+				fsys["b.mprot"] = &fstest.MapFile{Data: []byte("package a\n\nimport \"sub/c.mprot\"\n\nstruct B {\n\tC *c.C \"1\"\n}\n")}
+			},
+			gen:      NewGolang(GolangOptions{}),
+			expected: []string{"a.go", "b.go"},
+		},
+		{ // generator options changed
+			gen:      NewGolang(GolangOptions{TypeID: true}),
+			expected: []string{"a.go", "b.go", "d.go", "sub/c.go"},
+		},
+		{ // mprotc build changed
+			change: func() {
+				prev := buildVersion
+				buildVersion = func() string { return "other" }
+				t.Cleanup(func() { buildVersion = prev })
+			},
+			gen:      NewGolang(GolangOptions{TypeID: true}),
+			expected: []string{"a.go", "b.go", "d.go", "sub/c.go"},
+		},
+	}
+
+	for i, test := range tests {
+		if test.change != nil {
+			test.change()
+		}
+		if names := generate(test.gen); !reflect.DeepEqual(names, test.expected) {
+			t.Errorf("unexpected files generated in run %d: %v (expected %v)", i, names, test.expected)
+		}
+	}
+}
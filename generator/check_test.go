@@ -0,0 +1,77 @@
+package generator
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	rootDir := t.TempDir()
+	outDir := t.TempDir()
+	writeTestFiles(t, rootDir, map[string]string{
+		"a.mprot": "package a\n\nconst A = 1\n",
+		"b.mprot": "package a\n\nconst B = 2\n",
+		"c.mprot": "package a\n\nconst C = 3\n",
+	})
+
+	check := func() ([]string, string) {
+		g := NewGolang(GolangOptions{})
+		if err := g.Generate(Options{RootDirectory: rootDir, GlobPatterns: []string{"*.mprot"}, OutputDirectory: outDir}); err != nil {
+			t.Fatalf("unexpected generate error: %v", err)
+		}
+		var buf bytes.Buffer
+		outdated, err := g.Check(&buf)
+		if err != nil {
+			t.Fatalf("unexpected check error: %v", err)
+		}
+		return relativeNames(t, outDir, outdated), buf.String()
+	}
+
+	// nothing generated yet, all files are missing
+	if outdated, _ := check(); !reflect.DeepEqual(outdated, []string{"a.go", "b.go", "c.go"}) {
+		t.Errorf("unexpected out-of-date files without outputs: %v", outdated)
+	}
+	if entries, _ := os.ReadDir(outDir); len(entries) != 0 {
+		t.Errorf("unexpected files written by check: %v", entries)
+	}
+
+	g := NewGolang(GolangOptions{})
+	if err := g.Generate(Options{RootDirectory: rootDir, GlobPatterns: []string{"*.mprot"}, OutputDirectory: outDir}); err != nil {
+		t.Fatalf("unexpected generate error: %v", err)
+	}
+	if err := g.Dump(); err != nil {
+		t.Fatalf("unexpected dump error: %v", err)
+	}
+	if outdated, d := check(); len(outdated) != 0 || d != "" {
+		t.Errorf("unexpected out-of-date files: %v\n%s", outdated, d)
+	}
+
+	// modify one output and remove another one
+	writeTestFiles(t, outDir, map[string]string{"b.go": "package a\n"})
+	if err := os.Remove(filepath.Join(outDir, "c.go")); err != nil {
+		t.Fatal(err)
+	}
+
+	outdated, d := check()
+	if !reflect.DeepEqual(outdated, []string{"b.go", "c.go"}) {
+		t.Errorf("unexpected out-of-date files: %v", outdated)
+	}
+	for _, expected := range []string{
+		"b.go\n+++ ",                   // changed file
+		"--- " + os.DevNull + "\n+++ ", // missing file
+		"c.go\n@@",                     // header of the missing file
+		"\n+const B = 2\n",             // added line of the changed file
+		"\n+const C = 3\n",             // added line of the missing file
+	} {
+		if !strings.Contains(d, expected) {
+			t.Errorf("expected %q in diff:\n%s", expected, d)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(outDir, "c.go")); !os.IsNotExist(err) {
+		t.Errorf("missing file written by check: %v", err)
+	}
+}
@@ -1,41 +1,90 @@
 package generator
 
 import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mprot/mprotc/internal/diff"
 	"github.com/mprot/mprotc/internal/gen"
 	"github.com/mprot/mprotc/internal/gen/golang"
 	"github.com/mprot/mprotc/internal/gen/js"
-	"github.com/mprot/mprotc/internal/schema"
+	"github.com/mprot/mprotc/schema"
 )
 
 type internalGenerator interface {
 	Generate(w *gen.FileWriter, s schema.Schema)
 }
 
+// validatingGenerator is implemented by generators, which check the complete
+// schema before any of its files is generated.
+type validatingGenerator interface {
+	validate(s schema.Schema) error
+}
+
 type Generator struct {
-	newGen     func(opts *Options) internalGenerator
-	fileWriter *gen.FileWriter
+	newGen      func(opts *Options) internalGenerator
+	fingerprint string    // identifies the generator and its options
+	noCache     bool      // generates outputs depending on all schema files
+	pruneExts   []string  // extensions of the files removed by Prune, nil to never prune
+	runKey      string    // identifies the run in the manifest
+	manifest    *manifest // nil if the generator does not prune
+	fileWriter  *gen.FileWriter
+	outDir      string
+	cache       *cacheState // nil if disabled
+	schema      schema.Schema
 }
 
 func NewGolang(o GolangOptions) *Generator {
 	return &Generator{
 		newGen: func(opts *Options) internalGenerator {
-			return golang.NewGenerator(o.cast())
+			return &golangGenerator{gen: golang.NewGenerator(o.cast(opts))}
 		},
+		fingerprint: fmt.Sprintf("go %+v", o),
+		pruneExts:   []string{".go"},
 	}
 }
 
+type golangGenerator struct {
+	gen *golang.Generator
+	err error
+}
+
+func (g *golangGenerator) Generate(w *gen.FileWriter, s schema.Schema) {
+	g.err = g.gen.Generate(w, s)
+}
+
+func (g *golangGenerator) generateErr() error {
+	return g.err
+}
+
+func (g *golangGenerator) validate(s schema.Schema) error {
+	return g.gen.Validate(s)
+}
+
 func NewJavascript(o JavascriptOptions) *Generator {
 	return &Generator{
 		newGen: func(opts *Options) internalGenerator {
-			return js.NewGenerator(o.cast())
+			return js.NewGenerator(o.cast(opts))
 		},
+		fingerprint: fmt.Sprintf("js %+v", o),
+		pruneExts:   []string{".js", ".d.ts"},
 	}
 }
 
 func (g *Generator) Generate(opts Options) error {
 	opts.sanitize()
 
-	s, err := schema.Parse(opts.RootDirectory, opts.GlobPatterns)
+	fsys, err := schemaFS(&opts)
+	if err != nil {
+		return err
+	}
+
+	s, err := parseSchema(&opts, fsys)
 	if err != nil {
 		return err
 	}
@@ -43,26 +92,219 @@ func (g *Generator) Generate(opts Options) error {
 	if opts.RemoveDeprecated {
 		s.RemoveDeprecated()
 	}
+	return g.generate(s, &opts, fsys)
+}
+
+// GenerateSchema generates the code for an already parsed schema. This allows
+// several generators to share a single parse of the schema. The removal of
+// deprecated declarations is left to the caller, the remaining schema options
+// are only used for the cache keys.
+func (g *Generator) GenerateSchema(s schema.Schema, opts Options) error {
+	opts.sanitize()
 
+	fsys, err := schemaFS(&opts)
+	if err != nil {
+		return err
+	}
+	return g.generate(s, &opts, fsys)
+}
+
+func (g *Generator) generate(s schema.Schema, opts *Options, fsys fs.FS) error {
+	g.schema = s
+
+	var err error
 	g.fileWriter, err = gen.NewFileWriter(opts.OutputDirectory)
 	if err != nil {
 		return err
 	}
+	g.outDir = opts.OutputDirectory
+
+	g.manifest = nil
+	if len(g.pruneExts) != 0 {
+		// the root is stored relative to the output directory, so the manifest
+		// stays valid if both are moved together
+		root, err := filepath.Abs(opts.RootDirectory)
+		if err != nil {
+			return err
+		}
+		outDir, err := filepath.Abs(opts.OutputDirectory)
+		if err != nil {
+			return err
+		}
+		if rel, err := filepath.Rel(outDir, root); err == nil {
+			root = rel
+		}
+		g.runKey = strings.Join(g.pruneExts, ",") + " " + filepath.ToSlash(root)
+		g.manifest = readManifest(opts.OutputDirectory)
+	}
+
+	generator := g.newGen(opts)
+	if v, ok := generator.(validatingGenerator); ok {
+		if err := v.validate(s); err != nil {
+			return err
+		}
+	}
+
+	g.cache = nil
+	if opts.UseCache && !g.noCache {
+		g.cache = &cacheState{
+			outDir:  opts.OutputDirectory,
+			cache:   readCache(opts.OutputDirectory),
+			updated: make(map[string]string),
+		}
+		s = g.cache.filter(s, g.fingerprint, opts, fsys)
+	}
 
-	generator := g.newGen(&opts)
 	generator.Generate(g.fileWriter, s)
+	if f, ok := generator.(failingGenerator); ok {
+		return f.generateErr()
+	}
 	return nil
 }
 
+// Schema returns the schema parsed by the last call of Generate. All files
+// are returned, even if some of them were skipped because of the cache.
+func (g *Generator) Schema() schema.Schema {
+	return g.schema
+}
+
 func (g *Generator) IterateFiles(iter func(filename string)) {
 	if g.fileWriter != nil {
 		g.fileWriter.WalkFiles(iter)
 	}
 }
 
+// IterateContents iterates over all generated files and their generated
+// contents without writing them.
+func (g *Generator) IterateContents(iter func(filename string, content []byte)) {
+	if g.fileWriter != nil {
+		g.fileWriter.WalkContents(iter)
+	}
+}
+
+// FileStatus describes whether a generated file was created, updated, or left
+// unchanged by Dump.
+type FileStatus = gen.FileStatus
+
+const (
+	FileCreated   = gen.FileCreated
+	FileUpdated   = gen.FileUpdated
+	FileUnchanged = gen.FileUnchanged
+)
+
+// IterateStatus iterates over all files written by the last call of Dump
+// and their status.
+func (g *Generator) IterateStatus(iter func(filename string, status FileStatus)) {
+	if g.fileWriter != nil {
+		g.fileWriter.WalkStatus(iter)
+	}
+}
+
+// Dump writes the generated files. Files whose content did not change are
+// left untouched, all other files are replaced atomically. The generated files
+// are recorded in the manifest of the output directory, which is used by Prune.
 func (g *Generator) Dump() error {
 	if g.fileWriter == nil {
 		return nil
 	}
-	return g.fileWriter.Flush()
+	if err := g.fileWriter.Flush(); err != nil {
+		return err
+	}
+	if err := g.record(); err != nil {
+		return err
+	}
+	if g.cache != nil {
+		return g.cache.update(g.fileWriter)
+	}
+	return nil
+}
+
+// Sink receives the generated files written by DumpTo. The file names are
+// slash-separated and relative to the output directory.
+type Sink = gen.Sink
+
+// MemorySink collects the generated files in memory.
+type MemorySink = gen.MemorySink
+
+// ArchiveSink writes the generated files into a tar or zip archive.
+type ArchiveSink = gen.ArchiveSink
+
+// NewArchiveSink creates an archive sink writing to w. The format is either
+// "tar" or "zip".
+func NewArchiveSink(w io.Writer, format string) (*ArchiveSink, error) {
+	return gen.NewArchiveSink(w, gen.ArchiveFormat(format))
+}
+
+// DumpTo writes the generated files to the given sink instead of the output
+// directory. The build cache is not updated.
+func (g *Generator) DumpTo(sink Sink) error {
+	if g.fileWriter == nil {
+		return nil
+	}
+	return g.fileWriter.FlushTo(sink)
+}
+
+// Check compares the generated files with the files on disk without writing
+// anything. For each differing or missing file, a unified diff is written to
+// w. The names of the out-of-date files are returned.
+func (g *Generator) Check(w io.Writer) ([]string, error) {
+	if g.fileWriter == nil {
+		return nil, nil
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		outdated []string
+		checkErr error
+	)
+	g.fileWriter.WalkContents(func(filename string, content []byte) {
+		if checkErr != nil {
+			return
+		}
+
+		name := filename
+		if rel, err := filepath.Rel(wd, filename); err == nil {
+			name = rel
+		}
+
+		oldName := name
+		old, err := os.ReadFile(filename)
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			oldName = os.DevNull
+		case err != nil:
+			checkErr = err
+			return
+		}
+
+		if d := diff.Unified(oldName, name, old, content); d != nil {
+			outdated = append(outdated, filename)
+			_, checkErr = w.Write(d)
+		}
+	})
+	return outdated, checkErr
+}
+
+// schemaFS returns the file system of the schema files. If the schema files
+// are read from the local file system, nil will be returned.
+func schemaFS(opts *Options) (fs.FS, error) {
+	if opts.FileSystem == nil || opts.RootDirectory == "." {
+		return opts.FileSystem, nil
+	}
+	return fs.Sub(opts.FileSystem, filepath.ToSlash(opts.RootDirectory))
+}
+
+func parseSchema(opts *Options, fsys fs.FS) (schema.Schema, error) {
+	config := schema.Config{
+		IncludeDirs: opts.IncludeDirectories,
+		Jobs:        opts.Jobs,
+	}
+	if fsys == nil {
+		return config.Parse(opts.RootDirectory, opts.GlobPatterns)
+	}
+	return config.ParseFS(fsys, opts.GlobPatterns)
 }
@@ -1,15 +1,34 @@
 package generator
 
 import (
+	"io/fs"
+
 	"github.com/mprot/mprotc/internal/gen/golang"
 	"github.com/mprot/mprotc/internal/gen/js"
+	"github.com/mprot/mprotc/internal/gen/tmpl"
 )
 
 type Options struct {
-	RootDirectory    string
-	GlobPatterns     []string
-	RemoveDeprecated bool
-	OutputDirectory  string
+	// FileSystem is the file system the schema files are read from. If it is
+	// nil, the schema files are read from the local file system. Otherwise
+	// the root directory is interpreted as relative to the file system's root.
+	FileSystem    fs.FS
+	RootDirectory string
+	GlobPatterns  []string
+	// IncludeDirectories are searched in order for imported schema files,
+	// which cannot be found relative to the importing file. If a file system
+	// is set, the include directories are interpreted as relative to the
+	// root directory.
+	IncludeDirectories []string
+	RemoveDeprecated   bool
+	OutputDirectory    string
+	// Jobs is the maximum number of files parsed and generated concurrently.
+	// If it is zero or negative, runtime.GOMAXPROCS(0) is used.
+	Jobs int
+	// UseCache enables the build cache, which is stored in the output directory.
+	// Schema files, which did not change since the previous run, are neither
+	// regenerated nor rewritten.
+	UseCache bool
 }
 
 func (o *Options) sanitize() {
@@ -26,6 +45,7 @@ type GolangOptions struct {
 	ScopedEnums  bool
 	UnwrapUnions bool
 	TypeID       bool
+	Registry     bool
 }
 
 func (o *GolangOptions) sanitize(opts *Options) {
@@ -34,12 +54,14 @@ func (o *GolangOptions) sanitize(opts *Options) {
 	}
 }
 
-func (o *GolangOptions) cast() golang.Options {
+func (o *GolangOptions) cast(opts *Options) golang.Options {
 	return golang.Options{
 		ImportRoot:   o.ImportRoot,
 		ScopedEnums:  o.ScopedEnums,
 		UnwrapUnions: o.UnwrapUnions,
 		TypeID:       o.TypeID,
+		Registry:     o.Registry,
+		Jobs:         opts.Jobs,
 	}
 }
 
@@ -50,8 +72,24 @@ type JavascriptOptions struct {
 func (o *JavascriptOptions) sanitize(_ *Options) {
 }
 
-func (o *JavascriptOptions) cast() js.Options {
+func (o *JavascriptOptions) cast(opts *Options) js.Options {
 	return js.Options{
 		TypeDecls: o.TypeDeclarations,
+		Jobs:      opts.Jobs,
+	}
+}
+
+type TemplateOptions struct {
+	Template  string
+	Extension string
+	Aggregate string
+}
+
+func (o *TemplateOptions) cast(opts *Options) tmpl.Options {
+	return tmpl.Options{
+		Template:  o.Template,
+		Extension: o.Extension,
+		Aggregate: o.Aggregate,
+		Jobs:      opts.Jobs,
 	}
 }
@@ -0,0 +1,117 @@
+package generator
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/mprot/mprotc/internal/gen"
+	"github.com/mprot/mprotc/plugin"
+	"github.com/mprot/mprotc/schema"
+)
+
+const pluginErrorCode schema.ErrorCode = "plugin"
+
+// NewPlugin creates a generator, which runs the external plugin executable at
+// the given path for the given language. The options are passed to the plugin
+// as they are. See package plugin for the protocol.
+func NewPlugin(language string, path string, options map[string]string) *Generator {
+	return &Generator{
+		newGen: func(opts *Options) internalGenerator {
+			return &pluginGenerator{
+				language: language,
+				path:     path,
+				options:  options,
+				opts:     opts,
+			}
+		},
+		fingerprint: fmt.Sprintf("plugin %s %s %v", language, path, options),
+	}
+}
+
+// failingGenerator is implemented by generators, which can fail.
+type failingGenerator interface {
+	generateErr() error
+}
+
+type pluginGenerator struct {
+	language string
+	path     string
+	options  map[string]string
+	opts     *Options
+	err      error
+}
+
+func (g *pluginGenerator) Generate(w *gen.FileWriter, s schema.Schema) {
+	if len(s) != 0 {
+		g.err = g.run(w, s)
+	}
+}
+
+func (g *pluginGenerator) generateErr() error {
+	return g.err
+}
+
+func (g *pluginGenerator) run(w *gen.FileWriter, s schema.Schema) error {
+	name := filepath.Base(g.path)
+
+	options := g.options
+	if options == nil {
+		options = map[string]string{}
+	}
+	req, err := json.Marshal(plugin.Request{
+		Version:    plugin.Version,
+		Language:   g.language,
+		Options:    options,
+		Descriptor: schema.NewDescriptor(s),
+	})
+	if err != nil {
+		return err
+	}
+
+	var stdout bytes.Buffer
+	cmd := exec.Command(g.path)
+	cmd.Stdin = bytes.NewReader(req)
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s: %v", name, err)
+	}
+
+	var resp plugin.Response
+	if err := json.Unmarshal(stdout.Bytes(), &resp); err != nil {
+		return fmt.Errorf("%s: invalid response: %v", name, err)
+	}
+
+	if len(resp.Diagnostics) != 0 {
+		errs := make(schema.ErrorList, 0, len(resp.Diagnostics))
+		for _, d := range resp.Diagnostics {
+			pos := schema.Pos{Line: d.Line, Column: d.Column}
+			if d.File != "" {
+				pos.File = g.schemaFilename(d.File)
+			}
+			errs = append(errs, schema.Error{Pos: pos, End: pos, Code: pluginErrorCode, Text: d.Message})
+		}
+		return errs
+	}
+
+	for _, f := range resp.Files {
+		filename := filepath.FromSlash(f.Name)
+		if !filepath.IsLocal(filename) {
+			return fmt.Errorf("%s: invalid file name %q", name, f.Name)
+		}
+		w.Write(filename, filepath.FromSlash(f.Source), []byte(f.Content))
+	}
+	return nil
+}
+
+// schemaFilename returns the name of a schema file as reported by the parser.
+func (g *pluginGenerator) schemaFilename(filename string) string {
+	if g.opts.FileSystem != nil {
+		return filename
+	}
+	return filepath.Join(g.opts.RootDirectory, filepath.FromSlash(filename))
+}
@@ -0,0 +1,166 @@
+package generator
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/mprot/mprotc/plugin"
+	"github.com/mprot/mprotc/schema"
+)
+
+// testPluginEnv is set, if the test binary is executed as a fake plugin.
+const testPluginEnv = "MPROTC_TEST_PLUGIN"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(testPluginEnv) != "" {
+		plugin.Main(fakePlugin)
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// fakePlugin generates a file per schema file, which lists the language, the
+// options, and the declarations. The option "mode" selects a failure.
+func fakePlugin(req *plugin.Request) (*plugin.Response, error) {
+	s, err := req.Descriptor.Schema()
+	if err != nil {
+		return nil, err
+	}
+
+	switch req.Options["mode"] {
+	case "diagnostics":
+		resp := &plugin.Response{}
+		for _, f := range s {
+			for _, decl := range f.Decls {
+				resp.Diagnostics = append(resp.Diagnostics, plugin.Errorf(decl.Pos(), "unsupported declaration in package %s", f.Package.Name))
+			}
+		}
+		resp.Diagnostics = append(resp.Diagnostics, plugin.Diagnostic{Message: "general failure"})
+		return resp, nil
+	case "escape":
+		return &plugin.Response{Files: []plugin.File{{Name: "../escape.txt", Content: "escaped"}}}, nil
+	case "absolute":
+		return &plugin.Response{Files: []plugin.File{{Name: filepath.ToSlash(filepath.Join(os.TempDir(), "abs.txt")), Content: "escaped"}}}, nil
+	case "invalid":
+		fmt.Fprint(os.Stdout, "no json")
+		os.Exit(0)
+	case "fail":
+		os.Exit(3)
+	}
+
+	names := make([]string, 0, len(req.Options))
+	for name := range req.Options {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	opts := make([]string, 0, len(names))
+	for _, name := range names {
+		opts = append(opts, name+"="+req.Options[name])
+	}
+
+	resp := &plugin.Response{}
+	for _, f := range s {
+		var decls []string
+		for _, decl := range f.Decls {
+			if st, ok := decl.(*schema.Struct); ok {
+				decls = append(decls, st.Name)
+			}
+		}
+		resp.Files = append(resp.Files, plugin.File{
+			Name:    strings.TrimSuffix(f.Name, ".mprot") + ".txt",
+			Source:  f.Name,
+			Content: fmt.Sprintf("%s [%s] %s\n", req.Language, strings.Join(opts, " "), strings.Join(decls, " ")),
+		})
+	}
+	return resp, nil
+}
+
+func TestPlugin(t *testing.T) {
+	executable, err := os.Executable()
+	if err != nil {
+		t.Skipf("test executable not found: %v", err)
+	}
+	t.Setenv(testPluginEnv, "1")
+
+	rootDir := t.TempDir()
+	writeTestFiles(t, rootDir, map[string]string{
+		"a.mprot":     "package a\n\nstruct A {\n}\n\nstruct B {\n}\n",
+		"sub/c.mprot": "package sub\n\nstruct C {\n}\n",
+	})
+
+	run := func(options map[string]string) (*Generator, string, error) {
+		outDir := t.TempDir()
+		g := NewPlugin("fake", executable, options)
+		err := g.Generate(Options{RootDirectory: rootDir, GlobPatterns: []string{"**/*.mprot"}, OutputDirectory: outDir})
+		return g, outDir, err
+	}
+
+	// successful response
+	g, outDir, err := run(map[string]string{"greeting": "hello", "level": "2"})
+	if err != nil {
+		t.Fatalf("unexpected generate error: %v", err)
+	}
+	files := make(map[string]string)
+	g.IterateContents(func(filename string, content []byte) {
+		rel, err := filepath.Rel(outDir, filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[filepath.ToSlash(rel)] = string(content)
+	})
+	expected := map[string]string{
+		"a.txt":     "fake [greeting=hello level=2] A B\n",
+		"sub/c.txt": "fake [greeting=hello level=2] C\n",
+	}
+	if !reflect.DeepEqual(files, expected) {
+		t.Errorf("unexpected generated files: %v", files)
+	}
+
+	// diagnostics are mapped to schema errors of the schema files
+	_, _, err = run(map[string]string{"mode": "diagnostics"})
+	errs, ok := err.(schema.ErrorList)
+	if !ok {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var msgs []string
+	for _, e := range errs {
+		if e.Code != pluginErrorCode {
+			t.Errorf("unexpected error code: %s", e.Code)
+		}
+		msgs = append(msgs, e.Error())
+	}
+	expectedMsgs := []string{
+		filepath.Join(rootDir, "a.mprot") + ":3:1: unsupported declaration in package a",
+		filepath.Join(rootDir, "a.mprot") + ":6:1: unsupported declaration in package a",
+		filepath.Join(rootDir, "sub", "c.mprot") + ":3:1: unsupported declaration in package sub",
+		"0:0: general failure",
+	}
+	if !reflect.DeepEqual(msgs, expectedMsgs) {
+		t.Errorf("unexpected diagnostics:\n%s", strings.Join(msgs, "\n"))
+	}
+
+	// invalid responses and failures
+	base := filepath.Base(executable)
+	for mode, expectedErr := range map[string]string{
+		"escape":   base + `: invalid file name "../escape.txt"`,
+		"absolute": base + `: invalid file name "` + filepath.ToSlash(filepath.Join(os.TempDir(), "abs.txt")) + `"`,
+		"invalid":  base + ": invalid response: ",
+		"fail":     base + ": exit status 3",
+	} {
+		g, outDir, err := run(map[string]string{"mode": mode})
+		if err == nil || !strings.HasPrefix(err.Error(), expectedErr) {
+			t.Errorf("unexpected error for mode %s: %v", mode, err)
+		}
+		g.IterateFiles(func(filename string) {
+			t.Errorf("unexpected generated file for mode %s: %s", mode, filename)
+		})
+		if _, err := os.Stat(filepath.Join(filepath.Dir(outDir), "escape.txt")); !os.IsNotExist(err) {
+			t.Errorf("file written outside of the output directory for mode %s", mode)
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package generator
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	manifestFilename = ".mprotc-manifest"
+	manifestVersion  = 1 // version of the manifest file format
+)
+
+// generatedHeader is the first line of all files generated by the builtin
+// generators.
+var generatedHeader = []byte("// Code generated by mprotc.")
+
+// manifest records the files, which were generated into an output directory.
+// The files are recorded per run, which is identified by the generated
+// language and the schema root directory, so the runs for several roots or
+// languages can share one output directory.
+type manifest struct {
+	Version int                 `json:"version"`
+	Runs    map[string][]string `json:"runs"` // run key => sorted slash-separated output filenames
+}
+
+// readManifest reads the manifest of the given output directory. An empty
+// manifest is returned, if the manifest file does not exist or is invalid.
+func readManifest(outDir string) *manifest {
+	m := &manifest{Version: manifestVersion, Runs: make(map[string][]string)}
+
+	data, err := os.ReadFile(filepath.Join(outDir, manifestFilename))
+	if err != nil {
+		return m
+	}
+
+	var stored manifest
+	if err := json.Unmarshal(data, &stored); err != nil || stored.Version != manifestVersion || stored.Runs == nil {
+		return m
+	}
+	return &stored
+}
+
+func (m *manifest) write(outDir string) error {
+	data, err := json.MarshalIndent(m, "", "\t")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(outDir, 0777); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(outDir, manifestFilename), append(data, '\n'), 0666)
+}
+
+// generatedFiles returns the slash-separated names of the files generated by
+// the last call of Generate relative to the output directory. This includes
+// the outputs of the schema files skipped because of the cache.
+func (g *Generator) generatedFiles() (map[string]struct{}, error) {
+	outDir, err := filepath.Abs(g.outDir)
+	if err != nil {
+		return nil, err
+	}
+
+	generated := make(map[string]struct{})
+	g.fileWriter.WalkFiles(func(filename string) {
+		if rel, relErr := filepath.Rel(outDir, filename); relErr != nil {
+			err = relErr
+		} else {
+			generated[filepath.ToSlash(rel)] = struct{}{}
+		}
+	})
+	if g.cache != nil {
+		// the outputs of the skipped schema files are still up to date
+		for schemaFile, entry := range g.cache.cache.Files {
+			if _, updated := g.cache.updated[schemaFile]; updated {
+				continue
+			}
+			for output := range entry.Outputs {
+				generated[output] = struct{}{}
+			}
+		}
+	}
+	return generated, err
+}
+
+// record adds the files generated by the last call of Generate to the
+// manifest and writes it. The files recorded by previous runs are kept until
+// they are pruned.
+func (g *Generator) record() error {
+	if g.manifest == nil {
+		return nil
+	}
+
+	generated, err := g.generatedFiles()
+	if err != nil {
+		return err
+	}
+	for _, filename := range g.manifest.Runs[g.runKey] {
+		generated[filename] = struct{}{}
+	}
+	if len(generated) == 0 {
+		return nil
+	}
+	g.manifest.Runs[g.runKey] = sortedNames(generated)
+	return g.manifest.write(g.outDir)
+}
+
+// Prune removes the files in the output directory, which were generated by a
+// previous run with the same language and schema root directory, but not by
+// the last call of Generate. The generated files of each run are recorded in
+// the file .mprotc-manifest of the output directory by Dump, so the outputs of
+// other roots or languages in the same directory are kept. Only the builtin
+// Go and JavaScript generators prune their outputs. Files, which were modified
+// to no longer start with the generated header, are kept. Directories, which
+// become empty, are removed as well. If dryRun is set, nothing is removed. The
+// names of the stale files are returned in both cases.
+func (g *Generator) Prune(dryRun bool) ([]string, error) {
+	if g.fileWriter == nil || g.manifest == nil {
+		return nil, nil
+	}
+
+	outDir, err := filepath.Abs(g.outDir)
+	if err != nil {
+		return nil, err
+	}
+	generated, err := g.generatedFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	var stale []string
+	for _, name := range g.manifest.Runs[g.runKey] {
+		if _, has := generated[name]; has {
+			continue
+		}
+		filename := filepath.Join(outDir, filepath.FromSlash(name))
+		isGenerated, err := hasGeneratedHeader(filename)
+		if err != nil {
+			return nil, err
+		}
+		if isGenerated {
+			stale = append(stale, filename)
+		}
+	}
+	sort.Strings(stale)
+	if dryRun {
+		return stale, nil
+	}
+
+	for _, filename := range stale {
+		if err := os.Remove(filename); err != nil {
+			return stale, err
+		}
+		// remove the emptied directories
+		for dir := filepath.Dir(filename); dir != outDir && strings.HasPrefix(dir, outDir); dir = filepath.Dir(dir) {
+			if os.Remove(dir) != nil {
+				break
+			}
+		}
+	}
+
+	if _, recorded := g.manifest.Runs[g.runKey]; !recorded && len(generated) == 0 {
+		return stale, nil
+	}
+	g.manifest.Runs[g.runKey] = sortedNames(generated)
+	return stale, g.manifest.write(g.outDir)
+}
+
+// hasGeneratedHeader reports whether the file starts with the generated
+// header. A missing file is reported as not generated.
+func hasGeneratedHeader(filename string) (bool, error) {
+	f, err := os.Open(filename)
+	if os.IsNotExist(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	header := make([]byte, len(generatedHeader))
+	if _, err := io.ReadFull(f, header); err != nil {
+		return false, nil // too short
+	}
+	return bytes.Equal(header, generatedHeader), nil
+}
+
+func sortedNames(names map[string]struct{}) []string {
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+	return sorted
+}
@@ -0,0 +1,153 @@
+package generator
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestPrune(t *testing.T) {
+	const header = "// Code generated by mprotc.\n// Do not edit.\n"
+
+	tests := []struct {
+		gen      func() *Generator
+		expected []string // stale files
+	}{
+		{gen: func() *Generator { return NewGolang(GolangOptions{}) }, expected: []string{"b.go", "sub/c.go"}},
+		{gen: func() *Generator { return NewJavascript(JavascriptOptions{TypeDeclarations: true}) }, expected: []string{"b.d.ts", "b.js", "sub/c.d.ts", "sub/c.js"}},
+		{gen: func() *Generator { return NewTemplate(TemplateOptions{Template: "missing.tmpl"}) }, expected: nil},
+	}
+
+	for _, test := range tests {
+		rootDir := t.TempDir()
+		outDir := t.TempDir()
+		writeTestFiles(t, rootDir, map[string]string{
+			"a.mprot":     "package a\n",
+			"b.mprot":     "package a\n",
+			"sub/c.mprot": "package sub\n",
+		})
+		writeTestFiles(t, outDir, map[string]string{
+			"old.go":    header, // not recorded
+			"old.js":    header,
+			"manual.go": "package a\n",
+		})
+
+		// errors of the template are irrelevant for pruning
+		run := func() *Generator {
+			g := test.gen()
+			_ = g.Generate(Options{RootDirectory: rootDir, GlobPatterns: []string{"**/*.mprot"}, OutputDirectory: outDir})
+			if err := g.Dump(); err != nil {
+				t.Fatalf("unexpected dump error: %v", err)
+			}
+			return g
+		}
+
+		if stale, err := run().Prune(true); err != nil || len(stale) != 0 {
+			t.Fatalf("unexpected stale files of the first run: %v, %v", stale, err)
+		}
+
+		for _, name := range []string{"b.mprot", "sub/c.mprot"} {
+			if err := os.Remove(filepath.Join(rootDir, filepath.FromSlash(name))); err != nil {
+				t.Fatal(err)
+			}
+		}
+		g := run()
+		stale, err := g.Prune(true)
+		if err != nil {
+			t.Fatalf("unexpected prune error: %v", err)
+		}
+		if names := relativeNames(t, outDir, stale); !reflect.DeepEqual(names, test.expected) {
+			t.Errorf("unexpected stale files: %v (expected %v)", names, test.expected)
+		}
+
+		if _, err := g.Prune(false); err != nil {
+			t.Fatalf("unexpected prune error: %v", err)
+		}
+		for _, filename := range stale {
+			if _, err := os.Stat(filename); !os.IsNotExist(err) {
+				t.Errorf("stale file %s not removed", filename)
+			}
+		}
+		if _, err := os.Stat(filepath.Join(outDir, "sub")); len(test.expected) != 0 && !os.IsNotExist(err) {
+			t.Errorf("emptied sub directory not removed: %v", err)
+		}
+		for _, name := range []string{"old.go", "old.js", "manual.go"} {
+			if _, err := os.Stat(filepath.Join(outDir, name)); err != nil {
+				t.Errorf("unrecorded file %s removed: %v", name, err)
+			}
+		}
+
+		// the pruned files are removed from the manifest
+		if stale, err := run().Prune(true); err != nil || len(stale) != 0 {
+			t.Errorf("unexpected stale files after pruning: %v, %v", stale, err)
+		}
+	}
+}
+
+func TestPruneSharedOutput(t *testing.T) {
+	rootA := t.TempDir()
+	rootB := t.TempDir()
+	outDir := t.TempDir()
+	writeTestFiles(t, rootA, map[string]string{"a/x.mprot": "package a\n", "a/old.mprot": "package a\n"})
+	writeTestFiles(t, rootB, map[string]string{"b/y.mprot": "package b\n"})
+
+	run := func(rootDir string) *Generator {
+		g := NewGolang(GolangOptions{})
+		if err := g.Generate(Options{RootDirectory: rootDir, GlobPatterns: []string{"**/*.mprot"}, OutputDirectory: outDir}); err != nil {
+			t.Fatalf("unexpected generate error: %v", err)
+		}
+		if err := g.Dump(); err != nil {
+			t.Fatalf("unexpected dump error: %v", err)
+		}
+		return g
+	}
+
+	run(rootA)
+	run(rootB)
+	if err := os.Remove(filepath.Join(rootA, "a", "old.mprot")); err != nil {
+		t.Fatal(err)
+	}
+
+	// the outputs of the other root are kept
+	for rootDir, expected := range map[string][]string{rootA: {"a/old.go"}, rootB: nil} {
+		stale, err := run(rootDir).Prune(false)
+		if err != nil {
+			t.Fatalf("unexpected prune error: %v", err)
+		}
+		if names := relativeNames(t, outDir, stale); !reflect.DeepEqual(names, expected) {
+			t.Errorf("unexpected stale files for root %s: %v (expected %v)", rootDir, names, expected)
+		}
+	}
+	for _, name := range []string{"a/x.go", "b/y.go"} {
+		if _, err := os.Stat(filepath.Join(outDir, filepath.FromSlash(name))); err != nil {
+			t.Errorf("generated file %s removed: %v", name, err)
+		}
+	}
+}
+
+func writeTestFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		filename := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func relativeNames(t *testing.T, dir string, filenames []string) []string {
+	t.Helper()
+	var names []string
+	for _, filename := range filenames {
+		rel, err := filepath.Rel(dir, filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, filepath.ToSlash(rel))
+	}
+	return names
+}
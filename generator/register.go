@@ -0,0 +1,144 @@
+package generator
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/mprot/mprotc/internal/gen"
+	"github.com/mprot/mprotc/internal/parallel"
+	"github.com/mprot/mprotc/schema"
+)
+
+// Language is the interface of a code generator for a single language, which
+// can be registered with Register.
+type Language interface {
+	// Generate generates the code for the given schema files into w. The
+	// schema holds only the files, which have to be regenerated.
+	Generate(w *FileWriter, s schema.Schema) error
+}
+
+// OptionSet declares and holds the command line options of a registered
+// language. The option name is determined by the usage, which should be
+// something like "--bool-opt" or "--string-opt <s>".
+type OptionSet interface {
+	AddBool(usage string, val bool, help string)
+	AddInt(usage string, val int, help string)
+	AddString(usage string, val string, help string)
+	AddStrings(usage string, help string)
+
+	Bool(name string) bool
+	Int(name string) int
+	String(name string) string
+	Strings(name string) []string
+}
+
+// Factory creates the generator of a registered language.
+type Factory struct {
+	// Options declares the additional command line options of the language.
+	// It may be nil.
+	Options func(opts OptionSet)
+	// New creates the generator with the options given on the command line.
+	New func(opts OptionSet) Language
+}
+
+var (
+	languagesMtx sync.RWMutex
+	languages    = make(map[string]Factory) // language name => factory
+)
+
+// Register registers the generator factory for the language with the given
+// name, which is used as the command name of the command line interface. It
+// panics if the name is empty or already registered.
+func Register(name string, f Factory) {
+	if name == "" || f.New == nil {
+		panic("generator: invalid registration")
+	}
+
+	languagesMtx.Lock()
+	defer languagesMtx.Unlock()
+
+	if _, has := languages[name]; has {
+		panic(fmt.Sprintf("generator: language %q registered twice", name))
+	}
+	languages[name] = f
+}
+
+// Lookup returns the factory registered for the language with the given name.
+func Lookup(name string) (Factory, bool) {
+	languagesMtx.RLock()
+	defer languagesMtx.RUnlock()
+
+	f, has := languages[name]
+	return f, has
+}
+
+// Names returns the sorted names of all registered languages.
+func Names() []string {
+	languagesMtx.RLock()
+	defer languagesMtx.RUnlock()
+
+	names := make([]string, 0, len(languages))
+	for name := range languages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewLanguage creates a generator for the given language generator. The key
+// identifies the language and its options and is used for the build cache.
+func NewLanguage(lang Language, key string) *Generator {
+	return &Generator{
+		newGen: func(opts *Options) internalGenerator {
+			return &languageGenerator{lang: lang, jobs: opts.Jobs}
+		},
+		fingerprint: "language " + key,
+	}
+}
+
+type languageGenerator struct {
+	lang Language
+	jobs int
+	err  error
+}
+
+func (g *languageGenerator) Generate(w *gen.FileWriter, s schema.Schema) {
+	g.err = g.lang.Generate(&FileWriter{w: w, jobs: g.jobs}, s)
+}
+
+func (g *languageGenerator) generateErr() error {
+	return g.err
+}
+
+// Printer prints lines of code into a memory buffer.
+type Printer interface {
+	Println(args ...interface{}) // always appends newline
+}
+
+// FileWriter buffers the files generated by a language generator. Printers
+// can be requested concurrently, but each printer must only be used by a
+// single goroutine.
+type FileWriter struct {
+	w    *gen.FileWriter
+	jobs int
+}
+
+// Printer returns the printer for the target file of the given schema file.
+// The file extension of filename is replaced with fileExt.
+func (w *FileWriter) Printer(filename string, fileExt string) Printer {
+	return w.w.Printer(filename, fileExt)
+}
+
+// Write buffers the content of the target file with the given name, which is
+// relative to the output directory. The source is the name of the schema file
+// the content was generated from.
+func (w *FileWriter) Write(filename string, source string, content []byte) {
+	w.w.Write(filename, source, content)
+}
+
+// Each calls fn for all indices from 0 to n-1 using at most the configured
+// number of concurrent jobs.
+func (w *FileWriter) Each(n int, fn func(i int)) {
+	parallel.Each(w.jobs, n, fn)
+}
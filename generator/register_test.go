@@ -0,0 +1,93 @@
+package generator
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/mprot/mprotc/schema"
+)
+
+type testLanguage struct {
+	err error
+}
+
+func (l testLanguage) Generate(w *FileWriter, s schema.Schema) error {
+	if l.err != nil {
+		return l.err
+	}
+	for _, f := range s {
+		w.Printer(f.Name, ".txt").Println("package ", f.Package.Name)
+		w.Write("extra/"+f.Package.Name+".bin", f.Name, []byte{1, 2})
+	}
+	return nil
+}
+
+func TestRegister(t *testing.T) {
+	Register("test-language", Factory{
+		New: func(opts OptionSet) Language { return testLanguage{} },
+	})
+
+	if _, has := Lookup("test-language"); !has {
+		t.Fatal("language not registered")
+	}
+	if _, has := Lookup("unknown-language"); has {
+		t.Error("unexpected unknown language")
+	}
+
+	found := false
+	for _, name := range Names() {
+		found = found || name == "test-language"
+	}
+	if !found {
+		t.Errorf("registered language missing in %v", Names())
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic for duplicate registration, got none")
+		}
+	}()
+	Register("test-language", Factory{
+		New: func(opts OptionSet) Language { return testLanguage{} },
+	})
+}
+
+func TestNewLanguage(t *testing.T) {
+	opts := Options{
+		FileSystem:   fstest.MapFS{"a.mprot": {Data: []byte("package a\n")}},
+		GlobPatterns: []string{"*.mprot"},
+	}
+
+	g := NewLanguage(testLanguage{}, "test")
+	if err := g.Generate(opts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	contents := make(map[string]string)
+	g.IterateContents(func(filename string, content []byte) {
+		rel, err := filepath.Rel(mustAbs(t, "."), filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		contents[filepath.ToSlash(rel)] = string(content)
+	})
+	if len(contents) != 2 || contents["a.txt"] != "package a\n" || contents["extra/a.bin"] != "\x01\x02" {
+		t.Errorf("unexpected contents: %q", contents)
+	}
+
+	errGenerate := errors.New("generate error")
+	g = NewLanguage(testLanguage{err: errGenerate}, "test")
+	if err := g.Generate(opts); err != errGenerate {
+		t.Errorf("unexpected error: %v (expected %v)", err, errGenerate)
+	}
+}
+
+func mustAbs(t *testing.T, path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return abs
+}
@@ -0,0 +1,46 @@
+package generator
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"os"
+
+	"github.com/mprot/mprotc/internal/gen"
+	"github.com/mprot/mprotc/internal/gen/tmpl"
+	"github.com/mprot/mprotc/schema"
+)
+
+// NewTemplate creates a generator, which executes a text/template over the
+// schema. The build cache is never used, because each output can depend on
+// all schema files via .Files.
+func NewTemplate(o TemplateOptions) *Generator {
+	// the template content is part of the fingerprint, errors are reported
+	// when generating
+	content, _ := os.ReadFile(o.Template)
+
+	return &Generator{
+		newGen: func(opts *Options) internalGenerator {
+			return &templateGenerator{opts: o.cast(opts)}
+		},
+		fingerprint: fmt.Sprintf("template %+v %x", o, sha256.Sum256(content)),
+		noCache:     true,
+	}
+}
+
+type templateGenerator struct {
+	opts tmpl.Options
+	err  error
+}
+
+func (g *templateGenerator) Generate(w *gen.FileWriter, s schema.Schema) {
+	tg, err := tmpl.NewGenerator(g.opts)
+	if err != nil {
+		g.err = err
+		return
+	}
+	g.err = tg.Generate(w, s)
+}
+
+func (g *templateGenerator) generateErr() error {
+	return g.err
+}
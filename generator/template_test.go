@@ -0,0 +1,56 @@
+package generator
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestTemplateWithoutCache(t *testing.T) {
+	rootDir := t.TempDir()
+	outDir := t.TempDir()
+	writeTestFiles(t, rootDir, map[string]string{
+		"a.mprot":     "package a\n",
+		"b.mprot":     "package b\n",
+		"schema.tmpl": "{{ .File.Package.Name }}:{{ range .Files }} {{ .Package.Name }}{{ end }}\n",
+	})
+
+	run := func() map[string]string {
+		g := NewTemplate(TemplateOptions{Template: filepath.Join(rootDir, "schema.tmpl")})
+		err := g.Generate(Options{
+			RootDirectory:   rootDir,
+			GlobPatterns:    []string{"*.mprot"},
+			OutputDirectory: outDir,
+			UseCache:        true,
+		})
+		if err != nil {
+			t.Fatalf("unexpected generate error: %v", err)
+		}
+		if err := g.Dump(); err != nil {
+			t.Fatalf("unexpected dump error: %v", err)
+		}
+
+		files := make(map[string]string)
+		g.IterateContents(func(filename string, content []byte) {
+			files[filepath.Base(filename)] = string(content)
+		})
+		return files
+	}
+
+	run()
+	writeTestFiles(t, rootDir, map[string]string{"c.mprot": "package c\n"})
+
+	// the outputs of unchanged schema files depend on the new file as well
+	expected := map[string]string{
+		"a.txt": "a: a b c\n",
+		"b.txt": "b: a b c\n",
+		"c.txt": "c: a b c\n",
+	}
+	if files := run(); !reflect.DeepEqual(files, expected) {
+		t.Errorf("unexpected outputs: %v", files)
+	}
+	if _, err := os.Stat(filepath.Join(outDir, cacheFilename)); !os.IsNotExist(err) {
+		t.Errorf("unexpected cache file: %v", err)
+	}
+}
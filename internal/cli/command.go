@@ -1,83 +1,330 @@
 package cli
 
 import (
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/mprot/mprotc/generator"
+	"github.com/mprot/mprotc/internal/diff"
+	"github.com/mprot/mprotc/internal/gen"
+	"github.com/mprot/mprotc/schema"
 )
 
 const binName = "mprotc"
 
+// Command describes a command of the command line interface. A command is
+// either a code generator for a specific language, or a tool with its own
+// Run function.
 type Command struct {
+	Usage     string // usage of the arguments (tools only)
+	Help      string // short description (tools only)
 	Options   func(opts *Opts)
 	Generator func(opts *Opts) *generator.Generator
+	Run       func(opts *Opts, args []string) error
+
+	plugin bool // external generator plugin
 }
 
-func (c *Command) exec(opts *Opts, globPatterns []string) error {
-	dryRun := opts.Bool("dryrun")
+// pluginPrefix is the name prefix of the external generator plugins.
+const pluginPrefix = "mprotc-gen-"
+
+func pluginCommand(language string, path string) Command {
+	return Command{
+		Options: func(opts *Opts) {
+			opts.AddStrings("--opt <name=value>", "Pass an option to the "+pluginPrefix+language+" plugin (repeatable).")
+		},
+
+		Generator: func(opts *Opts) *generator.Generator {
+			options := make(map[string]string)
+			for _, opt := range opts.Strings("opt") {
+				name, value, _ := strings.Cut(opt, "=")
+				options[name] = value
+			}
+			return generator.NewPlugin(language, path, options)
+		},
+
+		plugin: true,
+	}
+}
+
+func (c *Command) exec(opts *Opts, args []string) error {
+	if c.Run != nil {
+		return c.Run(opts, args)
+	}
+
+	globPatterns := args
 
 	gen := c.Generator(opts)
-	err := gen.Generate(generator.Options{
-		RootDirectory:    opts.String("root"),
-		GlobPatterns:     globPatterns,
-		RemoveDeprecated: !opts.Bool("deprecated"),
-		OutputDirectory:  opts.String("out"),
+	err := gen.Generate(generatorOptions(opts, globPatterns))
+	if err != nil {
+		return err
+	}
+	outdated, err := output(gen, outputOptions{
+		dryRun:  opts.Bool("dryrun"),
+		check:   opts.Bool("check"),
+		prune:   opts.Bool("prune"),
+		verbose: opts.Bool("verbose"),
+		archive: opts.String("out-archive"),
 	})
 	if err != nil {
 		return err
 	}
+	return outdatedError(outdated)
+}
+
+// outdatedError returns the error for the given number of out-of-date files
+// in check mode, or nil if all files are up to date.
+func outdatedError(outdated int) error {
+	switch outdated {
+	case 0:
+		return nil
+	case 1:
+		return errors.New("1 generated file is out of date")
+	default:
+		return fmt.Errorf("%d generated files are out of date", outdated)
+	}
+}
+
+type outputOptions struct {
+	dryRun  bool   // print the names of the files only?
+	check   bool   // compare the files with the files on disk?
+	prune   bool   // remove stale generated files?
+	verbose bool   // print the status of each written file?
+	archive string // archive file written instead of the output path ("-" for stdout)
+}
+
+// output writes the generated files and removes the stale generated files,
+// if requested. In verbose mode, the status of each file is printed. In
+// dry-run mode, the names of the files are printed instead.
+// In check mode, the files are compared with the files on disk and the number
+// of out-of-date files is returned, where stale files count as out of date.
+func output(gen *generator.Generator, o outputOptions) (int, error) {
+	switch {
+	case o.check:
+		outdated, err := gen.Check(os.Stdout)
+		if err != nil || !o.prune {
+			return len(outdated), err
+		}
+
+		stale, err := gen.Prune(true)
+		if err != nil {
+			return 0, err
+		}
+		for _, filename := range stale {
+			content, err := os.ReadFile(filename)
+			if err != nil {
+				return 0, err
+			}
+			name := relativePath(filename)
+			os.Stdout.Write(diff.Unified(name, os.DevNull, content, nil))
+		}
+		return len(outdated) + len(stale), nil
 
-	if dryRun {
+	case o.dryRun:
 		gen.IterateFiles(func(filename string) {
 			fmt.Fprintln(os.Stdout, filename)
 		})
-		return nil
+		if o.prune {
+			stale, err := gen.Prune(true)
+			if err != nil {
+				return 0, err
+			}
+			for _, filename := range stale {
+				fmt.Fprintln(os.Stdout, "remove", filename)
+			}
+		}
+		return 0, nil
+
+	case o.archive != "":
+		if err := writeArchive(gen, o.archive); err != nil {
+			return 0, err
+		}
+		if o.verbose {
+			gen.IterateStatus(func(filename string, status generator.FileStatus) {
+				fmt.Fprintln(os.Stderr, "added", relativePath(filename))
+			})
+		}
+		return 0, nil
+
+	default:
+		if err := gen.Dump(); err != nil {
+			return 0, err
+		}
+		if o.verbose {
+			gen.IterateStatus(func(filename string, status generator.FileStatus) {
+				fmt.Fprintln(os.Stdout, status, relativePath(filename))
+			})
+		}
+		if o.prune {
+			stale, err := gen.Prune(false)
+			if o.verbose {
+				for _, filename := range stale {
+					fmt.Fprintln(os.Stdout, "removed", relativePath(filename))
+				}
+			}
+			return 0, err
+		}
+		return 0, nil
+	}
+}
+
+// writeArchive writes the generated files into the given archive file. The
+// archive format is determined by the file extension. If filename is "-", a
+// tar archive is written to stdout.
+func writeArchive(g *generator.Generator, filename string) (err error) {
+	format, w := gen.Tar, io.Writer(os.Stdout)
+	if filename != "-" {
+		if format, err = gen.ArchiveFormatOf(filename); err != nil {
+			return err
+		}
+
+		f, err := os.Create(filename)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if closeErr := f.Close(); err == nil {
+				err = closeErr
+			}
+		}()
+		w = f
 	}
 
-	return gen.Dump()
+	sink, err := gen.NewArchiveSink(w, format)
+	if err != nil {
+		return err
+	}
+	if err := g.DumpTo(sink); err != nil {
+		return err
+	}
+	return sink.Close()
 }
 
-func (c *Command) registerOpts(opts *Opts) {
+// relativePath returns the path relative to the working directory, if
+// possible.
+func relativePath(path string) string {
+	if wd, err := os.Getwd(); err == nil {
+		if rel, err := filepath.Rel(wd, path); err == nil {
+			return rel
+		}
+	}
+	return path
+}
+
+func generatorOptions(opts *Opts, globPatterns []string) generator.Options {
+	return generator.Options{
+		RootDirectory:      opts.String("root"),
+		GlobPatterns:       globPatterns,
+		IncludeDirectories: opts.Strings("include"),
+		RemoveDeprecated:   !opts.Bool("deprecated"),
+		OutputDirectory:    opts.String("out"),
+		Jobs:               opts.Int("jobs"),
+		UseCache:           opts.Bool("cache") && !opts.Bool("check") && opts.String("out-archive") == "", // output all files
+	}
+}
+
+func (c *Command) isTool() bool {
+	return c.Run != nil
+}
+
+func (c *Command) usage() string {
+	if c.isTool() {
+		return c.Usage
+	}
+	return "[options] [schema-file ...]"
+}
+
+func (c *Command) newOpts() *Opts {
+	opts := NewOpts()
+	if !c.isTool() {
+		opts.AddString("--root <path>", ".", "Specify the root path of the mprot schema files.")
+		opts.AddStrings("-I, --include <dir>", "Add a directory to search for imported schema files (repeatable).")
+		opts.AddString("--out <path>", ".", "Specify the output path for the generated code.")
+		opts.AddString("--out-archive <file>", "", "Write the generated code into a .tar or .zip archive instead of the output path (- for a tar archive on stdout).")
+		opts.AddBool("--deprecated", false, "Include the deprecated fields in the generated code.")
+		opts.AddBool("--dryrun", false, "Print the names of the generated files only.")
+		opts.AddBool("--check", false, "Check that the generated files are up to date without writing them.")
+		opts.AddBool("--prune", false, "Remove previously generated files, which were not generated in this run.")
+		opts.AddBool("-v, --verbose", false, "Print whether each generated file was created, updated, or unchanged.")
+		opts.AddBool("--watch", false, "Watch the schema files and regenerate the code on changes.")
+		opts.AddBool("--cache", false, "Skip unchanged schema files using a cache in the output path.")
+		opts.AddInt("--jobs <n>", 0, "Specify the number of files parsed and generated concurrently (default: number of CPUs).")
+	}
+	opts.AddString("--error-format <format>", "text", "Specify the output format of schema errors ("+strings.Join(errorFormatNames(), ", ")+").")
 	if c.Options != nil {
 		c.Options(opts)
 	}
+	return opts
 }
 
-type Commands map[string]Command // language => command
+type Commands map[string]Command // command name => command
 
-func (c Commands) Exec(language string, args []string) error {
-	opts := NewOpts()
-	opts.AddString("--root <path>", ".", "Specify the root path of the mprot schema files.")
-	opts.AddString("--out <path>", ".", "Specify the output path for the generated code.")
-	opts.AddBool("--deprecated", false, "Include the deprecated fields in the generated code.")
-	opts.AddBool("--dryrun", false, "Print the names of the generated files only.")
+func registeredCommand(language string, f generator.Factory) Command {
+	declare := func(opts *Opts) {
+		if f.Options != nil {
+			f.Options(opts)
+		}
+	}
 
-	cmd, has := c[language]
+	return Command{
+		Options: declare,
+
+		Generator: func(opts *Opts) *generator.Generator {
+			return generator.NewLanguage(f.New(opts), language+opts.key(declare))
+		},
+	}
+}
+
+// lookup returns the command with the given name. Unknown names are looked up
+// in the generator registry and then as external generator plugins named
+// mprotc-gen-<name> in PATH.
+func (c Commands) lookup(name string) (Command, bool) {
+	if cmd, has := c[name]; has {
+		return cmd, true
+	}
+	if f, has := generator.Lookup(name); has {
+		return registeredCommand(name, f), true
+	}
+	if name == "" || strings.HasPrefix(name, "-") || strings.ContainsAny(name, `/\`) {
+		return Command{}, false
+	}
+
+	path, err := exec.LookPath(pluginPrefix + name)
+	if err != nil {
+		return Command{}, false
+	}
+	return pluginCommand(name, path), true
+}
+
+func (c Commands) Exec(name string, args []string) error {
+	cmd, has := c.lookup(name)
 	if !has {
-		if strings.ToLower(strings.TrimLeft(language, "-")) != "help" {
-			return fmt.Errorf("unknown language %q", language)
+		if strings.ToLower(strings.TrimLeft(name, "-")) != "help" {
+			return fmt.Errorf("unknown command %q", name)
 		}
 		if len(args) != 0 {
-			language = args[0]
-			cmd, has = c[language]
+			name = args[0]
+			cmd, has = c.lookup(name)
 		}
 
 		if has {
-			cmd.registerOpts(opts)
+			c.printHelp(name, &cmd, cmd.newOpts())
 		} else {
-			language = "<language>"
+			c.printHelp("<command>", nil, nil)
 		}
-		c.printHelp(language, opts)
 		return nil
 	}
 
-	cmd.registerOpts(opts)
-
+	opts := cmd.newOpts()
 	fset := flag.NewFlagSet(binName, flag.ContinueOnError)
-	fset.Usage = func() { c.printHelp(language, opts) }
+	fset.Usage = func() { c.printHelp(name, &cmd, opts) }
 	opts.RegisterAt(fset)
 	if err := fset.Parse(args); err != nil {
 		if err == flag.ErrHelp {
@@ -86,16 +333,59 @@ func (c Commands) Exec(language string, args []string) error {
 		return err
 	}
 
-	return cmd.exec(opts, fset.Args())
+	errFormat, has := errorFormats[opts.String("error-format")]
+	if !has {
+		return fmt.Errorf("unknown error format %q", opts.String("error-format"))
+	}
+
+	report := func(err error) error {
+		errs, ok := err.(schema.ErrorList)
+		if !ok {
+			return err
+		}
+
+		w := os.Stderr
+		if errFormat.stdout {
+			w = os.Stdout
+		}
+		if err := errFormat.print(w, errs); err != nil {
+			return err
+		}
+		return ErrReported
+	}
+
+	if !cmd.isTool() && opts.String("out-archive") != "" {
+		for _, name := range []string{"check", "prune", "watch"} {
+			if opts.Bool(name) {
+				return fmt.Errorf("--%s cannot be combined with --out-archive", name)
+			}
+		}
+	}
+	if !cmd.isTool() && opts.Bool("watch") {
+		if opts.Bool("check") {
+			return fmt.Errorf("--check cannot be combined with --watch")
+		}
+		return cmd.watch(opts, fset.Args(), report)
+	}
+	return report(cmd.exec(opts, fset.Args()))
 }
 
-func (c Commands) printHelp(language string, opts *Opts) {
+func (c Commands) printHelp(name string, cmd *Command, opts *Opts) {
 	w := os.Stderr
 
 	fmt.Fprintln(w, `Usage:`)
-	fmt.Fprintln(w, ` `, binName, language, `[options] [schema-file ...]`)
-	fmt.Fprintln(w, ` `, binName, `help`, language)
+	if cmd != nil {
+		fmt.Fprintln(w, ` `, binName, name, cmd.usage())
+	} else {
+		fmt.Fprintln(w, ` `, binName, `<language> [options] [schema-file ...]`)
+		fmt.Fprintln(w, ` `, binName, `<command> [options] [arguments]`)
+	}
+	fmt.Fprintln(w, ` `, binName, `help`, name)
 	fmt.Fprintln(w)
+	if cmd != nil && cmd.Help != "" {
+		fmt.Fprintln(w, cmd.Help)
+		fmt.Fprintln(w)
+	}
 	if opts != nil {
 		fmt.Fprintln(w, `Options:`)
 		opts.ForEach(func(usage, help string) {
@@ -104,16 +394,34 @@ func (c Commands) printHelp(language string, opts *Opts) {
 				fmt.Fprintln(w, `     `, help)
 			}
 		})
+		fmt.Fprintln(w)
 	}
-	fmt.Fprintln(w)
 	fmt.Fprintln(w, `Supported Languages:`)
-	fmt.Fprintln(w, ` `, strings.Join(c.supportedLangs(), ", "))
+	fmt.Fprintln(w, ` `, strings.Join(c.names(false), ", "))
+	if tools := c.names(true); len(tools) != 0 {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, `Commands:`)
+		for _, name := range tools {
+			cmd := c[name]
+			fmt.Fprintln(w, `  `+gen.RPad(name, 10), cmd.Help)
+		}
+	}
 }
 
-func (c Commands) supportedLangs() []string {
-	langs := make([]string, 0, len(c))
-	for lang := range c {
-		langs = append(langs, lang)
+func (c Commands) names(tools bool) []string {
+	names := make([]string, 0, len(c))
+	for name, cmd := range c {
+		if cmd.isTool() == tools {
+			names = append(names, name)
+		}
+	}
+	if !tools {
+		for _, name := range generator.Names() {
+			if _, has := c[name]; !has {
+				names = append(names, name)
+			}
+		}
 	}
-	return langs
+	sort.Strings(names)
+	return names
 }
@@ -0,0 +1,64 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExecCheck(t *testing.T) {
+	dir := t.TempDir()
+	root := filepath.Join(dir, "schema")
+	out := filepath.Join(dir, "out")
+	writeFiles(t, root, map[string]string{
+		"a.mprot": "package a\n\nconst A = 1\n",
+		"b.mprot": "package a\n\nconst B = 2\n",
+	})
+
+	exec := func(args ...string) error {
+		args = append([]string{"--root", root, "--out", out}, args...)
+		return testCommands().Exec("go", append(args, "*.mprot"))
+	}
+
+	// suppress the diffs
+	stdout := os.Stdout
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer devNull.Close()
+	os.Stdout = devNull
+	defer func() { os.Stdout = stdout }()
+
+	if err := exec("--check"); err == nil || err.Error() != "2 generated files are out of date" {
+		t.Errorf("unexpected error for missing files: %v", err)
+	}
+	if err := exec(); err != nil {
+		t.Fatalf("unexpected generate error: %v", err)
+	}
+	if err := exec("--check"); err != nil {
+		t.Errorf("unexpected error for up-to-date files: %v", err)
+	}
+
+	writeFiles(t, out, map[string]string{"a.go": "package a\n"})
+	if err := exec("--check"); err == nil || err.Error() != "1 generated file is out of date" {
+		t.Errorf("unexpected error for a changed file: %v", err)
+	}
+	if err := exec("--check", "--prune"); err == nil || err.Error() != "1 generated file is out of date" {
+		t.Errorf("unexpected error for a changed file with --prune: %v", err)
+	}
+
+	// stale files count as out of date with --prune
+	if err := exec(); err != nil {
+		t.Fatalf("unexpected generate error: %v", err)
+	}
+	if err := os.Remove(filepath.Join(root, "b.mprot")); err != nil {
+		t.Fatal(err)
+	}
+	if err := exec("--check"); err != nil {
+		t.Errorf("unexpected error without --prune: %v", err)
+	}
+	if err := exec("--check", "--prune"); err == nil || err.Error() != "1 generated file is out of date" {
+		t.Errorf("unexpected error for a stale file: %v", err)
+	}
+}
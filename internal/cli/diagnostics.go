@@ -0,0 +1,221 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/mprot/mprotc/schema"
+)
+
+// ErrReported is returned by Exec, if the schema errors were already printed
+// in the requested error format.
+var ErrReported = errorString("errors reported")
+
+const severityError = "error"
+
+type errorString string
+
+func (e errorString) Error() string {
+	return string(e)
+}
+
+type errorPrinter func(w io.Writer, errs schema.ErrorList) error
+
+// errorFormats maps the supported values of the --error-format option to the
+// respective printer and a flag whether the output is written to stdout.
+var errorFormats = map[string]struct {
+	print  errorPrinter
+	stdout bool
+}{
+	"text":   {printTextErrors, false},
+	"json":   {printJSONErrors, true},
+	"sarif":  {printSarifErrors, true},
+	"github": {printGithubErrors, true},
+}
+
+func errorFormatNames() []string {
+	names := make([]string, 0, len(errorFormats))
+	for name := range errorFormats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// printTextErrors prints the errors in a human readable form. The output is
+// limited to a few files and a few errors per file.
+func printTextErrors(w io.Writer, errs schema.ErrorList) error {
+	const (
+		maxFiles       = 5
+		maxErrsPerFile = 5
+	)
+
+	var (
+		filename  string
+		fileCount int
+		errCount  int
+	)
+	for _, err := range errs {
+		if filename != err.Pos.File {
+			filename = err.Pos.File
+			errCount = 0
+			if fileCount++; fileCount > maxFiles {
+				break
+			}
+			fmt.Fprintln(w, "#", filename)
+		}
+		if errCount++; errCount > maxErrsPerFile {
+			continue
+		}
+		fmt.Fprintln(w, err.Error())
+	}
+	return nil
+}
+
+type jsonError struct {
+	File      string `json:"file"`
+	Line      int    `json:"line"`
+	Column    int    `json:"column"`
+	EndLine   int    `json:"endLine"`
+	EndColumn int    `json:"endColumn"`
+	Severity  string `json:"severity"`
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+}
+
+func printJSONErrors(w io.Writer, errs schema.ErrorList) error {
+	res := make([]jsonError, 0, len(errs))
+	for _, err := range errs {
+		res = append(res, jsonError{
+			File:      filepath.ToSlash(err.Pos.File),
+			Line:      err.Pos.Line,
+			Column:    err.Pos.Column,
+			EndLine:   err.End.Line,
+			EndColumn: err.End.Column,
+			Severity:  severityError,
+			Code:      string(err.Code),
+			Message:   err.Text,
+		})
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(res)
+}
+
+type sarifLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool struct {
+		Driver struct {
+			Name           string      `json:"name"`
+			InformationURI string      `json:"informationUri"`
+			Rules          []sarifRule `json:"rules"`
+		} `json:"driver"`
+	} `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifRule struct {
+	ID string `json:"id"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation struct {
+		ArtifactLocation struct {
+			URI string `json:"uri"`
+		} `json:"artifactLocation"`
+		Region struct {
+			StartLine   int `json:"startLine"`
+			StartColumn int `json:"startColumn"`
+			EndLine     int `json:"endLine"`
+			EndColumn   int `json:"endColumn"`
+		} `json:"region"`
+	} `json:"physicalLocation"`
+}
+
+func printSarifErrors(w io.Writer, errs schema.ErrorList) error {
+	var run sarifRun
+	run.Tool.Driver.Name = binName
+	run.Tool.Driver.InformationURI = "https://github.com/mprot/mprotc"
+	run.Tool.Driver.Rules = []sarifRule{}
+	run.Results = make([]sarifResult, 0, len(errs))
+
+	rules := make(map[schema.ErrorCode]struct{})
+	for _, err := range errs {
+		if _, has := rules[err.Code]; !has {
+			rules[err.Code] = struct{}{}
+			run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{ID: string(err.Code)})
+		}
+
+		var loc sarifLocation
+		loc.PhysicalLocation.ArtifactLocation.URI = filepath.ToSlash(err.Pos.File)
+		loc.PhysicalLocation.Region.StartLine = err.Pos.Line
+		loc.PhysicalLocation.Region.StartColumn = err.Pos.Column
+		loc.PhysicalLocation.Region.EndLine = err.End.Line
+		loc.PhysicalLocation.Region.EndColumn = err.End.Column
+
+		run.Results = append(run.Results, sarifResult{
+			RuleID:    string(err.Code),
+			Level:     severityError,
+			Message:   sarifMessage{Text: err.Text},
+			Locations: []sarifLocation{loc},
+		})
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(sarifLog{
+		Version: "2.1.0",
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Runs:    []sarifRun{run},
+	})
+}
+
+// printGithubErrors prints the errors as GitHub Actions workflow commands,
+// which are shown as annotations in code reviews.
+func printGithubErrors(w io.Writer, errs schema.ErrorList) error {
+	for _, err := range errs {
+		_, e := fmt.Fprintf(w, "::%s file=%s,line=%d,col=%d,endLine=%d,endColumn=%d,title=%s::%s\n",
+			severityError,
+			escapeGithubProperty(filepath.ToSlash(err.Pos.File)),
+			err.Pos.Line,
+			err.Pos.Column,
+			err.End.Line,
+			err.End.Column,
+			escapeGithubProperty(string(err.Code)),
+			escapeGithubData(err.Text),
+		)
+		if e != nil {
+			return e
+		}
+	}
+	return nil
+}
+
+func escapeGithubData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+func escapeGithubProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
@@ -0,0 +1,203 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/mprot/mprotc/schema"
+)
+
+var testErrors = schema.ErrorList{
+	{
+		Pos:  schema.Pos{File: "a.mprot", Line: 3, Column: 2},
+		End:  schema.Pos{File: "a.mprot", Line: 3, Column: 14},
+		Code: schema.CodeDuplicateField,
+		Text: "duplicate field F in struct S",
+	},
+	{
+		Pos:  schema.Pos{File: "dir/b,c.mprot", Line: 7, Column: 1},
+		End:  schema.Pos{File: "dir/b,c.mprot", Line: 7, Column: 1},
+		Code: schema.CodeUndefinedType,
+		Text: "undefined type X\n100%",
+	},
+}
+
+func TestPrintErrors(t *testing.T) {
+	tests := []struct {
+		format   string
+		expected string
+	}{
+		{
+			format: "text",
+			expected: "# a.mprot\n" +
+				"a.mprot:3:2: duplicate field F in struct S\n" +
+				"# dir/b,c.mprot\n" +
+				"dir/b,c.mprot:7:1: undefined type X\n100%\n",
+		},
+		{
+			format: "json",
+			expected: `[
+  {
+    "file": "a.mprot",
+    "line": 3,
+    "column": 2,
+    "endLine": 3,
+    "endColumn": 14,
+    "severity": "error",
+    "code": "duplicate-field",
+    "message": "duplicate field F in struct S"
+  },
+  {
+    "file": "dir/b,c.mprot",
+    "line": 7,
+    "column": 1,
+    "endLine": 7,
+    "endColumn": 1,
+    "severity": "error",
+    "code": "undefined-type",
+    "message": "undefined type X\n100%"
+  }
+]
+`,
+		},
+		{
+			format: "sarif",
+			expected: `{
+  "version": "2.1.0",
+  "$schema": "https://json.schemastore.org/sarif-2.1.0.json",
+  "runs": [
+    {
+      "tool": {
+        "driver": {
+          "name": "mprotc",
+          "informationUri": "https://github.com/mprot/mprotc",
+          "rules": [
+            {
+              "id": "duplicate-field"
+            },
+            {
+              "id": "undefined-type"
+            }
+          ]
+        }
+      },
+      "results": [
+        {
+          "ruleId": "duplicate-field",
+          "level": "error",
+          "message": {
+            "text": "duplicate field F in struct S"
+          },
+          "locations": [
+            {
+              "physicalLocation": {
+                "artifactLocation": {
+                  "uri": "a.mprot"
+                },
+                "region": {
+                  "startLine": 3,
+                  "startColumn": 2,
+                  "endLine": 3,
+                  "endColumn": 14
+                }
+              }
+            }
+          ]
+        },
+        {
+          "ruleId": "undefined-type",
+          "level": "error",
+          "message": {
+            "text": "undefined type X\n100%"
+          },
+          "locations": [
+            {
+              "physicalLocation": {
+                "artifactLocation": {
+                  "uri": "dir/b,c.mprot"
+                },
+                "region": {
+                  "startLine": 7,
+                  "startColumn": 1,
+                  "endLine": 7,
+                  "endColumn": 1
+                }
+              }
+            }
+          ]
+        }
+      ]
+    }
+  ]
+}
+`,
+		},
+		{
+			format: "github",
+			expected: "::error file=a.mprot,line=3,col=2,endLine=3,endColumn=14,title=duplicate-field::duplicate field F in struct S\n" +
+				"::error file=dir/b%2Cc.mprot,line=7,col=1,endLine=7,endColumn=1,title=undefined-type::undefined type X%0A100%25\n",
+		},
+	}
+
+	for _, test := range tests {
+		f, has := errorFormats[test.format]
+		if !has {
+			t.Fatalf("unknown error format %q", test.format)
+		}
+
+		var buf bytes.Buffer
+		if err := f.print(&buf, testErrors); err != nil {
+			t.Fatalf("unexpected error for format %q: %v", test.format, err)
+		}
+		if buf.String() != test.expected {
+			t.Errorf("unexpected %s output:\n%s\nexpected:\n%s", test.format, buf.String(), test.expected)
+		}
+	}
+}
+
+func TestPrintTextErrorsLimit(t *testing.T) {
+	var errs schema.ErrorList
+	for i := 0; i < 7; i++ {
+		filename := fmt.Sprintf("%c.mprot", 'a'+i)
+		for line := 1; line <= 7; line++ {
+			errs = append(errs, schema.Error{Pos: schema.Pos{File: filename, Line: line, Column: 1}, Text: "error"})
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := printTextErrors(&buf, errs); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var expected strings.Builder
+	for i := 0; i < 5; i++ {
+		filename := fmt.Sprintf("%c.mprot", 'a'+i)
+		fmt.Fprintf(&expected, "# %s\n", filename)
+		for line := 1; line <= 5; line++ {
+			fmt.Fprintf(&expected, "%s:%d:1: error\n", filename, line)
+		}
+	}
+	if buf.String() != expected.String() {
+		t.Errorf("unexpected output:\n%s\nexpected:\n%s", buf.String(), expected.String())
+	}
+}
+
+func TestPrintNoErrors(t *testing.T) {
+	tests := map[string]string{
+		"text":   "",
+		"json":   "[]\n",
+		"github": "",
+	}
+
+	for format, expected := range tests {
+		var buf bytes.Buffer
+		if err := errorFormats[format].print(&buf, nil); err != nil {
+			t.Fatalf("unexpected error for format %q: %v", format, err)
+		}
+		if buf.String() != expected {
+			t.Errorf("unexpected %s output: %q", format, buf.String())
+		}
+	}
+}
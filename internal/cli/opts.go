@@ -3,14 +3,31 @@ package cli
 import (
 	"flag"
 	"fmt"
+	"strconv"
 	"strings"
 	"unicode"
 )
 
 type option struct {
-	val   any
-	usage string
-	help  string
+	val     any
+	usage   string
+	help    string
+	aliases []string
+}
+
+// stringList is a repeatable string option.
+type stringList []string
+
+func (l *stringList) String() string {
+	if l == nil {
+		return ""
+	}
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(s string) error {
+	*l = append(*l, s)
+	return nil
 }
 
 // Opts represents a set of command line options.
@@ -37,6 +54,11 @@ func (o *Opts) RegisterAt(fset *flag.FlagSet) {
 			fset.IntVar(val, name, *val, opt.usage)
 		case *string:
 			fset.StringVar(val, name, *val, opt.usage)
+		case *stringList:
+			fset.Var(val, name, opt.usage)
+		}
+		for _, alias := range opt.aliases {
+			fset.Var(fset.Lookup(name).Value, alias, opt.usage)
 		}
 	}
 }
@@ -50,7 +72,7 @@ func (o *Opts) ForEach(f func(usage, help string)) {
 }
 
 // AddBool adds a boolean option. The option name is determined by the
-// usage, which should be something like "--bool-opt".
+// usage, which should be something like "--bool-opt" or "-b".
 func (o *Opts) AddBool(usage string, val bool, help string) {
 	b := new(bool)
 	*b = val
@@ -73,6 +95,14 @@ func (o *Opts) AddString(usage string, val string, help string) {
 	o.add(s, usage, help)
 }
 
+// AddStrings adds a string option, which can be specified multiple times.
+// The option name is determined by the usage, which should be something like
+// "--string-opt <s>". An alias can be given by separating both names with a
+// comma, e.g. "-s, --string-opt <s>".
+func (o *Opts) AddStrings(usage string, help string) {
+	o.add(new(stringList), usage, help)
+}
+
 // Bool returns the value of the boolean option with the given name. If
 // this option is not boolean, it will panic.
 func (o *Opts) Bool(name string) bool {
@@ -100,24 +130,110 @@ func (o *Opts) String(name string) string {
 	return ""
 }
 
+// Strings returns all values of the repeatable string option with the given
+// name. If this option is not a repeatable string, it will panic.
+func (o *Opts) Strings(name string) []string {
+	if v := o.get(name); v != nil {
+		return *v.(*stringList)
+	}
+	return nil
+}
+
+// Set sets the value of the option with the given name from its string
+// representation. For repeatable options, the value is appended.
+func (o *Opts) Set(name string, value string) error {
+	opt, has := o.opts[name]
+	if !has {
+		return fmt.Errorf("unknown option %q", name)
+	}
+
+	switch val := opt.val.(type) {
+	case *bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid value %q for option %q", value, name)
+		}
+		*val = b
+	case *int:
+		i, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid value %q for option %q", value, name)
+		}
+		*val = i
+	case *string:
+		*val = value
+	case *stringList:
+		return val.Set(value)
+	}
+	return nil
+}
+
 func (o *Opts) add(val any, usage string, help string) {
-	usage = "--" + strings.TrimLeft(usage, "-")
-	name := usage[2:]
+	var aliases []string
+	if idx := strings.Index(usage, ", "); idx >= 0 {
+		alias := strings.TrimLeft(usage[:idx], "-")
+		usage = usage[idx+2:]
+		aliases = append(aliases, alias)
+	}
+
+	usage = strings.TrimLeft(usage, "-")
+	name := usage
 	if idx := strings.IndexFunc(name, unicode.IsSpace); idx >= 0 {
 		name = name[:idx]
 	}
+	if len(name) == 1 {
+		usage = "-" + usage // short option
+	} else {
+		usage = "--" + usage
+	}
+	for _, alias := range aliases {
+		if len(alias) == 1 {
+			usage = "-" + alias + ", " + usage
+		} else {
+			usage = "--" + alias + ", " + usage
+		}
+	}
 
-	if _, has := o.opts[name]; has {
-		panic(fmt.Sprintf("option %q already defined", name))
+	for _, n := range append([]string{name}, aliases...) {
+		if _, has := o.opts[n]; has {
+			panic(fmt.Sprintf("option %q already defined", n))
+		}
 	}
-	o.opts[name] = option{
-		val:   val,
-		usage: usage,
-		help:  help,
+	opt := option{
+		val:     val,
+		usage:   usage,
+		help:    help,
+		aliases: aliases,
+	}
+	o.opts[name] = opt
+	for _, alias := range aliases {
+		o.opts[alias] = opt
 	}
 	o.names = append(o.names, name)
 }
 
+// key returns a string representation of the values of all options, which
+// are declared by the given declaration function.
+func (o *Opts) key(declare func(opts *Opts)) string {
+	declared := NewOpts()
+	declare(declared)
+
+	var key strings.Builder
+	for _, name := range declared.names {
+		switch val := o.get(name).(type) {
+		case *stringList:
+			fmt.Fprintf(&key, " %s=%q", name, []string(*val))
+		case *bool:
+			fmt.Fprintf(&key, " %s=%v", name, *val)
+		case *int:
+			fmt.Fprintf(&key, " %s=%v", name, *val)
+		case *string:
+			fmt.Fprintf(&key, " %s=%q", name, *val)
+		}
+	}
+	return key.String()
+}
+
 func (o *Opts) get(name string) any {
 	if opt, has := o.opts[name]; has {
 		return opt.val
@@ -0,0 +1,111 @@
+package cli
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/mprot/mprotc/plugin"
+)
+
+// testPluginEnv is set, if the test binary is executed as a fake plugin.
+const testPluginEnv = "MPROTC_TEST_PLUGIN"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(testPluginEnv) != "" {
+		plugin.Main(fakePlugin)
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// fakePlugin generates a file per schema file with the package name and the
+// value of the option "suffix".
+func fakePlugin(req *plugin.Request) (*plugin.Response, error) {
+	s, err := req.Descriptor.Schema()
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &plugin.Response{}
+	for _, f := range s {
+		resp.Files = append(resp.Files, plugin.File{
+			Name:    strings.TrimSuffix(f.Name, ".mprot") + ".txt",
+			Source:  f.Name,
+			Content: req.Language + " " + f.Package.Name + req.Options["suffix"] + "\n",
+		})
+	}
+	return resp, nil
+}
+
+func TestExecPlugin(t *testing.T) {
+	executable, err := os.Executable()
+	if err != nil {
+		t.Skipf("test executable not found: %v", err)
+	}
+
+	// install the test binary as plugin in PATH
+	binDir := t.TempDir()
+	pluginName := pluginPrefix + "fake"
+	if runtime.GOOS == "windows" {
+		pluginName += ".exe"
+	}
+	copyFile(t, executable, filepath.Join(binDir, pluginName))
+	t.Setenv("PATH", binDir)
+	t.Setenv(testPluginEnv, "1")
+
+	dir := t.TempDir()
+	root := filepath.Join(dir, "schema")
+	out := filepath.Join(dir, "out")
+	writeFiles(t, root, map[string]string{
+		"a.mprot":     "package a\n",
+		"sub/b.mprot": "package sub\n",
+	})
+
+	err = Commands{}.Exec("fake", []string{"--root", root, "--out", out, "--opt", "suffix=!", "**/*.mprot"})
+	if err != nil {
+		t.Fatalf("unexpected exec error: %v", err)
+	}
+	for name, expected := range map[string]string{
+		"a.txt":     "fake a!\n",
+		"sub/b.txt": "fake sub!\n",
+	} {
+		content, err := os.ReadFile(filepath.Join(out, filepath.FromSlash(name)))
+		if err != nil {
+			t.Errorf("missing generated file: %v", err)
+		} else if string(content) != expected {
+			t.Errorf("unexpected content of %s: %q (expected %q)", name, content, expected)
+		}
+	}
+
+	// plugins which are not in PATH are unknown commands
+	if err := (Commands{}).Exec("missing", nil); err == nil || err.Error() != `unknown command "missing"` {
+		t.Errorf("unexpected error for missing plugin: %v", err)
+	}
+	if _, has := (Commands{}).lookup("../fake"); has {
+		t.Errorf("unexpected plugin for a path")
+	}
+}
+
+func copyFile(t *testing.T, src, dst string) {
+	t.Helper()
+	in, err := os.Open(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		t.Fatal(err)
+	}
+	if err := out.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,163 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/mprot/mprotc/generator"
+	"github.com/mprot/mprotc/schema"
+)
+
+// ProjectFilename is the default name of the project file.
+const ProjectFilename = "mprotc.json"
+
+// Project describes the schema inputs and the generator targets of a project
+// file. Relative paths are relative to the directory of the project file.
+type Project struct {
+	Root       string   `json:"root"`       // root directory of the schema files
+	Inputs     []string `json:"inputs"`     // glob patterns of the schema files
+	Include    []string `json:"include"`    // include directories for imports
+	Deprecated bool     `json:"deprecated"` // keep deprecated declarations?
+	Targets    []Target `json:"targets"`
+}
+
+// Target describes a single generator run of a project. The options are
+// the command line options of the language without the leading dashes, e.g.
+// "scoped-enums" for Go or "typedecls" for JavaScript. The options of an
+// external generator plugin are passed to the plugin as they are.
+type Target struct {
+	Language string                 `json:"language"`
+	Out      string                 `json:"out"`
+	Options  map[string]interface{} `json:"options"`
+}
+
+// ReadProject reads the project file with the given name.
+func ReadProject(filename string) (*Project, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var p Project
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+
+	dir := filepath.Dir(filename)
+	p.Root = projectPath(dir, p.Root)
+	for i := range p.Include {
+		p.Include[i] = projectPath(dir, p.Include[i])
+	}
+	for i := range p.Targets {
+		p.Targets[i].Out = projectPath(dir, p.Targets[i].Out)
+	}
+	return &p, nil
+}
+
+// projectPath returns the given path of a project file in the directory dir.
+// Absolute paths are returned as they are.
+func projectPath(dir string, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
+
+// BuildOptions holds the options for building a project.
+type BuildOptions struct {
+	Jobs    int
+	DryRun  bool // print the names of the generated files only?
+	Check   bool // check that the generated files are up to date?
+	Verbose bool // print the status of each written file?
+}
+
+// Build parses the schema of the given project once and runs the generators
+// of all targets.
+func (c Commands) Build(p *Project, bo BuildOptions) error {
+	generators := make([]*generator.Generator, 0, len(p.Targets))
+	options := make([]generator.Options, 0, len(p.Targets))
+	prune := make([]bool, 0, len(p.Targets))
+	for i, target := range p.Targets {
+		cmd, has := c.lookup(target.Language)
+		if !has || cmd.isTool() {
+			return fmt.Errorf("target %d: unknown language %q", i+1, target.Language)
+		}
+
+		opts := cmd.newOpts()
+		names := make([]string, 0, len(target.Options))
+		for name := range target.Options {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if err := setProjectOption(opts, cmd.plugin, name, target.Options[name]); err != nil {
+				return fmt.Errorf("target %d (%s): %v", i+1, target.Language, err)
+			}
+		}
+
+		genOpts := generatorOptions(opts, p.Inputs)
+		genOpts.RootDirectory = p.Root
+		genOpts.IncludeDirectories = p.Include
+		genOpts.OutputDirectory = target.Out
+		genOpts.Jobs = bo.Jobs
+		genOpts.UseCache = genOpts.UseCache && !bo.Check
+		generators = append(generators, cmd.Generator(opts))
+		options = append(options, genOpts)
+		prune = append(prune, opts.Bool("prune"))
+	}
+
+	s, err := schema.Config{IncludeDirs: p.Include, Jobs: bo.Jobs}.Parse(p.Root, p.Inputs)
+	if err != nil {
+		return err
+	}
+	if !p.Deprecated {
+		s.RemoveDeprecated()
+	}
+
+	outdated := 0
+	for i, gen := range generators {
+		if err := gen.GenerateSchema(s, options[i]); err != nil {
+			return err
+		}
+
+		n, err := output(gen, outputOptions{
+			dryRun:  bo.DryRun,
+			check:   bo.Check,
+			prune:   prune[i],
+			verbose: bo.Verbose,
+		})
+		if err != nil {
+			return err
+		}
+		outdated += n
+	}
+
+	return outdatedError(outdated)
+}
+
+func setProjectOption(opts *Opts, plugin bool, name string, value interface{}) error {
+	switch name {
+	case "root", "out", "out-archive", "include", "deprecated", "jobs", "watch", "dryrun", "check", "error-format":
+		return fmt.Errorf("option %q cannot be set for a target", name)
+	}
+
+	if plugin && name != "cache" && name != "prune" {
+		// plugin options are passed as they are
+		return opts.Set("opt", name+"="+fmt.Sprint(value))
+	}
+
+	switch value := value.(type) {
+	case []interface{}:
+		for _, v := range value {
+			if err := opts.Set(name, fmt.Sprint(v)); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return opts.Set(name, fmt.Sprint(value))
+	}
+}
@@ -0,0 +1,223 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mprot/mprotc/generator"
+)
+
+// testCommands returns a go and a js command with a subset of the options of
+// the real commands.
+func testCommands() Commands {
+	return Commands{
+		"go": Command{
+			Options: func(opts *Opts) {
+				opts.AddBool("--scoped-enums", false, "Scope the enumerators.")
+			},
+			Generator: func(opts *Opts) *generator.Generator {
+				return generator.NewGolang(generator.GolangOptions{
+					ScopedEnums: opts.Bool("scoped-enums"),
+				})
+			},
+		},
+		"js": Command{
+			Options: func(opts *Opts) {
+				opts.AddBool("--typedecls", false, "Generate type declarations.")
+			},
+			Generator: func(opts *Opts) *generator.Generator {
+				return generator.NewJavascript(generator.JavascriptOptions{
+					TypeDeclarations: opts.Bool("typedecls"),
+				})
+			},
+		},
+	}
+}
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		filename := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestReadProject(t *testing.T) {
+	dir := t.TempDir()
+	absInclude := filepath.Join(t.TempDir(), "include")
+	absOut := filepath.Join(t.TempDir(), "out")
+
+	writeFiles(t, dir, map[string]string{
+		"proj/mprotc.json": `{
+			"root": "schema",
+			"inputs": ["**/*.mprot"],
+			"include": ["vendor", ` + quote(absInclude) + `],
+			"targets": [
+				{"language": "go", "out": "gen/go"},
+				{"language": "js", "out": ` + quote(absOut) + `},
+				{"language": "js"}
+			]
+		}`,
+	})
+
+	p, err := ReadProject(filepath.Join(dir, "proj", ProjectFilename))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	projDir := filepath.Join(dir, "proj")
+	if expected := filepath.Join(projDir, "schema"); p.Root != expected {
+		t.Errorf("unexpected root: %s (expected %s)", p.Root, expected)
+	}
+	if expected := []string{filepath.Join(projDir, "vendor"), absInclude}; !reflect.DeepEqual(p.Include, expected) {
+		t.Errorf("unexpected include directories: %v (expected %v)", p.Include, expected)
+	}
+	expectedOuts := []string{filepath.Join(projDir, "gen", "go"), absOut, projDir}
+	for i, target := range p.Targets {
+		if target.Out != expectedOuts[i] {
+			t.Errorf("unexpected output directory of target %d: %s (expected %s)", i+1, target.Out, expectedOuts[i])
+		}
+	}
+
+	if _, err := ReadProject(filepath.Join(dir, "missing.json")); err == nil {
+		t.Errorf("expected error for missing project file")
+	}
+	writeFiles(t, dir, map[string]string{"invalid.json": "{"})
+	if _, err := ReadProject(filepath.Join(dir, "invalid.json")); err == nil || !strings.HasPrefix(err.Error(), filepath.Join(dir, "invalid.json")+": ") {
+		t.Errorf("unexpected error for invalid project file: %v", err)
+	}
+}
+
+func quote(s string) string {
+	return `"` + strings.ReplaceAll(s, `\`, `\\`) + `"`
+}
+
+func TestSetProjectOption(t *testing.T) {
+	newOpts := func() *Opts {
+		cmd := Command{Options: func(opts *Opts) {
+			opts.AddBool("--typedecls", false, "")
+			opts.AddStrings("--opt <name=value>", "")
+		}}
+		return cmd.newOpts()
+	}
+
+	// target options override the defaults
+	opts := newOpts()
+	for name, value := range map[string]interface{}{"typedecls": true, "cache": true, "include": []interface{}{"a", "b"}} {
+		err := setProjectOption(opts, false, name, value)
+		if name == "include" {
+			if err == nil {
+				t.Errorf("expected error for option %q", name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("unexpected error for option %q: %v", name, err)
+		}
+	}
+	if !opts.Bool("typedecls") || !opts.Bool("cache") {
+		t.Errorf("options not set")
+	}
+
+	// list values set repeatable options
+	opts = newOpts()
+	if err := setProjectOption(opts, false, "opt", []interface{}{"a=1", "b=2"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := []string{"a=1", "b=2"}; !reflect.DeepEqual(opts.Strings("opt"), expected) {
+		t.Errorf("unexpected list option: %v", opts.Strings("opt"))
+	}
+
+	// plugin options are passed as they are, except the generic ones
+	opts = newOpts()
+	for name, value := range map[string]interface{}{"level": 3, "prune": true} {
+		if err := setProjectOption(opts, true, name, value); err != nil {
+			t.Fatalf("unexpected error for plugin option %q: %v", name, err)
+		}
+	}
+	if expected := []string{"level=3"}; !reflect.DeepEqual(opts.Strings("opt"), expected) || !opts.Bool("prune") {
+		t.Errorf("unexpected plugin options: %v", opts.Strings("opt"))
+	}
+
+	// unknown options and invalid values
+	if err := setProjectOption(newOpts(), false, "unknown", true); err == nil {
+		t.Errorf("expected error for unknown option")
+	}
+	if err := setProjectOption(newOpts(), false, "typedecls", "yes please"); err == nil {
+		t.Errorf("expected error for invalid value")
+	}
+}
+
+func TestBuild(t *testing.T) {
+	dir := t.TempDir()
+	absOut := filepath.Join(t.TempDir(), "js")
+
+	writeFiles(t, dir, map[string]string{
+		"schema/api/color.mprot": "package api\n\nenum Color {\n\tRed \"1\"\n}\n",
+		"schema/api/item.mprot":  "package api\n\nstruct Item {\n\tID int \"1\"\n\tOld int \"2 deprecated\"\n}\n",
+		"mprotc.json": `{
+			"root": "schema",
+			"inputs": ["**/*.mprot"],
+			"targets": [
+				{"language": "go", "out": "gen/go", "options": {"scoped-enums": true}},
+				{"language": "go", "out": "gen/go-unscoped"},
+				{"language": "js", "out": ` + quote(absOut) + `, "options": {"typedecls": true}}
+			]
+		}`,
+	})
+
+	p, err := ReadProject(filepath.Join(dir, ProjectFilename))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := testCommands().Build(p, BuildOptions{}); err != nil {
+		t.Fatalf("unexpected build error: %v", err)
+	}
+
+	read := func(filename string) string {
+		t.Helper()
+		content, err := os.ReadFile(filename)
+		if err != nil {
+			t.Fatalf("missing generated file: %v", err)
+		}
+		return string(content)
+	}
+
+	if src := read(filepath.Join(dir, "gen", "go", "api", "color.go")); !strings.Contains(src, "ColorRed") {
+		t.Errorf("enumerators not scoped:\n%s", src)
+	}
+	if src := read(filepath.Join(dir, "gen", "go-unscoped", "api", "color.go")); strings.Contains(src, "ColorRed") {
+		t.Errorf("unexpected scoped enumerators:\n%s", src)
+	}
+	read(filepath.Join(absOut, "api", "color.js"))
+	read(filepath.Join(absOut, "api", "color.d.ts"))
+	if _, err := os.Stat(filepath.Join(dir, filepath.FromSlash(absOut))); !os.IsNotExist(err) {
+		t.Errorf("absolute output directory joined with the project directory")
+	}
+	if src := read(filepath.Join(dir, "gen", "go", "api", "item.go")); strings.Contains(src, "Old") {
+		t.Errorf("unexpected deprecated field:\n%s", src)
+	}
+
+	// the generated files are up to date
+	if err := testCommands().Build(p, BuildOptions{Check: true}); err != nil {
+		t.Errorf("unexpected check error: %v", err)
+	}
+
+	// invalid targets
+	p.Targets = []Target{{Language: "go"}, {Language: "cobol"}}
+	if err := testCommands().Build(p, BuildOptions{}); err == nil || err.Error() != `target 2: unknown language "cobol"` {
+		t.Errorf("unexpected error for unknown language: %v", err)
+	}
+	p.Targets = []Target{{Language: "go", Options: map[string]interface{}{"jobs": 2}}}
+	if err := testCommands().Build(p, BuildOptions{}); err == nil || err.Error() != `target 1 (go): option "jobs" cannot be set for a target` {
+		t.Errorf("unexpected error for reserved option: %v", err)
+	}
+}
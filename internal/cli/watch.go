@@ -0,0 +1,213 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/mprot/mprotc/generator"
+	"github.com/mprot/mprotc/schema"
+)
+
+const watchInterval = 500 * time.Millisecond
+
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// watcher polls the schema files in the root directory and the files they
+// import for changes.
+type watcher struct {
+	root        string
+	patterns    []string
+	includeDirs []string
+	imports     []string // imported files, which are not matched by the patterns
+}
+
+// watch generates the code for all schema files and then polls the schema
+// files and their imports for changes. After a change, the whole schema is
+// parsed again, so that the validation of the complete schema sees all files.
+// The build cache is always used in watch mode, so only the changed schema
+// files and the files importing them directly or transitively are generated
+// again. Errors are reported, but do not stop the watching.
+func (c *Command) watch(opts *Opts, globPatterns []string, report func(error) error) error {
+	w := watcher{
+		root:        opts.String("root"),
+		patterns:    globPatterns,
+		includeDirs: opts.Strings("include"),
+	}
+
+	w.update(c.regenerate(opts, globPatterns, report).Schema())
+	stamps, err := w.snapshot()
+	if err != nil {
+		return err
+	}
+
+	for {
+		time.Sleep(watchInterval)
+
+		next, err := w.snapshot()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			continue
+		}
+
+		changed := changedFiles(stamps, next)
+		stamps = next
+		if len(changed) != 0 {
+			if w.update(c.regenerate(opts, globPatterns, report).Schema()) {
+				stamps, _ = w.snapshot()
+			}
+		}
+	}
+}
+
+// watchOptions returns the generator options in watch mode, which always use
+// the build cache.
+func watchOptions(opts *Opts, globPatterns []string) generator.Options {
+	genOpts := generatorOptions(opts, globPatterns)
+	genOpts.UseCache = true
+	return genOpts
+}
+
+// regenerate generates the code for the schema, writes the outputs and
+// prints a summary. The generator is returned, even if an error was reported.
+func (c *Command) regenerate(opts *Opts, globPatterns []string, report func(error) error) *generator.Generator {
+	gen := c.Generator(opts)
+	err := gen.Generate(watchOptions(opts, globPatterns))
+	if err == nil {
+		_, err = output(gen, outputOptions{
+			dryRun:  opts.Bool("dryrun"),
+			prune:   opts.Bool("prune"),
+			verbose: opts.Bool("verbose"),
+		})
+	}
+	if err != nil {
+		if err := report(err); err != ErrReported {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		return gen
+	}
+
+	if opts.Bool("dryrun") {
+		n := 0
+		gen.IterateFiles(func(filename string) {
+			n++
+		})
+		fmt.Fprintf(os.Stderr, "%s: generated %d files (%s)\n", binName, n, time.Now().Format("15:04:05"))
+		return gen
+	}
+
+	counts := make(map[generator.FileStatus]int)
+	gen.IterateStatus(func(filename string, status generator.FileStatus) {
+		counts[status]++
+	})
+	fmt.Fprintf(os.Stderr, "%s: %d files created, %d updated, %d unchanged (%s)\n", binName,
+		counts[generator.FileCreated], counts[generator.FileUpdated], counts[generator.FileUnchanged], time.Now().Format("15:04:05"))
+	return gen
+}
+
+// update records the files imported by the given schema, which are not
+// matched by the glob patterns themselves, e.g. imports found in an include
+// directory. Their imports are followed transitively. It reports whether the
+// set of imported files changed. If the schema could not be parsed, the
+// previously recorded files are kept.
+func (w *watcher) update(s schema.Schema) bool {
+	if s == nil {
+		return false
+	}
+
+	known := make(map[string]struct{}, len(s))
+	for _, f := range s {
+		known[filepath.Join(w.root, f.Name)] = struct{}{}
+	}
+
+	var (
+		imports []string
+		visited = make(map[string]struct{})
+		queue   []string
+	)
+	follow := func(from string, imps map[string]*schema.Import) {
+		for _, imp := range imps {
+			location := filepath.Join(filepath.Dir(from), filepath.FromSlash(imp.Path))
+			if imp.IncludeDir != "" {
+				location = filepath.Join(imp.IncludeDir, filepath.FromSlash(imp.Path))
+			}
+			if _, has := known[location]; has {
+				continue
+			}
+			if _, has := visited[location]; !has {
+				visited[location] = struct{}{}
+				imports = append(imports, location)
+				queue = append(queue, location)
+			}
+		}
+	}
+
+	for _, f := range s {
+		follow(filepath.Join(w.root, f.Name), f.Imports)
+	}
+	for len(queue) != 0 {
+		location := queue[0]
+		queue = queue[1:]
+
+		config := schema.Config{IncludeDirs: w.includeDirs, Jobs: 1}
+		if s, _ := config.Parse(filepath.Dir(location), []string{filepath.Base(location)}); len(s) != 0 {
+			follow(location, s[0].Imports)
+		}
+	}
+
+	sort.Strings(imports)
+	changed := len(imports) != len(w.imports)
+	for i := 0; !changed && i < len(imports); i++ {
+		changed = imports[i] != w.imports[i]
+	}
+	w.imports = imports
+	return changed
+}
+
+// snapshot returns the modification times and sizes of all schema files and
+// the recorded imports by their path.
+func (w *watcher) snapshot() (map[string]fileStamp, error) {
+	filenames, err := schema.Glob(os.DirFS(w.root), w.patterns)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := make([]string, 0, len(filenames)+len(w.imports))
+	for _, filename := range filenames {
+		paths = append(paths, filepath.Join(w.root, filepath.FromSlash(filename)))
+	}
+	paths = append(paths, w.imports...)
+
+	stamps := make(map[string]fileStamp, len(paths))
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			continue // removed in the meantime
+		}
+		stamps[path] = fileStamp{modTime: info.ModTime(), size: info.Size()}
+	}
+	return stamps, nil
+}
+
+// changedFiles returns the files, which were added, modified, or removed.
+func changedFiles(prev, next map[string]fileStamp) []string {
+	var changed []string
+	for filename, stamp := range next {
+		prevStamp, has := prev[filename]
+		if !has || !prevStamp.modTime.Equal(stamp.modTime) || prevStamp.size != stamp.size {
+			changed = append(changed, filename)
+		}
+	}
+	for filename := range prev {
+		if _, has := next[filename]; !has {
+			changed = append(changed, filename)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
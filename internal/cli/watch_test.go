@@ -0,0 +1,115 @@
+package cli
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestChangedFiles(t *testing.T) {
+	now := time.Now()
+	prev := map[string]fileStamp{
+		"same.mprot":    {modTime: now, size: 10},
+		"touched.mprot": {modTime: now, size: 10},
+		"resized.mprot": {modTime: now, size: 10},
+		"removed.mprot": {modTime: now, size: 10},
+	}
+	next := map[string]fileStamp{
+		"same.mprot":    {modTime: now, size: 10},
+		"touched.mprot": {modTime: now.Add(time.Second), size: 10},
+		"resized.mprot": {modTime: now, size: 11},
+		"added.mprot":   {modTime: now, size: 10},
+	}
+
+	expected := []string{"added.mprot", "removed.mprot", "resized.mprot", "touched.mprot"}
+	if changed := changedFiles(prev, next); !reflect.DeepEqual(changed, expected) {
+		t.Errorf("unexpected changed files: %v", changed)
+	}
+	if changed := changedFiles(next, next); len(changed) != 0 {
+		t.Errorf("unexpected changed files: %v", changed)
+	}
+}
+
+func TestWatchRegenerate(t *testing.T) {
+	dir := t.TempDir()
+	root := filepath.Join(dir, "schema")
+	include := filepath.Join(dir, "include")
+	out := filepath.Join(dir, "out")
+
+	writeFiles(t, root, map[string]string{
+		"a.mprot": "package a\n\nimport \"b.mprot\"\n\nstruct A {\n\tB b.B \"1\"\n}\n",
+		"b.mprot": "package a\n\nstruct B {\n}\n",
+		"c.mprot": "package a\n\nimport \"shared/d.mprot\"\n\nstruct C {\n\tD d.D \"1\"\n}\n",
+	})
+	writeFiles(t, include, map[string]string{
+		"shared/d.mprot": "package shared\n\nimport \"e.mprot\"\n\nstruct D {\n\tE e.E \"1\"\n}\n",
+		"shared/e.mprot": "package shared\n\nstruct E {\n}\n",
+	})
+
+	cmd := testCommands()["go"]
+	opts := cmd.newOpts()
+	for name, value := range map[string]string{"root": root, "out": out, "include": include} {
+		if err := opts.Set(name, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	report := func(err error) error {
+		t.Errorf("unexpected error: %v", err)
+		return err
+	}
+
+	w := watcher{root: root, patterns: []string{"*.mprot"}, includeDirs: []string{include}}
+	regenerate := func() []string {
+		gen := cmd.regenerate(opts, w.patterns, report)
+		w.update(gen.Schema())
+
+		var names []string
+		gen.IterateFiles(func(filename string) {
+			names = append(names, filepath.Base(filename))
+		})
+		return names
+	}
+
+	if generated := regenerate(); !reflect.DeepEqual(generated, []string{"a.go", "b.go", "c.go"}) {
+		t.Errorf("unexpected generated files of the first run: %v", generated)
+	}
+	expectedImports := []string{filepath.Join(include, "shared", "d.mprot"), filepath.Join(include, "shared", "e.mprot")}
+	if !reflect.DeepEqual(w.imports, expectedImports) {
+		t.Errorf("unexpected imports: %v", w.imports)
+	}
+
+	tests := []struct {
+		dir      string
+		files    map[string]string
+		expected []string // regenerated files
+	}{
+		{dir: root, files: map[string]string{"b.mprot": "package a\n\nstruct B {\n\tX int \"1\"\n}\n"}, expected: []string{"a.go", "b.go"}},
+		{dir: root, files: map[string]string{"a.mprot": "package a\n\nimport \"b.mprot\"\n\nstruct A {\n\tB *b.B \"1\"\n}\n"}, expected: []string{"a.go"}},
+		{dir: include, files: map[string]string{"shared/e.mprot": "package shared\n\nstruct E {\n\tX int \"1\"\n}\n"}, expected: []string{"c.go"}},
+		{dir: root, files: map[string]string{"f.mprot": "package a\n"}, expected: []string{"f.go"}},
+	}
+
+	for i, test := range tests {
+		stamps, err := w.snapshot()
+		if err != nil {
+			t.Fatal(err)
+		}
+		writeFiles(t, test.dir, test.files)
+		next, err := w.snapshot()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var expectedChanged []string
+		for name := range test.files {
+			expectedChanged = append(expectedChanged, filepath.Join(test.dir, filepath.FromSlash(name)))
+		}
+		if changed := changedFiles(stamps, next); !reflect.DeepEqual(changed, expectedChanged) {
+			t.Errorf("unexpected changed files for change %d: %v", i+1, changed)
+		}
+		if generated := regenerate(); !reflect.DeepEqual(generated, test.expected) {
+			t.Errorf("unexpected regenerated files for change %d: %v (expected %v)", i+1, generated, test.expected)
+		}
+	}
+}
@@ -0,0 +1,341 @@
+// Package compat detects changes between two versions of an mprot schema,
+// which break the wire compatibility.
+package compat
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/mprot/mprotc/schema"
+)
+
+// Kind describes the kind of an incompatible change.
+type Kind string
+
+// Kinds of incompatible changes.
+const (
+	DeclRemoved            Kind = "decl-removed"
+	DeclKindChanged        Kind = "decl-kind-changed"
+	FieldOrdinalChanged    Kind = "field-ordinal-changed"
+	FieldTypeChanged       Kind = "field-type-changed"
+	OrdinalReused          Kind = "ordinal-reused"
+	EnumeratorRemoved      Kind = "enumerator-removed"
+	EnumeratorValueChanged Kind = "enumerator-value-changed"
+	BranchRemoved          Kind = "branch-removed"
+	BranchTypeChanged      Kind = "branch-type-changed"
+	MethodRemoved          Kind = "method-removed"
+	MethodOrdinalChanged   Kind = "method-ordinal-changed"
+	MethodSignatureChanged Kind = "method-signature-changed"
+)
+
+// Change describes an incompatible change of a schema declaration.
+type Change struct {
+	Kind Kind
+	Pos  schema.Pos // position of the declaration in the new schema, or in the old one if it was removed
+	File string     // name of the schema file
+	Path string     // declaration path, e.g. Struct.Field
+	Text string
+}
+
+// Key returns the key of the change, which is used to acknowledge the change
+// in an allowlist.
+func (c Change) Key() string {
+	return string(c.Kind) + " " + c.File + ":" + c.Path
+}
+
+// Compare compares the old and the new schema and returns all incompatible
+// changes. Declarations are matched by their name within the directory of
+// their file, so they may move between the files of a package. Removed
+// declarations and declarations, which changed their kind, are incompatible.
+func Compare(old, new schema.Schema) []Change {
+	newDecls := make(map[string]map[string]schema.Decl) // directory => name => declaration
+	for _, f := range new {
+		dir := filepath.Dir(f.Name)
+		if newDecls[dir] == nil {
+			newDecls[dir] = make(map[string]schema.Decl)
+		}
+		for name, decl := range declsByName(f) {
+			newDecls[dir][name] = decl
+		}
+	}
+
+	var changes []Change
+	for _, oldFile := range old {
+		c := comparer{file: oldFile.Name}
+		decls := newDecls[filepath.Dir(oldFile.Name)]
+		for _, oldDecl := range oldFile.Decls {
+			name := declName(oldDecl)
+			if name == "" {
+				continue
+			}
+
+			newDecl, has := decls[name]
+			if !has {
+				c.pos = oldDecl.Pos()
+				c.report(DeclRemoved, name, "%s %s removed", declKind(oldDecl), name)
+				continue
+			}
+
+			c.pos = newDecl.Pos()
+			if declKind(oldDecl) != declKind(newDecl) {
+				c.report(DeclKindChanged, name, "%s %s changed to %s", declKind(oldDecl), name, declKind(newDecl))
+				continue
+			}
+
+			switch oldDecl := oldDecl.(type) {
+			case *schema.Enum:
+				c.compareEnum(oldDecl, newDecl.(*schema.Enum))
+			case *schema.Struct:
+				c.compareStruct(oldDecl, newDecl.(*schema.Struct))
+			case *schema.Union:
+				c.compareUnion(oldDecl, newDecl.(*schema.Union))
+			case *schema.Service:
+				c.compareService(oldDecl, newDecl.(*schema.Service))
+			}
+		}
+		changes = append(changes, c.changes...)
+	}
+	return changes
+}
+
+// Allowlist holds the keys of acknowledged changes.
+type Allowlist map[string]struct{}
+
+// ReadAllowlist reads an allowlist. Each line holds the key of an acknowledged
+// change. Empty lines and lines starting with '#' are ignored.
+func ReadAllowlist(r io.Reader) (Allowlist, error) {
+	allowed := make(Allowlist)
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			allowed[strings.Join(strings.Fields(line), " ")] = struct{}{}
+		}
+	}
+	return allowed, sc.Err()
+}
+
+// Allows reports whether the given change is acknowledged.
+func (a Allowlist) Allows(c Change) bool {
+	_, has := a[c.Key()]
+	return has
+}
+
+type comparer struct {
+	file    string
+	pos     schema.Pos
+	changes []Change
+}
+
+func (c *comparer) report(kind Kind, path string, format string, args ...interface{}) {
+	c.changes = append(c.changes, Change{
+		Kind: kind,
+		Pos:  c.pos,
+		File: c.file,
+		Path: path,
+		Text: fmt.Sprintf(format, args...),
+	})
+}
+
+func (c *comparer) compareEnum(old, new *schema.Enum) {
+	newEnumerators := make(map[string]schema.Enumerator, len(new.Enumerators))
+	for _, en := range new.Enumerators {
+		newEnumerators[en.Name] = en
+	}
+	newValues := make(map[int64]struct{}, len(new.Enumerators))
+	for _, en := range new.Enumerators {
+		newValues[en.Value] = struct{}{}
+	}
+
+	for _, oldEn := range old.Enumerators {
+		path := old.Name + "." + oldEn.Name
+		newEn, has := newEnumerators[oldEn.Name]
+		switch {
+		case has && newEn.Value != oldEn.Value:
+			c.report(EnumeratorValueChanged, path, "value of enumerator %s changed from %d to %d", path, oldEn.Value, newEn.Value)
+		case !has:
+			if _, renamed := newValues[oldEn.Value]; !renamed {
+				c.report(EnumeratorRemoved, path, "enumerator %s removed", path)
+			}
+		}
+	}
+}
+
+func (c *comparer) compareStruct(old, new *schema.Struct) {
+	newFields := make(map[string]schema.Field, len(new.Fields))
+	newOrdinals := make(map[int64]schema.Field, len(new.Fields))
+	for _, f := range new.Fields {
+		newFields[f.Name] = f
+		newOrdinals[f.Ordinal] = f
+	}
+	oldFields := make(map[string]schema.Field, len(old.Fields))
+	for _, f := range old.Fields {
+		oldFields[f.Name] = f
+	}
+
+	for _, oldField := range old.Fields {
+		path := old.Name + "." + oldField.Name
+		if newField, has := newFields[oldField.Name]; has && newField.Ordinal != oldField.Ordinal {
+			c.report(FieldOrdinalChanged, path, "ordinal of field %s changed from %d to %d", path, oldField.Ordinal, newField.Ordinal)
+		}
+
+		newField, has := newOrdinals[oldField.Ordinal]
+		if !has {
+			continue // removed fields are skipped by the readers
+		}
+
+		_, oldNameKept := newFields[oldField.Name]
+		_, newNameExisted := oldFields[newField.Name]
+		switch {
+		case newField.Name == oldField.Name:
+			if !compatibleTypes(oldField.Type, newField.Type) {
+				c.report(FieldTypeChanged, path, "type of field %s changed from %s to %s", path, oldField.Type.Name(), newField.Type.Name())
+			}
+		case oldNameKept || newNameExisted || !compatibleTypes(oldField.Type, newField.Type):
+			c.report(OrdinalReused, old.Name+"."+newField.Name, "ordinal %d of field %s reused by field %s.%s", oldField.Ordinal, path, old.Name, newField.Name)
+		}
+	}
+}
+
+func (c *comparer) compareUnion(old, new *schema.Union) {
+	newOrdinals := make(map[int64]schema.Branch, len(new.Branches))
+	for _, b := range new.Branches {
+		newOrdinals[b.Ordinal] = b
+	}
+
+	for _, oldBranch := range old.Branches {
+		path := fmt.Sprintf("%s[%d]", old.Name, oldBranch.Ordinal)
+		newBranch, has := newOrdinals[oldBranch.Ordinal]
+		switch {
+		case !has:
+			c.report(BranchRemoved, path, "branch %s with ordinal %d removed from union %s", oldBranch.Type.Name(), oldBranch.Ordinal, old.Name)
+		case !compatibleTypes(oldBranch.Type, newBranch.Type):
+			c.report(BranchTypeChanged, path, "type of branch %d in union %s changed from %s to %s", oldBranch.Ordinal, old.Name, oldBranch.Type.Name(), newBranch.Type.Name())
+		}
+	}
+}
+
+func (c *comparer) compareService(old, new *schema.Service) {
+	newMethods := make(map[string]schema.Method, len(new.Methods))
+	newOrdinals := make(map[int64]schema.Method, len(new.Methods))
+	for _, m := range new.Methods {
+		newMethods[m.Name] = m
+		newOrdinals[m.Ordinal] = m
+	}
+
+	oldMethods := make(map[string]struct{}, len(old.Methods))
+	for _, m := range old.Methods {
+		oldMethods[m.Name] = struct{}{}
+	}
+
+	for _, oldMethod := range old.Methods {
+		path := old.Name + "." + oldMethod.Name
+		newMethod, has := newMethods[oldMethod.Name]
+		if !has {
+			// a new method with the same ordinal is a renamed method
+			newMethod, has = newOrdinals[oldMethod.Ordinal]
+			if _, existed := oldMethods[newMethod.Name]; !has || existed {
+				c.report(MethodRemoved, path, "method %s removed", path)
+				continue
+			}
+		} else if newMethod.Ordinal != oldMethod.Ordinal {
+			c.report(MethodOrdinalChanged, path, "ordinal of method %s changed from %d to %d", path, oldMethod.Ordinal, newMethod.Ordinal)
+		}
+
+		if !compatibleSignatures(oldMethod, newMethod) {
+			c.report(MethodSignatureChanged, path, "signature of method %s changed from %s to %s", path, signature(oldMethod), signature(newMethod))
+		}
+	}
+}
+
+func compatibleSignatures(old, new schema.Method) bool {
+	if len(old.Args) != len(new.Args) || (old.Return == nil) != (new.Return == nil) {
+		return false
+	}
+	for i := range old.Args {
+		if !compatibleTypes(old.Args[i], new.Args[i]) {
+			return false
+		}
+	}
+	return old.Return == nil || compatibleTypes(old.Return, new.Return)
+}
+
+func signature(m schema.Method) string {
+	args := make([]string, 0, len(m.Args))
+	for _, arg := range m.Args {
+		args = append(args, arg.Name())
+	}
+
+	sig := "(" + strings.Join(args, ", ") + ")"
+	if m.Return != nil {
+		sig += " " + m.Return.Name()
+	}
+	return sig
+}
+
+// compatibleTypes reports whether values of the old type can be decoded as
+// the new type. Integers and floating-point numbers can be widened, but not
+// narrowed. Defined types are compared by name.
+func compatibleTypes(old, new schema.Type) bool {
+	switch old := old.(type) {
+	case *schema.Int:
+		n, ok := new.(*schema.Int)
+		return ok && n.Unsigned == old.Unsigned && intBits(n.Bits) >= intBits(old.Bits)
+	case *schema.Float:
+		n, ok := new.(*schema.Float)
+		return ok && intBits(n.Bits) >= intBits(old.Bits)
+	case *schema.Array:
+		n, ok := new.(*schema.Array)
+		return ok && n.Size == old.Size && compatibleTypes(old.Value, n.Value)
+	case *schema.Map:
+		n, ok := new.(*schema.Map)
+		return ok && compatibleTypes(old.Key, n.Key) && compatibleTypes(old.Value, n.Value)
+	case *schema.Pointer:
+		n, ok := new.(*schema.Pointer)
+		return ok && compatibleTypes(old.Value, n.Value)
+	default:
+		return old.Name() == new.Name()
+	}
+}
+
+func intBits(bits int) int {
+	if bits <= 0 {
+		return 64
+	}
+	return bits
+}
+
+func declsByName(f *schema.File) map[string]schema.Decl {
+	decls := make(map[string]schema.Decl, len(f.Decls))
+	for _, decl := range f.Decls {
+		if name := declName(decl); name != "" {
+			decls[name] = decl
+		}
+	}
+	return decls
+}
+
+func declKind(decl schema.Decl) string {
+	switch decl.(type) {
+	case *schema.Enum:
+		return "enum"
+	case *schema.Struct:
+		return "struct"
+	case *schema.Union:
+		return "union"
+	case *schema.Service:
+		return "service"
+	default:
+		return ""
+	}
+}
+
+func declName(decl schema.Decl) string {
+	if typ := schema.DeclType(decl); typ != nil {
+		return typ.Name()
+	}
+	return ""
+}
@@ -0,0 +1,150 @@
+package compat
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mprot/mprotc/schema"
+)
+
+func TestCompare(t *testing.T) {
+	const old = `
+	package foo
+	enum E {
+		A "1"
+		B "2"
+		C "3"
+		D "4"
+	}
+	struct S {
+		A int      "1"
+		B string   "2"
+		C int32    "3"
+		D float32  "4"
+		E bytes    "5"
+		F []int    "6"
+	}
+	union U {
+		S      "1"
+		string "2"
+		E      "3"
+	}
+	service Svc {
+		F(int) string "1"
+		G()           "2"
+		H()           "3"
+		I()           "4"
+	}
+	`
+
+	const new = `
+	package foo
+	enum E {
+		A  "1"
+		B  "5"
+		C2 "3"
+	}
+	struct S {
+		A  int      "2"
+		B2 string   "1"
+		C  int64    "3"
+		D  float64  "4"
+		E  string   "5"
+		F  [2]int   "6"
+	}
+	union U {
+		S   "1"
+		int "3"
+	}
+	service Svc {
+		F(int32) string "1"
+		G()             "3"
+		H2()            "4"
+	}
+	`
+
+	expected := []string{
+		"value of enumerator E.B changed from 2 to 5",
+		"enumerator E.D removed",
+		"ordinal of field S.A changed from 1 to 2",
+		"ordinal 1 of field S.A reused by field S.B2",
+		"ordinal 2 of field S.B reused by field S.A",
+		"type of field S.E changed from bytes to string",
+		"type of field S.F changed from []int to [2]int",
+		"branch string with ordinal 2 removed from union U",
+		"type of branch 3 in union U changed from E to int",
+		"signature of method Svc.F changed from (int) string to (int32) string",
+		"ordinal of method Svc.G changed from 2 to 3",
+		"method Svc.H removed",
+	}
+
+	changes := Compare(parse(t, old), parse(t, new))
+	texts := make([]string, 0, len(changes))
+	for _, c := range changes {
+		texts = append(texts, c.Text)
+	}
+	if !reflect.DeepEqual(texts, expected) {
+		t.Errorf("unexpected changes:\n%s", strings.Join(texts, "\n"))
+	}
+}
+
+func TestAllowlist(t *testing.T) {
+	allowed, err := ReadAllowlist(strings.NewReader("# comment\n\nenumerator-removed  a.mprot:E.D\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	c := Change{Kind: EnumeratorRemoved, File: "a.mprot", Path: "E.D"}
+	if !allowed.Allows(c) {
+		t.Errorf("change %q not allowed", c.Key())
+	}
+	c.Path = "E.C"
+	if allowed.Allows(c) {
+		t.Errorf("change %q allowed", c.Key())
+	}
+}
+
+func parse(t *testing.T, src string) schema.Schema {
+	s, err := schema.ParseSources(map[string]string{"a.mprot": src})
+	if err != nil {
+		t.Fatalf("unexpected parsing error: %v", err)
+	}
+	return s
+}
+
+func TestCompareDecls(t *testing.T) {
+	old, err := schema.ParseSources(map[string]string{
+		"a.mprot":     "package foo\n\nstruct S {\n}\n\nenum E {\n}\n\nunion U {\n\tstring \"1\"\n}\n\nstruct Moved {\n}\n\nconst C = 1\n",
+		"b.mprot":     "package foo\n\nservice Svc {\n}\n",
+		"sub/c.mprot": "package sub\n\nstruct Moved2 {\n}\n",
+	})
+	if err != nil {
+		t.Fatalf("unexpected parsing error: %v", err)
+	}
+	new, err := schema.ParseSources(map[string]string{
+		"a.mprot":     "package foo\n\nunion S {\n\tstring \"1\"\n}\n\nstruct E {\n}\n",
+		"d.mprot":     "package foo\n\nunion U {\n\tstring \"1\"\n}\n\nstruct Moved {\n}\n",
+		"sub/c.mprot": "package sub\n",
+		"e.mprot":     "package foo\n\nstruct Moved2 {\n}\n",
+	})
+	if err != nil {
+		t.Fatalf("unexpected parsing error: %v", err)
+	}
+
+	expected := []string{
+		"decl-kind-changed a.mprot:S (a.mprot:3:1): struct S changed to union",
+		"decl-kind-changed a.mprot:E (a.mprot:7:1): enum E changed to struct",
+		"decl-removed b.mprot:Svc (b.mprot:3:1): service Svc removed",
+		"decl-removed sub/c.mprot:Moved2 (sub/c.mprot:3:1): struct Moved2 removed",
+	}
+
+	changes := Compare(old, new)
+	texts := make([]string, 0, len(changes))
+	for _, c := range changes {
+		texts = append(texts, c.Key()+" ("+c.Pos.String()+"): "+c.Text)
+	}
+	if !reflect.DeepEqual(texts, expected) {
+		t.Errorf("unexpected changes:\n%s", strings.Join(texts, "\n"))
+	}
+}
@@ -0,0 +1,197 @@
+// Package diff computes line-based differences of text files and prints
+// them in the unified diff format.
+package diff
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+const contextLines = 3
+
+type opKind byte
+
+const (
+	opEqual  opKind = ' '
+	opDelete opKind = '-'
+	opInsert opKind = '+'
+)
+
+type op struct {
+	kind opKind
+	line string
+}
+
+// Unified returns the unified diff of the old and new content. The given names
+// are used for the file header. If both contents are equal, nil is returned.
+func Unified(oldName, newName string, old, new []byte) []byte {
+	if bytes.Equal(old, new) {
+		return nil
+	}
+
+	ops := diffLines(splitLines(old), splitLines(new))
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "--- %s\n", oldName)
+	fmt.Fprintf(&buf, "+++ %s\n", newName)
+
+	oldLine, newLine := 1, 1
+	for i := 0; i < len(ops); {
+		if ops[i].kind == opEqual {
+			oldLine++
+			newLine++
+			i++
+			continue
+		}
+
+		// find the end of the hunk, which contains all changes that are
+		// separated by at most 2*contextLines unchanged lines
+		start := i - contextLines
+		if start < 0 {
+			start = 0
+		}
+		end := i
+		for end < len(ops) {
+			if ops[end].kind != opEqual {
+				end++
+				continue
+			}
+			n := 0
+			for end+n < len(ops) && ops[end+n].kind == opEqual {
+				n++
+			}
+			if end+n == len(ops) || n > 2*contextLines {
+				end += min(n, contextLines)
+				break
+			}
+			end += n
+		}
+
+		hunkOld, hunkNew := oldLine-(i-start), newLine-(i-start)
+		var oldCount, newCount int
+		for _, o := range ops[start:end] {
+			if o.kind != opInsert {
+				oldCount++
+			}
+			if o.kind != opDelete {
+				newCount++
+			}
+		}
+		fmt.Fprintf(&buf, "@@ -%s +%s @@\n", hunkRange(hunkOld, oldCount), hunkRange(hunkNew, newCount))
+		for _, o := range ops[start:end] {
+			buf.WriteByte(byte(o.kind))
+			buf.WriteString(o.line)
+			if !strings.HasSuffix(o.line, "\n") {
+				buf.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+
+		for _, o := range ops[i:end] {
+			if o.kind != opInsert {
+				oldLine++
+			}
+			if o.kind != opDelete {
+				newLine++
+			}
+		}
+		i = end
+	}
+	return buf.Bytes()
+}
+
+func hunkRange(line, count int) string {
+	switch {
+	case count == 0:
+		return fmt.Sprintf("%d,0", line-1)
+	case count == 1:
+		return fmt.Sprintf("%d", line)
+	default:
+		return fmt.Sprintf("%d,%d", line, count)
+	}
+}
+
+func splitLines(b []byte) []string {
+	if len(b) == 0 {
+		return nil
+	}
+
+	lines := strings.SplitAfter(string(b), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// diffLines computes the shortest edit script for transforming a into b with
+// the algorithm of Myers.
+func diffLines(a, b []string) []op {
+	n, m := len(a), len(b)
+	max := n + m
+	offset := max + 1
+	v := make([]int, 2*max+3)
+	var trace [][]int
+
+	for d := 0; d <= max; d++ {
+		trace = append(trace, append([]int(nil), v...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				return backtrack(trace, a, b, offset, d)
+			}
+		}
+	}
+	return nil
+}
+
+func backtrack(trace [][]int, a, b []string, offset int, d int) []op {
+	ops := make([]op, 0, len(a)+len(b))
+	x, y := len(a), len(b)
+	for ; d > 0; d-- {
+		v := trace[d]
+		k := x - y
+
+		var prevK int
+		if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[offset+prevK]
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			x--
+			y--
+			ops = append(ops, op{opEqual, a[x]})
+		}
+		if x == prevX {
+			y--
+			ops = append(ops, op{opInsert, b[y]})
+		} else {
+			x--
+			ops = append(ops, op{opDelete, a[x]})
+		}
+	}
+	for x > 0 && y > 0 {
+		x--
+		y--
+		ops = append(ops, op{opEqual, a[x]})
+	}
+
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+	return ops
+}
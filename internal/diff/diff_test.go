@@ -0,0 +1,52 @@
+package diff
+
+import "testing"
+
+func TestUnified(t *testing.T) {
+	tests := []struct {
+		name     string
+		old      string
+		new      string
+		expected string
+	}{
+		{
+			name:     "equal",
+			old:      "a\nb\n",
+			new:      "a\nb\n",
+			expected: "",
+		},
+		{
+			name:     "insert",
+			old:      "a\nc\n",
+			new:      "a\nb\nc\n",
+			expected: "--- old\n+++ new\n@@ -1,2 +1,3 @@\n a\n+b\n c\n",
+		},
+		{
+			name:     "delete all",
+			old:      "a\nb\n",
+			new:      "",
+			expected: "--- old\n+++ new\n@@ -1,2 +0,0 @@\n-a\n-b\n",
+		},
+		{
+			name:     "separate hunks",
+			old:      "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n",
+			new:      "0\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n13\n",
+			expected: "--- old\n+++ new\n@@ -1,4 +1,4 @@\n-1\n+0\n 2\n 3\n 4\n@@ -9,4 +9,4 @@\n 9\n 10\n 11\n-12\n+13\n",
+		},
+		{
+			name:     "missing newline",
+			old:      "a\nb",
+			new:      "a\nb\n",
+			expected: "--- old\n+++ new\n@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+b\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := string(Unified("old", "new", []byte(test.old), []byte(test.new)))
+			if got != test.expected {
+				t.Errorf("unexpected diff:\n%s\nexpected:\n%s", got, test.expected)
+			}
+		})
+	}
+}
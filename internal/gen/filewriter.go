@@ -3,13 +3,18 @@ package gen
 import (
 	"path/filepath"
 	"sort"
+	"sync"
 )
 
 // FileWriter manages the printer for the requested files. The base name of the
 // target file cannot be changed. Printers are distinguished by the file extension
 // of the target file.
+//
+// Printers can be requested concurrently, but each printer must only be used by
+// a single goroutine.
 type FileWriter struct {
 	rootDir  string
+	mtx      sync.Mutex
 	printers map[string]*printer // relative filename => printer
 }
 
@@ -33,26 +38,96 @@ func NewFileWriter(rootDir string) (*FileWriter, error) {
 //
 // For each requested file, a printer will be registered.
 func (w *FileWriter) Printer(filename string, fileExt string) Printer {
+	source := filename
 	filename = filename[:len(filename)-len(filepath.Ext(filename))] + fileExt
 	filename = filepath.Clean(filepath.Join(w.rootDir, filename))
 
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+
 	p := w.printers[filename]
 	if p == nil {
-		p = &printer{}
+		p = &printer{source: source}
 		w.printers[filename] = p
 	}
 	return p
 }
 
+// Write buffers the given content for the target file with the given name,
+// which is relative to the root directory. The source is the mprot file the
+// content was generated from. An existing buffer of the target file is
+// replaced.
+func (w *FileWriter) Write(filename string, source string, content []byte) {
+	filename = filepath.Clean(filepath.Join(w.rootDir, filename))
+
+	p := &printer{source: source}
+	p.Write(content)
+
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	w.printers[filename] = p
+}
+
+// Source returns the name of the mprot file, for which the printer of the
+// given target file was requested. If there is no such printer, an empty
+// string will be returned.
+func (w *FileWriter) Source(filename string) string {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+
+	if p := w.printers[filename]; p != nil {
+		return p.source
+	}
+	return ""
+}
+
+// FileStatus describes the result of flushing a file.
+type FileStatus int
+
+const (
+	FileCreated   FileStatus = iota + 1 // file did not exist
+	FileUpdated                         // file existed with a different content
+	FileUnchanged                       // file existed with the same content
+)
+
+func (s FileStatus) String() string {
+	switch s {
+	case FileCreated:
+		return "created"
+	case FileUpdated:
+		return "updated"
+	case FileUnchanged:
+		return "unchanged"
+	default:
+		return "unknown"
+	}
+}
+
 // Flush writes the buffered code into the corresponding files. The target filename
-// is the base name with the respective file extension appended.
+// is the base name with the respective file extension appended. Files with an
+// unchanged content are not rewritten, all other files are replaced atomically.
 func (w *FileWriter) Flush() error {
-	for filename, p := range w.printers {
-		if err := p.writeToFile(filename); err != nil {
-			return err
+	return w.FlushTo(DirSink(w.rootDir))
+}
+
+// FlushTo writes the buffered code to the given sink. The files are written
+// in the order of their names.
+func (w *FileWriter) FlushTo(sink Sink) error {
+	var err error
+	w.WalkFiles(func(filename string) {
+		if err != nil {
+			return
 		}
-	}
-	return nil
+
+		var name string
+		if name, err = filepath.Rel(w.rootDir, filename); err != nil {
+			return
+		}
+
+		p := w.printers[filename]
+		p.status, err = sink.WriteFile(filepath.ToSlash(name), p.Bytes())
+	})
+	return err
 }
 
 // WalkFiles walks all the files registered in the file writer and calls
@@ -68,3 +143,21 @@ func (w *FileWriter) WalkFiles(iter func(filename string)) {
 		iter(filename)
 	}
 }
+
+// WalkStatus walks all the files written by the last Flush and calls iter
+// for each of these files with its status.
+func (w *FileWriter) WalkStatus(iter func(filename string, status FileStatus)) {
+	w.WalkFiles(func(filename string) {
+		if status := w.printers[filename].status; status != 0 {
+			iter(filename, status)
+		}
+	})
+}
+
+// WalkContents walks all the files registered in the file writer and calls
+// iter for each of these files with the buffered code.
+func (w *FileWriter) WalkContents(iter func(filename string, content []byte)) {
+	w.WalkFiles(func(filename string) {
+		iter(filename, w.printers[filename].Bytes())
+	})
+}
@@ -0,0 +1,66 @@
+package gen
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileWriterFlush(t *testing.T) {
+	dir := t.TempDir()
+	unchanged := filepath.Join(dir, "unchanged.go")
+	updated := filepath.Join(dir, "updated.go")
+	created := filepath.Join(dir, "sub", "created.go")
+
+	past := time.Now().Add(-time.Hour).Truncate(time.Second)
+	for filename, content := range map[string]string{unchanged: "same\n", updated: "old\n"} {
+		if err := os.WriteFile(filename, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(filename, past, past); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	w, err := NewFileWriter(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Printer("unchanged.mprot", ".go").Println("same")
+	w.Printer("updated.mprot", ".go").Println("new")
+	w.Printer("sub/created.mprot", ".go").Println("created")
+	if err := w.Flush(); err != nil {
+		t.Fatalf("unexpected flush error: %v", err)
+	}
+
+	expected := map[string]FileStatus{unchanged: FileUnchanged, updated: FileUpdated, created: FileCreated}
+	n := 0
+	w.WalkStatus(func(filename string, status FileStatus) {
+		if status != expected[filename] {
+			t.Errorf("unexpected status for %s: %v (expected %v)", filename, status, expected[filename])
+		}
+		n++
+	})
+	if n != len(expected) {
+		t.Errorf("unexpected number of files: %d", n)
+	}
+
+	if info, err := os.Stat(unchanged); err != nil || !info.ModTime().Equal(past) {
+		t.Errorf("unchanged file was rewritten")
+	}
+	if info, err := os.Stat(updated); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("permissions of updated file were not kept")
+	}
+	if content, _ := os.ReadFile(updated); string(content) != "new\n" {
+		t.Errorf("unexpected content of updated file: %q", content)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 {
+		t.Errorf("unexpected directory entries: %v", entries)
+	}
+}
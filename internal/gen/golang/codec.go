@@ -7,7 +7,7 @@ import (
 	"unicode/utf8"
 
 	"github.com/mprot/mprotc/internal/gen"
-	"github.com/mprot/mprotc/internal/schema"
+	"github.com/mprot/mprotc/schema"
 )
 
 type codecFuncPrinter struct {
@@ -4,7 +4,7 @@ import (
 	"strconv"
 
 	"github.com/mprot/mprotc/internal/gen"
-	"github.com/mprot/mprotc/internal/schema"
+	"github.com/mprot/mprotc/schema"
 )
 
 type constGenerator struct{}
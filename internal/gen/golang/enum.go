@@ -2,9 +2,10 @@ package golang
 
 import (
 	"math"
+	"strings"
 
 	"github.com/mprot/mprotc/internal/gen"
-	"github.com/mprot/mprotc/internal/schema"
+	"github.com/mprot/mprotc/schema"
 )
 
 type enumGenerator struct {
@@ -26,6 +27,16 @@ func (g *enumGenerator) Generate(p gen.Printer, e *schema.Enum, ti *typeinfo) {
 	g.printEncodeFunc(p, e.Name, intType)
 	p.Println()
 	g.printDecodeFunc(p, e.Name, intType)
+	p.Println()
+	g.printStringFunc(p, e.Name, e.Enumerators, intType)
+	p.Println()
+	g.printIsValidFunc(p, e.Name, e.Enumerators)
+	p.Println()
+	g.printValuesFunc(p, e.Name, e.Enumerators)
+	p.Println()
+	g.printParseFunc(p, e.Name, e.Enumerators, intType)
+	p.Println()
+	g.printTextFuncs(p, e.Name)
 	if g.typeid {
 		p.Println()
 		g.printTypeidFunc(p, e.Name, ti.typeid(schema.DeclType(e)))
@@ -47,10 +58,7 @@ func (g *enumGenerator) printDecl(p gen.Printer, name string, enumerators []sche
 		p.Println(`// Enumerators for `, name, `.`)
 		p.Println(`const (`)
 		for _, e := range enumerators {
-			enumerator := gen.RPad(e.Name, maxNameLen)
-			if g.scoped {
-				enumerator = name + enumerator
-			}
+			enumerator := gen.RPad(g.enumerator(name, e), maxNameLen)
 
 			p.Println(`	`, enumerator, ` `, name, ` = `, e.Value)
 		}
@@ -81,6 +89,117 @@ func (g *enumGenerator) printDecodeFunc(p gen.Printer, name string, intType stri
 	p.Println(`}`)
 }
 
+func (g *enumGenerator) printStringFunc(p gen.Printer, name string, enumerators []schema.Enumerator, intType string) {
+	p.Println(`// String returns the name of the enumerator. Unknown values are formatted`)
+	p.Println(`// as `, name, `(<value>).`)
+	p.Println(`func (o `, name, `) String() string {`)
+	if len(enumerators) != 0 {
+		p.Println(`	switch o {`)
+		for _, e := range uniqueEnumerators(enumerators) {
+			p.Println(`	case `, g.enumerator(name, e), `:`)
+			p.Println(`		return "`, e.Name, `"`)
+		}
+		p.Println(`	}`)
+	}
+	p.Println(`	return "`, name, `(" + strconv.FormatInt(int64(o), 10) + ")"`)
+	p.Println(`}`)
+}
+
+func (g *enumGenerator) printIsValidFunc(p gen.Printer, name string, enumerators []schema.Enumerator) {
+	p.Println(`// IsValid reports whether the value is an enumerator of `, name, `.`)
+	p.Println(`func (o `, name, `) IsValid() bool {`)
+	if len(enumerators) != 0 {
+		unique := uniqueEnumerators(enumerators)
+		names := make([]string, 0, len(unique))
+		for _, e := range unique {
+			names = append(names, g.enumerator(name, e))
+		}
+		p.Println(`	switch o {`)
+		p.Println(`	case `, strings.Join(names, ", "), `:`)
+		p.Println(`		return true`)
+		p.Println(`	}`)
+	}
+	p.Println(`	return false`)
+	p.Println(`}`)
+}
+
+func (g *enumGenerator) printValuesFunc(p gen.Printer, name string, enumerators []schema.Enumerator) {
+	p.Println(`// `, name, `Values returns all enumerators of `, name, ` in declaration order.`)
+	p.Println(`func `, name, `Values() []`, name, ` {`)
+	p.Println(`	return []`, name, `{`)
+	for _, e := range enumerators {
+		p.Println(`		`, g.enumerator(name, e), `,`)
+	}
+	p.Println(`	}`)
+	p.Println(`}`)
+}
+
+func (g *enumGenerator) printParseFunc(p gen.Printer, name string, enumerators []schema.Enumerator, intType string) {
+	bitSize := "0"
+	if intType == "int64" {
+		bitSize = "64"
+	}
+
+	p.Println(`// Parse`, name, ` returns the enumerator of `, name, ` with the given name. It also`)
+	p.Println(`// accepts the `, name, `(<value>) form returned by String for unknown values.`)
+	p.Println(`func Parse`, name, `(s string) (`, name, `, error) {`)
+	if len(enumerators) != 0 {
+		p.Println(`	switch s {`)
+		for _, e := range enumerators {
+			p.Println(`	case "`, e.Name, `":`)
+			p.Println(`		return `, g.enumerator(name, e), `, nil`)
+		}
+		p.Println(`	}`)
+	}
+	p.Println(`	if strings.HasPrefix(s, "`, name, `(") && strings.HasSuffix(s, ")") {`)
+	p.Println(`		if val, err := strconv.ParseInt(s[len("`, name, `("):len(s)-1], 10, `, bitSize, `); err == nil {`)
+	p.Println(`			return `, name, `(val), nil`)
+	p.Println(`		}`)
+	p.Println(`	}`)
+	p.Println(`	return 0, fmt.Errorf("invalid `, name, ` %q", s)`)
+	p.Println(`}`)
+}
+
+func (g *enumGenerator) printTextFuncs(p gen.Printer, name string) {
+	p.Println(`// MarshalText implements the encoding.TextMarshaler interface for `, name, `.`)
+	p.Println(`// Unknown values are marshaled as `, name, `(<value>).`)
+	p.Println(`func (o `, name, `) MarshalText() ([]byte, error) {`)
+	p.Println(`	return []byte(o.String()), nil`)
+	p.Println(`}`)
+	p.Println()
+	p.Println(`// UnmarshalText implements the encoding.TextUnmarshaler interface for `, name, `.`)
+	p.Println(`func (o *`, name, `) UnmarshalText(text []byte) error {`)
+	p.Println(`	val, err := Parse`, name, `(string(text))`)
+	p.Println(`	if err != nil {`)
+	p.Println(`		return err`)
+	p.Println(`	}`)
+	p.Println(`	*o = val`)
+	p.Println(`	return nil`)
+	p.Println(`}`)
+}
+
+// enumerator returns the Go name of the given enumerator.
+func (g *enumGenerator) enumerator(enumName string, e schema.Enumerator) string {
+	if g.scoped {
+		return enumName + e.Name
+	}
+	return e.Name
+}
+
+// uniqueEnumerators returns the enumerators with distinct values. For
+// enumerators with the same value, the first one is kept.
+func uniqueEnumerators(enumerators []schema.Enumerator) []schema.Enumerator {
+	seen := make(map[int64]bool, len(enumerators))
+	unique := make([]schema.Enumerator, 0, len(enumerators))
+	for _, e := range enumerators {
+		if !seen[e.Value] {
+			seen[e.Value] = true
+			unique = append(unique, e)
+		}
+	}
+	return unique
+}
+
 func (g *enumGenerator) printTypeidFunc(p gen.Printer, name string, typeid string) {
 	p.Println(`// TypeID returns the type id for `, name, `.`)
 	p.Println(`func (o `, name, `) TypeID() string {`)
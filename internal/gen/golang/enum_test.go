@@ -0,0 +1,153 @@
+package golang
+
+import (
+	"strings"
+	"testing"
+)
+
+const testEnums = `package colors
+
+enum Color {
+	Red     "1"
+	Green   "2"
+	Blue    "3"
+	Crimson "1"
+}
+
+enum Big {
+	Small "1"
+	Large "5000000000"
+}
+
+enum Empty {
+}
+`
+
+func TestEnumNames(t *testing.T) {
+	tests := []struct {
+		scoped   bool
+		expected []string
+	}{
+		{
+			scoped:   false,
+			expected: []string{"Red     Color = 1", "case Red:\n\t\treturn \"Red\"", "case \"Crimson\":\n\t\treturn Crimson, nil"},
+		},
+		{
+			scoped:   true,
+			expected: []string{"ColorRed     Color = 1", "case ColorRed:\n\t\treturn \"Red\"", "case \"Crimson\":\n\t\treturn ColorCrimson, nil"},
+		},
+	}
+
+	for _, test := range tests {
+		src := generate(t, Options{ScopedEnums: test.scoped}, map[string]string{"colors.mprot": testEnums})["colors.go"]
+		for _, expected := range test.expected {
+			if !strings.Contains(src, expected) {
+				t.Errorf("expected %q in generated code (scoped=%v):\n%s", expected, test.scoped, src)
+			}
+		}
+	}
+}
+
+func TestEnumFuncs(t *testing.T) {
+	files := generate(t, Options{ScopedEnums: true}, map[string]string{"colors.mprot": testEnums})
+	runGenerated(t, files, `package colors
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestString(t *testing.T) {
+	tests := []struct {
+		value    Color
+		expected string
+	}{
+		{ColorRed, "Red"},
+		{ColorGreen, "Green"},
+		{ColorBlue, "Blue"},
+		{ColorCrimson, "Red"},
+		{0, "Color(0)"},
+		{-7, "Color(-7)"},
+	}
+	for _, test := range tests {
+		if s := test.value.String(); s != test.expected {
+			t.Errorf("unexpected string for %d: %q", int(test.value), s)
+		}
+	}
+	if s := BigLarge.String(); s != "Large" {
+		t.Errorf("unexpected string for BigLarge: %q", s)
+	}
+	if s := Empty(3).String(); s != "Empty(3)" {
+		t.Errorf("unexpected string for Empty(3): %q", s)
+	}
+}
+
+func TestIsValid(t *testing.T) {
+	for _, c := range ColorValues() {
+		if !c.IsValid() {
+			t.Errorf("unexpected invalid enumerator %v", c)
+		}
+	}
+	if Color(0).IsValid() || Big(2).IsValid() || Empty(0).IsValid() {
+		t.Errorf("unexpected valid value")
+	}
+}
+
+func TestValues(t *testing.T) {
+	if values := ColorValues(); !reflect.DeepEqual(values, []Color{ColorRed, ColorGreen, ColorBlue, ColorCrimson}) {
+		t.Errorf("unexpected values: %v", values)
+	}
+	if values := EmptyValues(); len(values) != 0 {
+		t.Errorf("unexpected values: %v", values)
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := map[string]Color{
+		"Red":       ColorRed,
+		"Crimson":   ColorCrimson,
+		"Blue":      ColorBlue,
+		"Color(0)":  0,
+		"Color(-7)": -7,
+	}
+	for s, expected := range tests {
+		if c, err := ParseColor(s); err != nil {
+			t.Errorf("unexpected error for %q: %v", s, err)
+		} else if c != expected {
+			t.Errorf("unexpected value for %q: %d", s, int(c))
+		}
+	}
+	for _, s := range []string{"", "red", "ColorRed", "Color()", "Color(x)", "Big(1)"} {
+		if _, err := ParseColor(s); err == nil {
+			t.Errorf("expected error for %q", s)
+		}
+	}
+	if b, err := ParseBig("Big(6000000000)"); err != nil || b != 6000000000 {
+		t.Errorf("unexpected result for Big(6000000000): %d, %v", int64(b), err)
+	}
+}
+
+func TestText(t *testing.T) {
+	for _, c := range []Color{0, ColorRed, ColorBlue, 42} {
+		text, err := c.MarshalText()
+		if err != nil {
+			t.Fatalf("unexpected marshal error for %v: %v", c, err)
+		}
+		var res Color
+		if err := res.UnmarshalText(text); err != nil {
+			t.Fatalf("unexpected unmarshal error for %s: %v", text, err)
+		} else if res != c {
+			t.Errorf("unexpected value for %s: %v", text, res)
+		}
+	}
+
+	data, err := json.Marshal(map[string]Color{"zero": 0})
+	if err != nil {
+		t.Fatalf("unexpected json error: %v", err)
+	} else if string(data) != `+"`"+`{"zero":"Color(0)"}`+"`"+` {
+		t.Errorf("unexpected json: %s", data)
+	}
+}
+`)
+}
@@ -1,14 +1,17 @@
 package golang
 
 import (
+	"bytes"
 	"fmt"
+	"go/format"
 	"path"
 	"path/filepath"
 	"sort"
 	"strings"
 
 	"github.com/mprot/mprotc/internal/gen"
-	"github.com/mprot/mprotc/internal/schema"
+	"github.com/mprot/mprotc/internal/parallel"
+	"github.com/mprot/mprotc/schema"
 )
 
 // Options holds all the options for the Go language generator.
@@ -17,22 +20,27 @@ type Options struct {
 	ScopedEnums  bool   // scope enumerators?
 	UnwrapUnions bool   // unwrap union types in struct fields?
 	TypeID       bool   // generate TypeID method?
+	Registry     bool   // register the types at the runtime type registry?
+	Jobs         int    // maximum number of files generated concurrently, GOMAXPROCS if <= 0
 }
 
 // Generator represents a code generator for the Go programming language.
 type Generator struct {
 	importRoot string
+	jobs       int
 	cnst       constGenerator
 	enum       enumGenerator
 	strct      structGenerator
 	union      unionGenerator
 	service    serviceGenerator
+	registry   *registryGenerator // nil if disabled
 }
 
 // NewGenerator creates a new Go code generator with the given options.
 func NewGenerator(opts Options) *Generator {
-	return &Generator{
+	g := &Generator{
 		importRoot: opts.ImportRoot,
+		jobs:       opts.Jobs,
 		enum: enumGenerator{
 			scoped: opts.ScopedEnums,
 			typeid: opts.TypeID,
@@ -45,87 +53,120 @@ func NewGenerator(opts Options) *Generator {
 			typeid: opts.TypeID,
 		},
 	}
+	if opts.Registry {
+		g.registry = &registryGenerator{}
+	}
+	return g
 }
 
-// Generate generates the Go code for the given schema and prints it to p.
-func (g *Generator) Generate(w *gen.FileWriter, s schema.Schema) {
-	for _, file := range s {
-		g.generate(w.Printer(file.Name, ".go"), file)
-	}
+// Generate generates the Go code for the given schema and writes it to w. The
+// generated code is formatted with go/format. If the generated code cannot be
+// formatted, an error is returned and the file is not written.
+func (g *Generator) Generate(w *gen.FileWriter, s schema.Schema) error {
+	return parallel.FirstError(g.jobs, len(s), func(i int) error {
+		f := s[i]
+		filename := strings.TrimSuffix(f.Name, filepath.Ext(f.Name)) + ".go"
+		content, err := g.generate(filename, f)
+		if err != nil {
+			return fmt.Errorf("%s: invalid Go code generated (this is a bug in mprotc, please report it): %v", f.Name, err)
+		}
+		w.Write(filename, f.Name, content)
+		return nil
+	})
 }
 
-func (g *Generator) generate(p gen.Printer, f *schema.File) {
+func (g *Generator) generate(filename string, f *schema.File) ([]byte, error) {
 	imports, importNames := g.goImports(f)
-	hasService := containsService(f)
-
-	p.Println(`// Code generated by mprotc.`)
-	p.Println(`// Do not edit.`)
-	p.Println()
-
-	printDoc(p, f.Doc, "")
-	p.Println(`package `, f.Package.Name)
-	p.Println()
-	p.Println(`import (`)
-	if hasService {
-		p.Println(`	"bytes"`)
-		p.Println(`	"context"`)
-	}
-	p.Println(`	"fmt"`)
-	p.Println(`	"time"`)
-	p.Println()
-	if hasService {
-		p.Println(`	mrpc "github.com/mprot/mrpc-go"`)
-	}
-	p.Println(`	msgpack "github.com/mprot/msgpack-go"`)
-	if len(imports) != 0 {
-		p.Println()
-		for _, imp := range imports {
-			p.Println(`	`, imp.Name, ` "`, imp.Path, `"`)
-		}
-	}
-	p.Println(`)`)
-	p.Println()
-	if hasService {
-		p.Println(`var _ *bytes.Buffer`)
-		p.Println(`var _ context.Context`)
-	}
-	p.Println(`var _ = fmt.Errorf`)
-	p.Println(`var _ time.Time`)
-	if hasService {
-		p.Println(`var _ *mrpc.Server`)
-	}
-	p.Println(`var _ *msgpack.Writer`)
 
+	var body buffer
 	ti := newTypeinfo(importNames)
 	for _, decl := range f.Decls {
-		p.Println()
+		body.Println()
 
 		switch decl := decl.(type) {
 		case *schema.Const:
-			g.cnst.Generate(p, decl)
+			g.cnst.Generate(&body, decl)
 		case *schema.Enum:
-			g.enum.Generate(p, decl, ti)
+			g.enum.Generate(&body, decl, ti)
 		case *schema.Struct:
-			g.strct.Generate(p, decl, ti)
+			g.strct.Generate(&body, decl, ti)
 		case *schema.Union:
-			g.union.Generate(p, decl, ti)
+			g.union.Generate(&body, decl, ti)
 		case *schema.Service:
-			g.service.Generate(p, decl, ti)
+			g.service.Generate(&body, decl, ti)
 		default:
 			panic(fmt.Sprintf("unsupported declaration type %T", decl))
 		}
 	}
+
+	if g.registry != nil && containsRegistryTypes(f) {
+		body.Println()
+		g.registry.Generate(&body, g.packagePath(f), f.Decls, ti)
+	}
+
+	// The source is printed with all possible imports first to find the used
+	// ones. This also checks the syntax of the generated code.
+	groups := [][]goImport{stdImports, runtimeImports, imports}
+	used, err := usedPackages(filename, g.source(f, groups, nil, body.Bytes()))
+	if err != nil {
+		return nil, err
+	}
+	return format.Source(g.source(f, groups, used, body.Bytes()))
+}
+
+// source returns the source of a generated file with the given import groups
+// and declarations. If used is nil, all imports are printed. Otherwise, only
+// the imports of used packages are printed.
+func (g *Generator) source(f *schema.File, groups [][]goImport, used map[string]bool, decls []byte) []byte {
+	var p buffer
+	p.Println(`// Code generated by mprotc.`)
+	p.Println(`// Do not edit.`)
+	p.Println()
+
+	printDoc(&p, f.Doc, "")
+	p.Println(`package `, f.Package.Name)
+
+	var imports buffer
+	for _, group := range groups {
+		imports.Println()
+		for _, imp := range group {
+			if used == nil || used[imp.pkgName()] {
+				imports.Println(`	`, imp.name, ` "`, imp.path, `"`)
+			}
+		}
+	}
+	if len(bytes.TrimSpace(imports.Bytes())) != 0 {
+		p.Println()
+		p.Println(`import (`)
+		p.Write(imports.Bytes())
+		p.Println(`)`)
+	}
+
+	p.Write(decls)
+	return p.Bytes()
 }
 
-func (g *Generator) goImports(f *schema.File) ([]schema.Import, map[string]string) {
+// packagePath returns the Go import path of the package the given file is
+// generated into.
+func (g *Generator) packagePath(f *schema.File) string {
+	return normalizePath(path.Join(g.importRoot, filepath.Dir(f.Name)))
+}
+
+func (g *Generator) goImports(f *schema.File) ([]goImport, map[string]string) {
 	curdir := filepath.Dir(f.Name)
-	imports := make([]schema.Import, 0, len(f.Imports))
+	imports := make([]goImport, 0, len(f.Imports))
 	importNames := make(map[string]string, len(f.Imports)) // go name => mprot name
 	for _, imp := range f.Imports {
 		goimp := *imp
 
+		// imports found in an include directory are generated relative to
+		// the import root instead of the importing file
 		goimp.Path = filepath.Dir(goimp.Path)
-		goimp.Path = normalizePath(path.Join(g.importRoot, curdir, goimp.Path))
+		if imp.IncludeDir != "" {
+			goimp.Path = normalizePath(path.Join(g.importRoot, goimp.Path))
+		} else {
+			goimp.Path = normalizePath(path.Join(g.importRoot, curdir, goimp.Path))
+		}
 
 		rel := strings.TrimPrefix(goimp.Path, g.importRoot)
 		rel = strings.TrimPrefix(rel, "/")
@@ -139,11 +180,11 @@ func (g *Generator) goImports(f *schema.File) ([]schema.Import, map[string]strin
 		goimp.Name = path.Base(goimp.Path)
 		goimp.Name = strings.ReplaceAll(goimp.Name, "-", "_")
 
-		imports = append(imports, goimp)
+		imports = append(imports, goImport{name: goimp.Name, path: goimp.Path})
 		importNames[imp.Name] = goimp.Name
 	}
 
-	sort.Slice(imports, func(i, j int) bool { return imports[i].Path < imports[j].Path })
+	sort.Slice(imports, func(i, j int) bool { return imports[i].path < imports[j].path })
 	return imports, importNames
 }
 
@@ -154,11 +195,12 @@ func normalizePath(path string) string {
 	return strings.ReplaceAll(path, string(filepath.Separator), "/")
 }
 
-func containsService(f *schema.File) bool {
-	for _, decl := range f.Decls {
-		if _, ok := decl.(*schema.Service); ok {
-			return true
-		}
-	}
-	return false
+// buffer is a printer, which prints into a memory buffer.
+type buffer struct {
+	bytes.Buffer
+}
+
+func (b *buffer) Println(args ...interface{}) {
+	fmt.Fprint(b, args...)
+	b.WriteByte('\n')
 }
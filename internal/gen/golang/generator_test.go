@@ -0,0 +1,131 @@
+package golang
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/mprot/mprotc/internal/gen"
+	"github.com/mprot/mprotc/schema"
+)
+
+// generate generates the Go code for the given schema sources, which map the
+// file names to the file contents. The generated files are returned by name.
+func generate(t *testing.T, opts Options, sources map[string]string) map[string]string {
+	t.Helper()
+
+	w, err := gen.NewFileWriter(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := NewGenerator(opts).Generate(w, parse(t, sources)); err != nil {
+		t.Fatalf("unexpected generate error: %v", err)
+	}
+	return flush(t, w)
+}
+
+// parse parses the given schema sources, which map the file names to the file
+// contents.
+func parse(t *testing.T, sources map[string]string) schema.Schema {
+	t.Helper()
+
+	fsys := make(fstest.MapFS, len(sources))
+	for name, src := range sources {
+		fsys[name] = &fstest.MapFile{Data: []byte(src)}
+	}
+	s, err := schema.ParseFS(fsys, []string{"**/*.mprot"})
+	if err != nil {
+		t.Fatalf("unexpected parse error: %v", err)
+	}
+	return s
+}
+
+// flush returns the files of the given file writer by name.
+func flush(t *testing.T, w *gen.FileWriter) map[string]string {
+	t.Helper()
+
+	var sink gen.MemorySink
+	if err := w.FlushTo(&sink); err != nil {
+		t.Fatal(err)
+	}
+	files := make(map[string]string)
+	for name, content := range sink.Files() {
+		files[name] = string(content)
+	}
+	return files
+}
+
+const testItem = `package sub
+
+struct Item {
+	ID int64 "1"
+}
+`
+
+func TestTypeIDOfImportedTypes(t *testing.T) {
+	files := generate(t, Options{TypeID: true, Registry: true, ImportRoot: "example.com/gen"}, map[string]string{
+		"sub/item.mprot": testItem,
+		"event.mprot":    "package event\n\nimport \"sub/item.mprot\"\n\nunion Event {\n\titem.Item \"1\"\n\tstring    \"2\"\n}\n",
+	})
+
+	src := files["event.go"]
+	for _, expected := range []string{"case sub.Item:\n\t\treturn \"item\"", `Branches: []registry.Branch{`} {
+		if !strings.Contains(src, expected) {
+			t.Errorf("expected %q in generated code:\n%s", expected, src)
+		}
+	}
+}
+
+// runGenerated writes the generated files together with the given test source
+// into a temporary module and runs its tests. The runtime packages are replaced
+// by the stubs in testdata/stubs and the local registry package.
+func runGenerated(t *testing.T, files map[string]string, test string) {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("skipping compilation of generated code in short mode")
+	}
+	goBin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go command not found")
+	}
+
+	root, err := filepath.Abs(filepath.Join("..", "..", ".."))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stubs, err := filepath.Abs(filepath.Join("testdata", "stubs"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	files["go.mod"] = "module example.com/gen\n\ngo 1.21\n\n" +
+		"require (\n" +
+		"\tgithub.com/mprot/mprotc v0.0.0\n" +
+		"\tgithub.com/mprot/mrpc-go v0.0.0\n" +
+		"\tgithub.com/mprot/msgpack-go v0.0.0\n" +
+		")\n\n" +
+		"replace github.com/mprot/mprotc => " + root + "\n" +
+		"replace github.com/mprot/mrpc-go => " + filepath.Join(stubs, "mrpc-go") + "\n" +
+		"replace github.com/mprot/msgpack-go => " + filepath.Join(stubs, "msgpack-go") + "\n"
+	files["gen_test.go"] = test
+	for name, content := range files {
+		filename := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cmd := exec.Command(goBin, "test", "./...")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOFLAGS=-mod=mod", "GOPROXY=off", "GOWORK=off")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("unexpected test error: %v\n%s", err, out)
+	}
+}
@@ -0,0 +1,86 @@
+package golang
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"path"
+)
+
+// goImport describes an import of a generated Go file.
+type goImport struct {
+	name string // empty for the base name of the path
+	path string
+}
+
+func (imp goImport) pkgName() string {
+	if imp.name != "" {
+		return imp.name
+	}
+	return path.Base(imp.path)
+}
+
+// Imports, which are possibly used by the generated code. The imports of the
+// schema files are added per file.
+var (
+	stdImports = []goImport{
+		{path: "bytes"},
+		{path: "context"},
+		{path: "encoding/json"},
+		{path: "fmt"},
+		{path: "strconv"},
+		{path: "strings"},
+		{path: "time"},
+	}
+
+	runtimeImports = []goImport{
+		{name: "mrpc", path: "github.com/mprot/mrpc-go"},
+		{name: "msgpack", path: "github.com/mprot/msgpack-go"},
+		{name: "registry", path: "github.com/mprot/mprotc/registry"},
+	}
+)
+
+// usedPackages returns the names of all packages referenced by the given Go
+// source. A package is referenced by a selector expression, whose operand does
+// not resolve to an object declared in the source itself. The source has no
+// imports, so the operands are resolved by type checking the file, which
+// reports the missing packages as errors and is not complete otherwise.
+func usedPackages(filename string, src []byte) (map[string]bool, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, filename, src, parser.SkipObjectResolution)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &types.Info{Uses: make(map[*ast.Ident]types.Object)}
+	conf := types.Config{
+		Error:    func(error) {}, // continue after errors
+		Importer: noImporter{},
+	}
+	conf.Check(f.Name.Name, fset, []*ast.File{f}, info)
+
+	used := make(map[string]bool)
+	ast.Inspect(f, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			if id, ok := sel.X.(*ast.Ident); ok {
+				if obj := info.Uses[id]; obj == nil {
+					used[id.Name] = true
+				} else if _, isPkg := obj.(*types.PkgName); isPkg {
+					used[id.Name] = true
+				}
+			}
+		}
+		return true
+	})
+	return used, nil
+}
+
+// noImporter fails for all imports, since the packages used by the generated
+// code are not available when generating it.
+type noImporter struct{}
+
+func (noImporter) Import(path string) (*types.Package, error) {
+	return nil, fmt.Errorf("package %s not available", path)
+}
@@ -0,0 +1,133 @@
+package golang
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mprot/mprotc/internal/gen"
+)
+
+func TestImports(t *testing.T) {
+	files := generate(t, Options{Registry: true, ImportRoot: "example.com/gen"}, map[string]string{
+		"sub/item.mprot": testItem,
+		"consts.mprot":   "package gen\n\nconst Pi = 3.14\n",
+		"enums.mprot":    "package gen\n\nenum E {\n\tA \"1\"\n}\n",
+		"unions.mprot":   "package gen\n\nunion U {\n\tstring \"1\"\n}\n",
+		"times.mprot":    "package gen\n\nimport \"sub/item.mprot\"\n\nstruct T {\n\tAt   time      \"1\"\n\tItem item.Item \"2\"\n}\n",
+		"services.mprot": "package gen\n\nservice S {\n\tPing(string) int64 \"1\"\n}\n",
+	})
+
+	expected := map[string][]string{
+		"consts.go":   nil,
+		"enums.go":    {`"fmt"`, `"strconv"`, `"strings"`, ``, `registry "github.com/mprot/mprotc/registry"`, `msgpack "github.com/mprot/msgpack-go"`},
+		"unions.go":   {`"encoding/json"`, `"fmt"`, ``, `registry "github.com/mprot/mprotc/registry"`, `msgpack "github.com/mprot/msgpack-go"`},
+		"times.go":    {`"encoding/json"`, `"time"`, ``, `registry "github.com/mprot/mprotc/registry"`, `msgpack "github.com/mprot/msgpack-go"`, ``, `sub "example.com/gen/sub"`},
+		"services.go": {`"bytes"`, `"context"`, ``, `mrpc "github.com/mprot/mrpc-go"`, `msgpack "github.com/mprot/msgpack-go"`},
+		"sub/item.go": {`"encoding/json"`, ``, `registry "github.com/mprot/mprotc/registry"`, `msgpack "github.com/mprot/msgpack-go"`},
+	}
+	for name, imports := range expected {
+		src, has := files[name]
+		if !has {
+			t.Errorf("%s not generated", name)
+			continue
+		}
+
+		if block := importBlock(src); block != goImportBlock(imports) {
+			t.Errorf("unexpected imports in %s:\n%s", name, block)
+		}
+	}
+
+	// unused imports do not compile
+	runGenerated(t, files, "package gen\n")
+}
+
+func TestInvalidCode(t *testing.T) {
+	s := parse(t, map[string]string{
+		"valid.mprot":   "package gen\n\nstruct S {\n\tName string \"1\"\n}\n",
+		"invalid.mprot": "package gen\n\nstruct T {\n\trange int \"1\"\n}\n",
+	})
+	w, err := gen.NewFileWriter(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = NewGenerator(Options{}).Generate(w, s)
+	if err == nil || !strings.HasPrefix(err.Error(), "invalid.mprot: invalid Go code generated (this is a bug in mprotc, please report it): ") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	files := flush(t, w)
+	if _, has := files["invalid.go"]; has {
+		t.Errorf("invalid file written")
+	}
+	if _, has := files["valid.go"]; !has {
+		t.Errorf("valid file not written")
+	}
+}
+
+func TestUsedPackages(t *testing.T) {
+	const src = `package gen
+
+type T struct {
+	strings []string
+}
+
+var bytes = []byte{}
+
+func (fmt T) String() string {
+	return fmt.strings[0] + strconv.Itoa(len(bytes.x))
+}
+
+func F(json int) {
+	time := T{}
+	_ = time.strings
+	for _, context := range []T{} {
+		_ = context.strings
+	}
+	{
+		var mrpc T
+		_ = mrpc.strings
+	}
+	_ = mrpc.Client
+	_ = json.Valid
+}
+`
+
+	used, err := usedPackages("gen.go", []byte(src))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := map[string]bool{"strconv": true, "mrpc": true}
+	if !reflect.DeepEqual(used, expected) {
+		t.Errorf("unexpected used packages: %v", used)
+	}
+}
+
+// importBlock returns the import declaration of the given Go source.
+func importBlock(src string) string {
+	start := strings.Index(src, "\nimport (\n")
+	if start < 0 {
+		return ""
+	}
+	end := strings.Index(src[start:], "\n)\n")
+	return src[start+1 : start+end+3]
+}
+
+// goImportBlock returns the import declaration of the given import specs, where
+// an empty spec separates two groups.
+func goImportBlock(imports []string) string {
+	if len(imports) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("import (\n")
+	for _, imp := range imports {
+		if imp != "" {
+			b.WriteString("\t" + imp)
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString(")\n")
+	return b.String()
+}
@@ -0,0 +1,71 @@
+package golang
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/mprot/mprotc/internal/gen"
+	"github.com/mprot/mprotc/schema"
+)
+
+type registryGenerator struct{}
+
+func (g *registryGenerator) Generate(p gen.Printer, pkgPath string, decls []schema.Decl, ti *typeinfo) {
+	p.Println(`func init() {`)
+	p.Println(`	registry.Register("`, pkgPath, `",`)
+	for _, decl := range decls {
+		switch decl := decl.(type) {
+		case *schema.Enum:
+			g.printType(p, "EnumKind", decl.Name, decl.Doc, ti.typeid(schema.DeclType(decl)), func(p gen.Printer) {
+				p.Println(`Enumerators: []registry.Enumerator{`)
+				for _, e := range decl.Enumerators {
+					p.Println(`	{Name: "`, e.Name, `", Value: `, e.Value, `},`)
+				}
+				p.Println(`},`)
+			})
+
+		case *schema.Struct:
+			g.printType(p, "StructKind", decl.Name, decl.Doc, ti.typeid(schema.DeclType(decl)), func(p gen.Printer) {
+				p.Println(`Fields: []registry.Field{`)
+				for _, f := range decl.Fields {
+					p.Println(`	{Name: "`, f.Name, `", Ordinal: `, f.Ordinal, `, Type: "`, f.Type.Name(), `"},`)
+				}
+				p.Println(`},`)
+			})
+
+		case *schema.Union:
+			g.printType(p, "UnionKind", decl.Name, decl.Doc, ti.typeid(schema.DeclType(decl)), func(p gen.Printer) {
+				p.Println(`Branches: []registry.Branch{`)
+				for _, b := range decl.Branches {
+					p.Println(`	{Ordinal: `, b.Ordinal, `, Type: "`, b.Type.Name(), `", TypeID: "`, ti.typeid(b.Type), `"},`)
+				}
+				p.Println(`},`)
+			})
+		}
+	}
+	p.Println(`	)`)
+	p.Println(`}`)
+}
+
+func (g *registryGenerator) printType(p gen.Printer, kind string, name string, doc []string, typeid string, printMembers func(p gen.Printer)) {
+	p.Println(`		registry.Type{`)
+	p.Println(`			ID: "`, typeid, `",`)
+	p.Println(`			Name: "`, name, `",`)
+	p.Println(`			Kind: registry.`, kind, `,`)
+	if len(doc) != 0 {
+		p.Println(`			Doc: `, strconv.Quote(strings.Join(doc, "\n")), `,`)
+	}
+	printMembers(gen.PrefixedPrinter(p, "\t\t\t"))
+	p.Println(`			New: func() interface{} { return new(`, name, `) },`)
+	p.Println(`		},`)
+}
+
+func containsRegistryTypes(f *schema.File) bool {
+	for _, decl := range f.Decls {
+		switch decl.(type) {
+		case *schema.Enum, *schema.Struct, *schema.Union:
+			return true
+		}
+	}
+	return false
+}
@@ -5,7 +5,7 @@ import (
 	"strings"
 
 	"github.com/mprot/mprotc/internal/gen"
-	"github.com/mprot/mprotc/internal/schema"
+	"github.com/mprot/mprotc/schema"
 )
 
 type serviceGenerator struct{}
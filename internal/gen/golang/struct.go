@@ -1,8 +1,10 @@
 package golang
 
 import (
+	"strconv"
+
 	"github.com/mprot/mprotc/internal/gen"
-	"github.com/mprot/mprotc/internal/schema"
+	"github.com/mprot/mprotc/schema"
 )
 
 type structGenerator struct {
@@ -16,6 +18,8 @@ func (g *structGenerator) Generate(p gen.Printer, s *schema.Struct, ti *typeinfo
 	g.printEncodeFunc(p, s.Name, s.Fields, ti)
 	p.Println()
 	g.printDecodeFunc(p, s.Name, s.Fields, ti)
+	p.Println()
+	g.printJSONFuncs(p, s.Name, s.Fields, ti)
 	if g.typeid {
 		p.Println()
 		g.printTypeidFunc(p, s.Name, ti.typeid(schema.DeclType(s)))
@@ -88,6 +92,60 @@ func (g *structGenerator) printDecodeFunc(p gen.Printer, name string, fields []s
 	p.Println(`}`)
 }
 
+// printJSONFuncs prints the JSON marshaling methods. The fields are marshaled
+// with an anonymous struct, which holds the JSON names as struct tags and
+// wraps the unwrapped union fields into their union types.
+func (g *structGenerator) printJSONFuncs(p gen.Printer, name string, fields []schema.Field, ti *typeinfo) {
+	p.Println(`// MarshalJSON implements the json.Marshaler interface for `, name, `.`)
+	p.Println(`func (o `, name, `) MarshalJSON() ([]byte, error) {`)
+	p.Println(`	return json.Marshal(`, g.jsonStruct(fields, ti), `{`)
+	g.printJSONFields(gen.PrefixedPrinter(p, "\t\t"), fields, ti)
+	p.Println(`	})`)
+	p.Println(`}`)
+	p.Println()
+	p.Println(`// UnmarshalJSON implements the json.Unmarshaler interface for `, name, `.`)
+	p.Println(`func (o *`, name, `) UnmarshalJSON(data []byte) error {`)
+	p.Println(`	v := `, g.jsonStruct(fields, ti), `{`)
+	g.printJSONFields(gen.PrefixedPrinter(p, "\t\t"), fields, ti)
+	p.Println(`	}`)
+	p.Println(`	if err := json.Unmarshal(data, &v); err != nil {`)
+	p.Println(`		return err`)
+	p.Println(`	}`)
+	for _, f := range fields {
+		if g.unwrapUnion && isUnion(f.Type) {
+			p.Println(`	o.`, f.Name, ` = v.`, f.Name, `.Value`)
+		} else {
+			p.Println(`	o.`, f.Name, ` = v.`, f.Name)
+		}
+	}
+	p.Println(`	return nil`)
+	p.Println(`}`)
+}
+
+func (g *structGenerator) jsonStruct(fields []schema.Field, ti *typeinfo) string {
+	var buf buffer
+	buf.Println(`struct {`)
+	for _, f := range fields {
+		jsonName := f.Name
+		if tag, has := f.Tags["json"]; has {
+			jsonName = tag
+		}
+		buf.Println(`		`, f.Name, ` `, ti.typename(f.Type), " `json:", strconv.Quote(jsonName), "`")
+	}
+	buf.WriteString(`	}`)
+	return buf.String()
+}
+
+func (g *structGenerator) printJSONFields(p gen.Printer, fields []schema.Field, ti *typeinfo) {
+	for _, f := range fields {
+		if g.unwrapUnion && isUnion(f.Type) {
+			p.Println(f.Name, `: `, ti.typename(f.Type), `{Value: o.`, f.Name, `},`)
+		} else {
+			p.Println(f.Name, `: o.`, f.Name, `,`)
+		}
+	}
+}
+
 func (g *structGenerator) printTypeidFunc(p gen.Printer, name string, typeid string) {
 	p.Println(`// TypeID returns the type id for `, name, `.`)
 	p.Println(`func (o *`, name, `) TypeID() string {`)
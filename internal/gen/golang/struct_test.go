@@ -0,0 +1,112 @@
+package golang
+
+import "testing"
+
+const testOrder = `package shop
+
+enum Color {
+	Red  "1"
+	Blue "2"
+}
+
+struct Item {
+	Name string "1"
+}
+
+union Value {
+	Item   "1"
+	string "2"
+	int64  "3"
+}
+
+struct Order {
+	ID      int64            ` + "`1 json:\"id\"`" + `
+	Color   Color            "2"
+	Created time             "3"
+	Data    bytes            "4"
+	Value   Value            "5"
+	Items   []Item           "6"
+	Ptr     *Item            "7"
+	Colors  map[string]Color "8"
+	Skip    string           ` + "`9 json:\"-\"`" + `
+}
+`
+
+// testOrderJSON is the test for the JSON marshaling of the generated code.
+// The value function creates the value of a union field.
+const testOrderJSON = `package shop
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestJSONRoundTrip(t *testing.T) {
+	orders := []Order{
+		{},
+		{Color: 42, Value: value(nil)},
+		{
+			ID:      1,
+			Color:   Blue,
+			Created: time.Date(2020, 1, 2, 3, 4, 5, 6, time.UTC),
+			Data:    []byte{0, 1, 0xfe, 0xff},
+			Value:   value(Item{Name: "item"}),
+			Items:   []Item{{Name: "a"}, {}},
+			Ptr:     &Item{Name: "ptr"},
+			Colors:  map[string]Color{"red": Red, "unknown": 0},
+		},
+		{Value: value("str")},
+		{Value: value(int64(-3))},
+	}
+
+	for _, order := range orders {
+		data, err := json.Marshal(order)
+		if err != nil {
+			t.Fatalf("unexpected marshal error for %+v: %v", order, err)
+		}
+		var res Order
+		if err := json.Unmarshal(data, &res); err != nil {
+			t.Fatalf("unexpected unmarshal error for %s: %v", data, err)
+		}
+		if !reflect.DeepEqual(res, order) {
+			t.Errorf("unexpected round trip of %s: %+v", data, res)
+		}
+	}
+}
+
+func TestJSONNames(t *testing.T) {
+	data, err := json.Marshal(Order{ID: 7, Color: Red, Value: value("s"), Skip: "skip"})
+	if err != nil {
+		t.Fatalf("unexpected marshal error: %v", err)
+	}
+	expected := ` + "`" + `{"id":7,"Color":"Red","Created":"0001-01-01T00:00:00Z","Data":null,"Value":{"type":"string","value":"s"},"Items":null,"Ptr":null,"Colors":null}` + "`" + `
+	if string(data) != expected {
+		t.Errorf("unexpected json: %s", data)
+	}
+}
+
+func TestJSONInvalidUnion(t *testing.T) {
+	var res Order
+	if err := json.Unmarshal([]byte(` + "`" + `{"Value":{"type":"bool","value":true}}` + "`" + `), &res); err == nil {
+		t.Errorf("expected error for invalid type id")
+	}
+}
+`
+
+func TestStructJSON(t *testing.T) {
+	tests := []struct {
+		unwrap bool
+		value  string
+	}{
+		{unwrap: false, value: "func value(v interface{}) Value { return Value{Value: v} }\n"},
+		{unwrap: true, value: "func value(v interface{}) interface{} { return v }\n"},
+	}
+
+	for _, test := range tests {
+		files := generate(t, Options{UnwrapUnions: test.unwrap}, map[string]string{"shop.mprot": testOrder})
+		files["value_test.go"] = "package shop\n\n" + test.value
+		runGenerated(t, files, testOrderJSON)
+	}
+}
@@ -0,0 +1,3 @@
+module github.com/mprot/mrpc-go
+
+go 1.21
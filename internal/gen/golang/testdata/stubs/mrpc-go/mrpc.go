@@ -0,0 +1,44 @@
+// Package mrpc is a stand-in for github.com/mprot/mrpc-go, which provides the
+// API used by the generated code. It is used to compile the generated code in
+// the tests.
+package mrpc
+
+import "context"
+
+// Request is a request of a service method.
+type Request struct {
+	Service string
+	Method  int64
+	Body    []byte
+}
+
+// Response is the response of a service method.
+type Response struct {
+	Body []byte
+}
+
+// Caller calls service methods.
+type Caller interface {
+	Call(ctx context.Context, req Request) (*Response, error)
+}
+
+// ResponseError returns the error of the response.
+func ResponseError(resp *Response) error { return nil }
+
+// MethodSpec describes a service method.
+type MethodSpec struct {
+	ID      int64
+	Handler func(ctx context.Context, svc interface{}, body []byte) ([]byte, error)
+}
+
+// ServiceSpec describes a service.
+type ServiceSpec struct {
+	Name    string
+	Service interface{}
+	Methods []MethodSpec
+}
+
+// Registry registers services.
+type Registry interface {
+	Register(spec ServiceSpec)
+}
@@ -0,0 +1,3 @@
+module github.com/mprot/msgpack-go
+
+go 1.21
@@ -5,7 +5,7 @@ import (
 	"strings"
 
 	"github.com/mprot/mprotc/internal/gen"
-	"github.com/mprot/mprotc/internal/schema"
+	"github.com/mprot/mprotc/schema"
 )
 
 type typeinfo struct {
@@ -79,8 +79,7 @@ func (ti *typeinfo) typeid(t schema.Type) string {
 	case *schema.DefinedType:
 		name := t.Name()
 		if t.Imported() {
-			imp := t.Decl.(*schema.Import)
-			name = strings.TrimPrefix(name, imp.Name+".")
+			name = name[strings.LastIndexByte(name, '.')+1:]
 		}
 		return gen.SnakeCase(name)
 
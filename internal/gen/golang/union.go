@@ -2,7 +2,7 @@ package golang
 
 import (
 	"github.com/mprot/mprotc/internal/gen"
-	"github.com/mprot/mprotc/internal/schema"
+	"github.com/mprot/mprotc/schema"
 )
 
 type unionGenerator struct {
@@ -15,6 +15,8 @@ func (g *unionGenerator) Generate(p gen.Printer, u *schema.Union, ti *typeinfo)
 	g.printEncodeFunc(p, u.Name, u.Branches, ti)
 	p.Println()
 	g.printDecodeFunc(p, u.Name, u.Branches, ti)
+	p.Println()
+	g.printJSONFuncs(p, u.Name, u.Branches, ti)
 	if g.typeid {
 		p.Println()
 		g.printTypeidFunc(p, u.Name, u.Branches, ti)
@@ -99,6 +101,58 @@ func (g *unionGenerator) printDecodeFunc(p gen.Printer, name string, branches []
 	p.Println(`}`)
 }
 
+// printJSONFuncs prints the JSON marshaling methods. The union is marshaled
+// as an object holding the type id and the value of the branch.
+func (g *unionGenerator) printJSONFuncs(p gen.Printer, name string, branches []schema.Branch, ti *typeinfo) {
+	p.Println(`// MarshalJSON implements the json.Marshaler interface for `, name, `. The`)
+	p.Println(`// value is marshaled as {"type": <type id>, "value": <value>}.`)
+	p.Println(`func (o `, name, `) MarshalJSON() ([]byte, error) {`)
+	p.Println(`	var typ string`)
+	p.Println(`	switch o.Value.(type) {`)
+	p.Println(`	case nil:`)
+	p.Println(`		return []byte("null"), nil`)
+	for _, b := range branches {
+		p.Println(`	case `, ti.typename(b.Type), `:`)
+		p.Println(`		typ = "`, ti.typeid(b.Type), `"`)
+	}
+	p.Println(`	default:`)
+	p.Println(`		return nil, fmt.Errorf("invalid `, name, ` type %T", o.Value)`)
+	p.Println(`	}`)
+	p.Println(`	return json.Marshal(struct {`)
+	p.Println(`		Type  string      ` + "`" + `json:"type"` + "`")
+	p.Println(`		Value interface{} ` + "`" + `json:"value"` + "`")
+	p.Println(`	}{typ, o.Value})`)
+	p.Println(`}`)
+	p.Println()
+	p.Println(`// UnmarshalJSON implements the json.Unmarshaler interface for `, name, `.`)
+	p.Println(`func (o *`, name, `) UnmarshalJSON(data []byte) error {`)
+	p.Println(`	if string(data) == "null" {`)
+	p.Println(`		o.Value = nil`)
+	p.Println(`		return nil`)
+	p.Println(`	}`)
+	p.Println(`	var u struct {`)
+	p.Println(`		Type  string          ` + "`" + `json:"type"` + "`")
+	p.Println(`		Value json.RawMessage ` + "`" + `json:"value"` + "`")
+	p.Println(`	}`)
+	p.Println(`	if err := json.Unmarshal(data, &u); err != nil {`)
+	p.Println(`		return err`)
+	p.Println(`	}`)
+	p.Println(`	switch u.Type {`)
+	for _, b := range branches {
+		p.Println(`	case "`, ti.typeid(b.Type), `":`)
+		p.Println(`		var v `, ti.typename(b.Type))
+		p.Println(`		if err := json.Unmarshal(u.Value, &v); err != nil {`)
+		p.Println(`			return err`)
+		p.Println(`		}`)
+		p.Println(`		o.Value = v`)
+	}
+	p.Println(`	default:`)
+	p.Println(`		return fmt.Errorf("invalid type id %q for `, name, `", u.Type)`)
+	p.Println(`	}`)
+	p.Println(`	return nil`)
+	p.Println(`}`)
+}
+
 func (g *unionGenerator) printTypeidFunc(p gen.Printer, name string, branches []schema.Branch, ti *typeinfo) {
 	p.Println(`// TypeID returns the type id for the underlying value of `, name, `.`)
 	p.Println(`func (o *`, name, `) TypeID() string {`)
@@ -0,0 +1,138 @@
+package golang
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/mprot/mprotc/internal/gen"
+	"github.com/mprot/mprotc/schema"
+)
+
+// Validate checks that the schema files generated into the same directory
+// form a valid Go package: all files have to declare the same package name,
+// and the names of their top-level declarations must not collide. A conflict
+// is reported at both positions, the error at the first declaration refers to
+// the conflicting one.
+//
+// The registry needs the import paths of the generated packages, so it cannot
+// be enabled without an import root. It also identifies the registered types
+// by their type ids, so two types of a package must not map to the same id,
+// e.g. HTTPServer and HttpServer.
+func (g *Generator) Validate(s schema.Schema) error {
+	if g.registry != nil && g.importRoot == "" {
+		return errors.New("--registry requires --import-root")
+	}
+
+	type declared struct {
+		decl schema.Decl
+		kind string
+	}
+
+	var (
+		errs     schema.ErrorList
+		packages = make(map[string]*schema.Package)     // directory => first package
+		names    = make(map[string]map[string]declared) // directory => go name => declaration
+		typeids  = make(map[string]map[string]declared) // directory => type id => declaration
+	)
+	conflict := func(decl, other schema.Decl, code schema.ErrorCode, text, otherText string) {
+		errs = append(errs,
+			schema.Error{Pos: decl.Pos(), End: decl.End(), Code: code, Text: fmt.Sprintf("%s (other declaration at %s)", text, other.Pos())},
+			schema.Error{Pos: other.Pos(), End: other.End(), Code: code, Text: fmt.Sprintf("%s (conflicting declaration at %s)", otherText, decl.Pos())},
+		)
+	}
+
+	for _, f := range s {
+		dir := filepath.Dir(f.Name)
+		if pkg, has := packages[dir]; !has {
+			packages[dir] = f.Package
+			names[dir] = make(map[string]declared)
+			typeids[dir] = make(map[string]declared)
+		} else if pkg.Name != f.Package.Name {
+			conflict(f.Package, pkg, schema.CodeConflictingPackage,
+				fmt.Sprintf("package %s conflicts with package %s in the same directory", f.Package.Name, pkg.Name),
+				fmt.Sprintf("package %s conflicts with package %s in the same directory", pkg.Name, f.Package.Name),
+			)
+			continue
+		}
+
+		for _, decl := range f.Decls {
+			g.goNames(decl, func(name, kind string) {
+				if prev, has := names[dir][name]; has {
+					conflict(decl, prev.decl, schema.CodeRedeclaredName,
+						fmt.Sprintf("%s %s redeclared in package %s", kind, name, f.Package.Name),
+						fmt.Sprintf("%s %s redeclared in package %s", prev.kind, name, f.Package.Name),
+					)
+					return
+				}
+				names[dir][name] = declared{decl: decl, kind: kind}
+			})
+
+			if g.registry != nil {
+				kind, name, has := registryType(decl)
+				if !has {
+					continue
+				}
+				typeid := gen.SnakeCase(name)
+				if prev, has := typeids[dir][typeid]; has {
+					_, prevName, _ := registryType(prev.decl)
+					if prevName == name {
+						continue // already reported as redeclared name
+					}
+					conflict(decl, prev.decl, schema.CodeDuplicateTypeID,
+						fmt.Sprintf("type id %s of %s %s conflicts with %s %s", typeid, kind, name, prev.kind, prevName),
+						fmt.Sprintf("type id %s of %s %s conflicts with %s %s", typeid, prev.kind, prevName, kind, name),
+					)
+					continue
+				}
+				typeids[dir][typeid] = declared{decl: decl, kind: kind}
+			}
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// goNames calls fn for the names of all top-level Go declarations generated
+// for the given schema declaration. The kind describes the origin of a name.
+func (g *Generator) goNames(decl schema.Decl, fn func(name, kind string)) {
+	switch decl := decl.(type) {
+	case *schema.Const:
+		fn(decl.Name, "const")
+	case *schema.Enum:
+		fn(decl.Name, "enum")
+		fn(decl.Name+"Values", "values function of enum "+decl.Name)
+		fn("Parse"+decl.Name, "parse function of enum "+decl.Name)
+		for _, e := range decl.Enumerators {
+			fn(g.enum.enumerator(decl.Name, e), "enumerator of "+decl.Name)
+		}
+	case *schema.Struct:
+		fn(decl.Name, "struct")
+	case *schema.Union:
+		fn(decl.Name, "union")
+	case *schema.Service:
+		client := gen.TitleFirstWord(decl.Name) + "Client"
+		fn(decl.Name, "service")
+		fn(client, "client of service "+decl.Name)
+		fn("New"+client, "client constructor of service "+decl.Name)
+		fn("Register"+gen.TitleFirstWord(decl.Name), "register function of service "+decl.Name)
+	}
+}
+
+// registryType returns the kind and the name of a declaration, which is
+// registered at the runtime type registry.
+func registryType(decl schema.Decl) (kind, name string, ok bool) {
+	switch decl := decl.(type) {
+	case *schema.Enum:
+		return "enum", decl.Name, true
+	case *schema.Struct:
+		return "struct", decl.Name, true
+	case *schema.Union:
+		return "union", decl.Name, true
+	default:
+		return "", "", false
+	}
+}
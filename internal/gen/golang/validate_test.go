@@ -0,0 +1,160 @@
+package golang
+
+import (
+	"testing"
+
+	"github.com/mprot/mprotc/schema"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		scoped   bool
+		sources  map[string]string
+		expected []string
+		ends     []string // end positions of the errors
+	}{
+		{ // same names in different directories
+			sources: map[string]string{
+				"a.mprot":     "package a\n\nstruct S {\n}\n",
+				"sub/b.mprot": "package b\n\nstruct S {\n}\n",
+			},
+		},
+		{
+			sources: map[string]string{
+				"a.mprot": "package a\n",
+				"b.mprot": "package b\n",
+			},
+			expected: []string{
+				"b.mprot:1:1: package b conflicts with package a in the same directory (other declaration at a.mprot:1:1)",
+				"a.mprot:1:1: package a conflicts with package b in the same directory (conflicting declaration at b.mprot:1:1)",
+			},
+			ends: []string{"b.mprot:1:10", "a.mprot:1:10"},
+		},
+		{
+			sources: map[string]string{
+				"a.mprot": "package a\n\nstruct S {\n}\n",
+				"b.mprot": "package a\n\nunion S {\n\tstring \"1\"\n}\n",
+			},
+			expected: []string{
+				"b.mprot:3:1: union S redeclared in package a (other declaration at a.mprot:3:1)",
+				"a.mprot:3:1: struct S redeclared in package a (conflicting declaration at b.mprot:3:1)",
+			},
+			ends: []string{"b.mprot:3:8", "a.mprot:3:9"},
+		},
+		{
+			sources: map[string]string{
+				"a.mprot": "package a\n\nenum Color {\n\tRed \"1\"\n}\n",
+				"b.mprot": "package a\n\nconst Red = 1\n\nstruct ColorValues {\n}\n\nstruct ParseColor {\n}\n",
+			},
+			expected: []string{
+				"b.mprot:3:1: const Red redeclared in package a (other declaration at a.mprot:3:1)",
+				"a.mprot:3:1: enumerator of Color Red redeclared in package a (conflicting declaration at b.mprot:3:1)",
+				"b.mprot:5:1: struct ColorValues redeclared in package a (other declaration at a.mprot:3:1)",
+				"a.mprot:3:1: values function of enum Color ColorValues redeclared in package a (conflicting declaration at b.mprot:5:1)",
+				"b.mprot:8:1: struct ParseColor redeclared in package a (other declaration at a.mprot:3:1)",
+				"a.mprot:3:1: parse function of enum Color ParseColor redeclared in package a (conflicting declaration at b.mprot:8:1)",
+			},
+		},
+		{
+			scoped: true,
+			sources: map[string]string{
+				"a.mprot": "package a\n\nenum Color {\n\tRed \"1\"\n}\n",
+				"b.mprot": "package a\n\nconst Red = 1\n\nconst ColorRed = 1\n",
+			},
+			expected: []string{
+				"b.mprot:5:1: const ColorRed redeclared in package a (other declaration at a.mprot:3:1)",
+				"a.mprot:3:1: enumerator of Color ColorRed redeclared in package a (conflicting declaration at b.mprot:5:1)",
+			},
+		},
+		{
+			sources: map[string]string{
+				"a.mprot": "package a\n\nservice Store {\n}\n",
+				"b.mprot": "package a\n\nstruct StoreClient {\n}\n\nconst NewStoreClient = 1\n\nconst RegisterStore = 1\n",
+			},
+			expected: []string{
+				"b.mprot:3:1: struct StoreClient redeclared in package a (other declaration at a.mprot:3:1)",
+				"a.mprot:3:1: client of service Store StoreClient redeclared in package a (conflicting declaration at b.mprot:3:1)",
+				"b.mprot:6:1: const NewStoreClient redeclared in package a (other declaration at a.mprot:3:1)",
+				"a.mprot:3:1: client constructor of service Store NewStoreClient redeclared in package a (conflicting declaration at b.mprot:6:1)",
+				"b.mprot:8:1: const RegisterStore redeclared in package a (other declaration at a.mprot:3:1)",
+				"a.mprot:3:1: register function of service Store RegisterStore redeclared in package a (conflicting declaration at b.mprot:8:1)",
+			},
+		},
+	}
+
+	for i, test := range tests {
+		err := NewGenerator(Options{ScopedEnums: test.scoped}).Validate(parse(t, test.sources))
+		if len(test.expected) == 0 {
+			if err != nil {
+				t.Errorf("unexpected error for test %d: %v", i, err)
+			}
+			continue
+		}
+
+		errs, ok := err.(schema.ErrorList)
+		if !ok {
+			t.Errorf("unexpected error for test %d: %v", i, err)
+			continue
+		}
+		if len(errs) != len(test.expected) {
+			t.Errorf("unexpected number of errors for test %d: %v", i, errs)
+			continue
+		}
+		for k, e := range errs {
+			if e.Error() != test.expected[k] {
+				t.Errorf("unexpected error %d for test %d: %s", k, i, e)
+			}
+			if k < len(test.ends) && e.End.String() != test.ends[k] {
+				t.Errorf("unexpected end position of error %d for test %d: %s", k, i, e.End)
+			}
+		}
+	}
+}
+
+func TestValidateRegistry(t *testing.T) {
+	s := parse(t, map[string]string{"a.mprot": "package a\n\nstruct S {\n}\n"})
+
+	err := NewGenerator(Options{Registry: true}).Validate(s)
+	if err == nil || err.Error() != "--registry requires --import-root" {
+		t.Errorf("unexpected error without import root: %v", err)
+	}
+
+	if err := NewGenerator(Options{Registry: true, ImportRoot: "example.com/gen"}).Validate(s); err != nil {
+		t.Errorf("unexpected error with import root: %v", err)
+	}
+	if err := NewGenerator(Options{}).Validate(s); err != nil {
+		t.Errorf("unexpected error without registry: %v", err)
+	}
+
+	// registered types of a package need distinct type ids
+	s = parse(t, map[string]string{
+		"a.mprot":     "package a\n\nstruct HTTPServer {\n}\n\nenum Color {\n\tRed \"1\"\n}\n\nservice Store {\n}\n",
+		"b.mprot":     "package a\n\nstruct HttpServer {\n}\n\nunion color {\n\tstring \"1\"\n}\n\nstruct store {\n}\n\nstruct S {\n}\n",
+		"sub/c.mprot": "package sub\n\nstruct HttpServer {\n}\n",
+	})
+	if err := NewGenerator(Options{}).Validate(s); err != nil {
+		t.Errorf("unexpected error without registry: %v", err)
+	}
+	err = NewGenerator(Options{Registry: true, ImportRoot: "example.com/gen"}).Validate(s)
+	errs, ok := err.(schema.ErrorList)
+	if !ok {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []string{
+		"b.mprot:3:1: type id http_server of struct HttpServer conflicts with struct HTTPServer (other declaration at a.mprot:3:1)",
+		"a.mprot:3:1: type id http_server of struct HTTPServer conflicts with struct HttpServer (conflicting declaration at b.mprot:3:1)",
+		"b.mprot:6:1: type id color of union color conflicts with enum Color (other declaration at a.mprot:6:1)",
+		"a.mprot:6:1: type id color of enum Color conflicts with union color (conflicting declaration at b.mprot:6:1)",
+	}
+	if len(errs) != len(expected) {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	for i, e := range errs {
+		if e.Error() != expected[i] || e.Code != schema.CodeDuplicateTypeID {
+			t.Errorf("unexpected error %d: %s (%s)", i, e, e.Code)
+		}
+	}
+	if end := errs[0].End.String(); end != "b.mprot:3:18" {
+		t.Errorf("unexpected end position: %s", end)
+	}
+}
@@ -2,7 +2,7 @@ package js
 
 import (
 	"github.com/mprot/mprotc/internal/gen"
-	"github.com/mprot/mprotc/internal/schema"
+	"github.com/mprot/mprotc/schema"
 )
 
 type enumGenerator struct {
@@ -6,12 +6,14 @@ import (
 	"strings"
 
 	"github.com/mprot/mprotc/internal/gen"
-	"github.com/mprot/mprotc/internal/schema"
+	"github.com/mprot/mprotc/internal/parallel"
+	"github.com/mprot/mprotc/schema"
 )
 
 // Options holds all the options for the JavaScript language generator.
 type Options struct {
 	TypeDecls bool // generate type declarations?
+	Jobs      int  // maximum number of files generated concurrently, GOMAXPROCS if <= 0
 }
 
 // Generator represents a code generator for the JavaScript language.
@@ -22,6 +24,7 @@ type Generator struct {
 	union unionGenerator
 
 	typeDecls bool
+	jobs      int
 }
 
 // NewGenerator creates a new JavaScript code generator with the given options.
@@ -31,17 +34,18 @@ func NewGenerator(opts Options) *Generator {
 			typeDecls: opts.TypeDecls,
 		},
 		typeDecls: opts.TypeDecls,
+		jobs:      opts.Jobs,
 	}
 }
 
 // Generate generates the JavaScript code for the given schema and prints it to p.
 func (g *Generator) Generate(w *gen.FileWriter, s schema.Schema) {
-	for _, file := range s {
-		g.generate(w.Printer(file.Name, ".js"), file)
+	parallel.Each(g.jobs, len(s), func(i int) {
+		g.generate(w.Printer(s[i].Name, ".js"), s[i])
 		if g.typeDecls {
-			g.generateTypeDecls(w.Printer(file.Name, ".d.ts"), file)
+			g.generateTypeDecls(w.Printer(s[i].Name, ".d.ts"), s[i])
 		}
-	}
+	})
 }
 
 func (g *Generator) generate(p gen.Printer, f *schema.File) {
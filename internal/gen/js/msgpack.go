@@ -4,7 +4,7 @@ import (
 	"sort"
 
 	"github.com/mprot/mprotc/internal/gen"
-	"github.com/mprot/mprotc/internal/schema"
+	"github.com/mprot/mprotc/schema"
 )
 
 func msgpackImports(f *schema.File) []string {
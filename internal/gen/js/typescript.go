@@ -4,7 +4,7 @@ import (
 	"fmt"
 
 	"github.com/mprot/mprotc/internal/gen"
-	"github.com/mprot/mprotc/internal/schema"
+	"github.com/mprot/mprotc/schema"
 )
 
 func typescriptImports(f *schema.File) []string {
@@ -3,9 +3,6 @@ package gen
 import (
 	"bytes"
 	"fmt"
-	"io"
-	"os"
-	"path/filepath"
 )
 
 // Printer defines an interface for printing lines of code into a memory buffer.
@@ -24,6 +21,8 @@ func PrefixedPrinter(p Printer, prefix string) Printer {
 
 type printer struct {
 	bytes.Buffer
+	source string     // mprot file the printer was requested for
+	status FileStatus // zero until flushed
 }
 
 func (p *printer) Println(args ...interface{}) {
@@ -31,30 +30,6 @@ func (p *printer) Println(args ...interface{}) {
 	p.WriteByte('\n')
 }
 
-func (p *printer) writeToFile(filename string) error {
-	if dir := filepath.Dir(filename); dir != "." && dir != string(filepath.Separator) {
-		if err := os.MkdirAll(dir, os.ModePerm); err != nil {
-			return err
-		}
-	}
-
-	f, err := os.Create(filename)
-	if err != nil {
-		return err
-	}
-	defer f.Close()
-
-	n, err := f.Write(p.Bytes())
-	switch {
-	case err != nil:
-		return err
-	case n != p.Len():
-		return io.ErrShortWrite
-	default:
-		return nil
-	}
-}
-
 type prefixedPrinter struct {
 	p      Printer
 	prefix string
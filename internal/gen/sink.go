@@ -0,0 +1,190 @@
+package gen
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Sink receives the files flushed by a file writer. The file names are
+// slash-separated and relative to the root directory of the file writer.
+type Sink interface {
+	WriteFile(name string, content []byte) (FileStatus, error)
+}
+
+// DirSink writes the files into a directory of the local file system. Files
+// with an unchanged content are not rewritten. All other files are written to
+// a temporary file first, which is then renamed to the target file. Hence, a
+// target file is never half-written.
+type DirSink string
+
+func (s DirSink) WriteFile(name string, content []byte) (FileStatus, error) {
+	filename := filepath.Join(string(s), filepath.FromSlash(name))
+
+	status := FileCreated
+	perm := os.FileMode(0644)
+	switch old, err := os.ReadFile(filename); {
+	case err == nil && bytes.Equal(old, content):
+		return FileUnchanged, nil
+	case err == nil:
+		status = FileUpdated
+		if info, err := os.Stat(filename); err == nil {
+			perm = info.Mode().Perm()
+		}
+	case !errors.Is(err, fs.ErrNotExist):
+		return 0, err
+	}
+
+	dir := filepath.Dir(filename)
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return 0, err
+	}
+
+	f, err := os.CreateTemp(dir, "."+filepath.Base(filename)+".*.tmp")
+	if err != nil {
+		return 0, err
+	}
+	tmpname := f.Name()
+
+	_, err = f.Write(content)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmpname, perm)
+	}
+	if err == nil {
+		err = os.Rename(tmpname, filename)
+	}
+	if err != nil {
+		os.Remove(tmpname)
+		return 0, err
+	}
+	return status, nil
+}
+
+// MemorySink collects the files in memory.
+type MemorySink struct {
+	mtx   sync.Mutex
+	files map[string][]byte // name => content
+}
+
+func (s *MemorySink) WriteFile(name string, content []byte) (FileStatus, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if s.files == nil {
+		s.files = make(map[string][]byte)
+	}
+	status := FileCreated
+	if old, has := s.files[name]; has {
+		status = FileUpdated
+		if bytes.Equal(old, content) {
+			status = FileUnchanged
+		}
+	}
+	s.files[name] = append([]byte(nil), content...)
+	return status, nil
+}
+
+// Files returns the collected files by their names.
+func (s *MemorySink) Files() map[string][]byte {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	files := make(map[string][]byte, len(s.files))
+	for name, content := range s.files {
+		files[name] = content
+	}
+	return files
+}
+
+// ArchiveFormat specifies the format of an archive sink.
+type ArchiveFormat string
+
+const (
+	Tar ArchiveFormat = "tar"
+	Zip ArchiveFormat = "zip"
+)
+
+// ArchiveFormatOf returns the archive format for the given file name, which
+// is determined by the file extension.
+func ArchiveFormatOf(filename string) (ArchiveFormat, error) {
+	switch ext := strings.ToLower(filepath.Ext(filename)); ext {
+	case ".tar":
+		return Tar, nil
+	case ".zip":
+		return Zip, nil
+	default:
+		return "", fmt.Errorf("unsupported archive format %q (expected .tar or .zip)", ext)
+	}
+}
+
+// ArchiveSink writes the files into a tar or zip archive. The archive must be
+// closed after the last file was written.
+type ArchiveSink struct {
+	tar *tar.Writer
+	zip *zip.Writer
+}
+
+// NewArchiveSink creates an archive sink of the given format writing to w.
+func NewArchiveSink(w io.Writer, format ArchiveFormat) (*ArchiveSink, error) {
+	switch format {
+	case Tar:
+		return &ArchiveSink{tar: tar.NewWriter(w)}, nil
+	case Zip:
+		return &ArchiveSink{zip: zip.NewWriter(w)}, nil
+	default:
+		return nil, fmt.Errorf("unsupported archive format %q", format)
+	}
+}
+
+func (s *ArchiveSink) WriteFile(name string, content []byte) (FileStatus, error) {
+	if !fs.ValidPath(name) {
+		return 0, fmt.Errorf("cannot add %s to archive: file is outside of the output directory", name)
+	}
+
+	// use a fixed modification time to get reproducible archives
+	modTime := time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	if s.tar != nil {
+		err := s.tar.WriteHeader(&tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     name,
+			Mode:     0644,
+			Size:     int64(len(content)),
+			ModTime:  modTime,
+		})
+		if err == nil {
+			_, err = s.tar.Write(content)
+		}
+		return FileCreated, err
+	}
+
+	f, err := s.zip.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: modTime,
+	})
+	if err == nil {
+		_, err = f.Write(content)
+	}
+	return FileCreated, err
+}
+
+// Close finishes the archive. It does not close the underlying writer.
+func (s *ArchiveSink) Close() error {
+	if s.tar != nil {
+		return s.tar.Close()
+	}
+	return s.zip.Close()
+}
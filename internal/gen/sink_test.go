@@ -0,0 +1,98 @@
+package gen
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestArchiveSink(t *testing.T) {
+	files := map[string]string{
+		"a.go":     "package a\n",
+		"sub/b.go": "package sub\n",
+	}
+
+	for _, format := range []ArchiveFormat{Tar, Zip} {
+		t.Run(string(format), func(t *testing.T) {
+			var buf bytes.Buffer
+			sink, err := NewArchiveSink(&buf, format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for name, content := range files {
+				if _, err := sink.WriteFile(name, []byte(content)); err != nil {
+					t.Fatalf("unexpected write error: %v", err)
+				}
+			}
+			if _, err := sink.WriteFile("../c.go", nil); err == nil {
+				t.Errorf("expected error for file outside of the output directory")
+			}
+			if err := sink.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			got := make(map[string]string)
+			switch format {
+			case Tar:
+				r := tar.NewReader(&buf)
+				for {
+					hdr, err := r.Next()
+					if err == io.EOF {
+						break
+					} else if err != nil {
+						t.Fatal(err)
+					}
+					content, _ := io.ReadAll(r)
+					got[hdr.Name] = string(content)
+				}
+			case Zip:
+				r, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+				if err != nil {
+					t.Fatal(err)
+				}
+				for _, f := range r.File {
+					rc, err := f.Open()
+					if err != nil {
+						t.Fatal(err)
+					}
+					content, _ := io.ReadAll(rc)
+					rc.Close()
+					got[f.Name] = string(content)
+				}
+			}
+
+			if len(got) != len(files) {
+				t.Fatalf("unexpected archive files: %v", got)
+			}
+			for name, content := range files {
+				if got[name] != content {
+					t.Errorf("unexpected content of %s: %q", name, got[name])
+				}
+			}
+		})
+	}
+}
+
+func TestMemorySink(t *testing.T) {
+	w, err := NewFileWriter("out")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Printer("api/user.mprot", ".go").Println("package api")
+
+	var sink MemorySink
+	if err := w.FlushTo(&sink); err != nil {
+		t.Fatal(err)
+	}
+	if files := sink.Files(); len(files) != 1 || string(files["api/user.go"]) != "package api\n" {
+		t.Errorf("unexpected files: %v", files)
+	}
+
+	w.WalkStatus(func(filename string, status FileStatus) {
+		if status != FileCreated {
+			t.Errorf("unexpected status for %s: %v", filename, status)
+		}
+	})
+}
@@ -0,0 +1,149 @@
+package tmpl
+
+import (
+	"strings"
+	"text/template"
+
+	"github.com/mprot/mprotc/internal/gen"
+	"github.com/mprot/mprotc/schema"
+)
+
+var funcs = template.FuncMap{
+	// strings
+	"snakeCase":      gen.SnakeCase,
+	"titleFirstWord": gen.TitleFirstWord,
+	"lowerFirstWord": gen.LowerFirstWord,
+	"rpad":           gen.RPad,
+	"lower":          strings.ToLower,
+	"upper":          strings.ToUpper,
+	"join":           strings.Join,
+
+	// declarations
+	"declName": declName,
+	"declKind": declKind,
+	"consts":   filterDecls("const"),
+	"enums":    filterDecls("enum"),
+	"structs":  filterDecls("struct"),
+	"unions":   filterDecls("union"),
+	"services": filterDecls("service"),
+
+	// types
+	"typeName":  typeName,
+	"localName": localName,
+	"typeKind":  typeKind,
+	"elemType":  elemType,
+	"keyType":   keyType,
+}
+
+func declName(decl schema.Decl) string {
+	switch decl := decl.(type) {
+	case *schema.Const:
+		return decl.Name
+	case *schema.Enum:
+		return decl.Name
+	case *schema.Struct:
+		return decl.Name
+	case *schema.Union:
+		return decl.Name
+	case *schema.Service:
+		return decl.Name
+	default:
+		return ""
+	}
+}
+
+func declKind(decl schema.Decl) string {
+	switch decl.(type) {
+	case *schema.Const:
+		return "const"
+	case *schema.Enum:
+		return "enum"
+	case *schema.Struct:
+		return "struct"
+	case *schema.Union:
+		return "union"
+	case *schema.Service:
+		return "service"
+	default:
+		return ""
+	}
+}
+
+func filterDecls(kind string) func(decls []schema.Decl) []schema.Decl {
+	return func(decls []schema.Decl) []schema.Decl {
+		var res []schema.Decl
+		for _, decl := range decls {
+			if declKind(decl) == kind {
+				res = append(res, decl)
+			}
+		}
+		return res
+	}
+}
+
+// typeName returns the name of the type as written in the schema.
+func typeName(t schema.Type) string {
+	return t.Name()
+}
+
+// localName returns the name of the type without the import qualifier.
+func localName(t schema.Type) string {
+	if dt, ok := t.(*schema.DefinedType); ok {
+		return dt.LocalName()
+	}
+	return t.Name()
+}
+
+func typeKind(t schema.Type) string {
+	switch t := t.(type) {
+	case *schema.Bool:
+		return "bool"
+	case *schema.Int:
+		return "int"
+	case *schema.Float:
+		return "float"
+	case *schema.String:
+		return "string"
+	case *schema.Bytes:
+		return "bytes"
+	case *schema.Array:
+		return "array"
+	case *schema.Map:
+		return "map"
+	case *schema.Raw:
+		return "raw"
+	case *schema.Time:
+		return "time"
+	case *schema.Pointer:
+		return "pointer"
+	case *schema.DefinedType:
+		if kind := declKind(t.Decl); kind != "" {
+			return kind
+		}
+		return "defined"
+	default:
+		return ""
+	}
+}
+
+// elemType returns the value type of an array, map, or pointer type.
+func elemType(t schema.Type) schema.Type {
+	switch t := t.(type) {
+	case *schema.Array:
+		return t.Value
+	case *schema.Map:
+		return t.Value
+	case *schema.Pointer:
+		return t.Value
+	default:
+		return nil
+	}
+}
+
+// keyType returns the key type of a map type.
+func keyType(t schema.Type) schema.Type {
+	if m, ok := t.(*schema.Map); ok {
+		return m.Key
+	}
+	return nil
+}
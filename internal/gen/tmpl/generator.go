@@ -0,0 +1,107 @@
+package tmpl
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/mprot/mprotc/internal/gen"
+	"github.com/mprot/mprotc/internal/parallel"
+	"github.com/mprot/mprotc/schema"
+)
+
+// Options holds all the options for the template generator.
+type Options struct {
+	Template  string // filename of the template
+	Extension string // file extension of the outputs per schema file
+	Aggregate string // filename of the aggregate output, empty for one output per schema file
+	Jobs      int    // maximum number of files generated concurrently, GOMAXPROCS if <= 0
+}
+
+// Generator represents a generator, which executes a text/template over
+// the schema.
+type Generator struct {
+	tmpl      *template.Template
+	ext       string
+	aggregate string
+	jobs      int
+}
+
+// Data holds the data passed to the template. For an aggregate output, File
+// is nil.
+type Data struct {
+	File  *schema.File
+	Files schema.Schema
+}
+
+// NewGenerator creates a new template generator by parsing the template file
+// of the given options. If no extension is given, it is derived from the
+// template filename, e.g. ".sql" for "schema.sql.tmpl".
+func NewGenerator(opts Options) (*Generator, error) {
+	if opts.Template == "" {
+		return nil, errors.New("missing template file")
+	}
+
+	src, err := os.ReadFile(opts.Template)
+	if err != nil {
+		return nil, err
+	}
+
+	name := filepath.Base(opts.Template)
+	tmpl, err := template.New(name).Funcs(funcs).Parse(string(src))
+	if err != nil {
+		return nil, err
+	}
+
+	ext := opts.Extension
+	if ext == "" {
+		ext = filepath.Ext(strings.TrimSuffix(name, filepath.Ext(name)))
+		if ext == "" {
+			ext = ".txt"
+		}
+	} else if !strings.HasPrefix(ext, ".") {
+		ext = "." + ext
+	}
+
+	return &Generator{
+		tmpl:      tmpl,
+		ext:       ext,
+		aggregate: opts.Aggregate,
+		jobs:      opts.Jobs,
+	}, nil
+}
+
+// Generate executes the template for the given schema and writes the outputs
+// to w.
+func (g *Generator) Generate(w *gen.FileWriter, s schema.Schema) error {
+	if g.aggregate != "" {
+		content, err := g.execute(Data{Files: s})
+		if err != nil {
+			return err
+		}
+		w.Write(g.aggregate, "", content)
+		return nil
+	}
+
+	return parallel.FirstError(g.jobs, len(s), func(i int) error {
+		f := s[i]
+		content, err := g.execute(Data{File: f, Files: s})
+		if err != nil {
+			return fmt.Errorf("%s: %v", f.Name, err)
+		}
+		w.Write(strings.TrimSuffix(f.Name, filepath.Ext(f.Name))+g.ext, f.Name, content)
+		return nil
+	})
+}
+
+func (g *Generator) execute(data Data) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := g.tmpl.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,194 @@
+package tmpl
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/mprot/mprotc/internal/gen"
+	"github.com/mprot/mprotc/schema"
+)
+
+var testSources = map[string]string{
+	"a.mprot": "// first\n// second\npackage a\n\nimport \"sub/b.mprot\"\n\n" +
+		"const Max = 10\n\n" +
+		"enum Color {\n\tRed \"1\"\n}\n\n" +
+		"struct UserInfo {\n\tID int64 \"1\"\n\tTags []string \"2\"\n\tAttrs map[string]b.Item \"3\"\n\tParent *UserInfo \"4\"\n\tColor Color \"5\"\n}\n\n" +
+		"union Value {\n\tstring \"1\"\n}\n\n" +
+		"service Store {\n}\n",
+	"sub/b.mprot": "package sub\n\nstruct Item {\n}\n",
+}
+
+// generate executes the template with the given content and options for
+// the test sources and returns the outputs by name.
+func generate(t *testing.T, templateName string, content string, opts Options) map[string]string {
+	t.Helper()
+
+	opts.Template = filepath.Join(t.TempDir(), templateName)
+	if err := os.WriteFile(opts.Template, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	g, err := NewGenerator(opts)
+	if err != nil {
+		t.Fatalf("unexpected template error: %v", err)
+	}
+
+	fsys := make(fstest.MapFS, len(testSources))
+	for name, src := range testSources {
+		fsys[name] = &fstest.MapFile{Data: []byte(src)}
+	}
+	s, err := schema.ParseFS(fsys, []string{"**/*.mprot"})
+	if err != nil {
+		t.Fatalf("unexpected parse error: %v", err)
+	}
+
+	w, err := gen.NewFileWriter(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Generate(w, s); err != nil {
+		t.Fatalf("unexpected generate error: %v", err)
+	}
+
+	var sink gen.MemorySink
+	if err := w.FlushTo(&sink); err != nil {
+		t.Fatal(err)
+	}
+	files := make(map[string]string)
+	for name, content := range sink.Files() {
+		files[name] = string(content)
+	}
+	return files
+}
+
+func TestGeneratePerFile(t *testing.T) {
+	files := generate(t, "schema.sql.tmpl", "{{ .File.Package.Name }} {{ len .Files }}\n", Options{})
+
+	expected := map[string]string{
+		"a.sql":     "a 2\n",
+		"sub/b.sql": "sub 2\n",
+	}
+	if !reflect.DeepEqual(files, expected) {
+		t.Errorf("unexpected outputs: %v", files)
+	}
+}
+
+func TestGenerateAggregate(t *testing.T) {
+	files := generate(t, "index.tmpl", "{{ if not .File }}{{ range .Files }}{{ .Package.Name }};{{ end }}{{ end }}\n", Options{Aggregate: "index.txt"})
+
+	expected := map[string]string{"index.txt": "a;sub;\n"}
+	if !reflect.DeepEqual(files, expected) {
+		t.Errorf("unexpected outputs: %v", files)
+	}
+}
+
+func TestExtension(t *testing.T) {
+	tests := []struct {
+		template  string
+		extension string
+		expected  string
+	}{
+		{template: "schema.sql.tmpl", expected: ".sql"},
+		{template: "schema.tmpl", expected: ".txt"},
+		{template: "schema", expected: ".txt"},
+		{template: "schema.sql.tmpl", extension: "md", expected: ".md"},
+		{template: "schema.tmpl", extension: ".d.ts", expected: ".d.ts"},
+	}
+
+	for _, test := range tests {
+		filename := filepath.Join(t.TempDir(), test.template)
+		if err := os.WriteFile(filename, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+		g, err := NewGenerator(Options{Template: filename, Extension: test.extension})
+		if err != nil {
+			t.Fatalf("unexpected template error: %v", err)
+		}
+		if g.ext != test.expected {
+			t.Errorf("unexpected extension for %s and %q: %s (expected %s)", test.template, test.extension, g.ext, test.expected)
+		}
+	}
+}
+
+func TestGeneratorErrors(t *testing.T) {
+	if _, err := NewGenerator(Options{}); err == nil || err.Error() != "missing template file" {
+		t.Errorf("unexpected error without template: %v", err)
+	}
+	if _, err := NewGenerator(Options{Template: filepath.Join(t.TempDir(), "missing.tmpl")}); err == nil {
+		t.Errorf("expected error for missing template")
+	}
+
+	filename := filepath.Join(t.TempDir(), "invalid.tmpl")
+	if err := os.WriteFile(filename, []byte("{{ .File.Name "), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewGenerator(Options{Template: filename}); err == nil {
+		t.Errorf("expected error for invalid template")
+	}
+
+	// execution errors are reported with the schema file
+	if err := os.WriteFile(filename, []byte("{{ .File.Unknown }}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	g, err := NewGenerator(Options{Template: filename})
+	if err != nil {
+		t.Fatalf("unexpected template error: %v", err)
+	}
+	w, err := gen.NewFileWriter(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := schema.ParseSources(map[string]string{"a.mprot": "package a\n"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Generate(w, s); err == nil || !strings.HasPrefix(err.Error(), "a.mprot: ") {
+		t.Errorf("unexpected execution error: %v", err)
+	}
+}
+
+func TestFuncs(t *testing.T) {
+	const content = `{{ with .File }}{{ if eq .Package.Name "a" -}}
+{{ range .Decls }}{{ declKind . }} {{ declName . }}
+{{ end -}}
+{{ range consts .Decls }}const {{ upper .Name }}
+{{ end -}}
+{{ range enums .Decls }}enum {{ lower .Name }}
+{{ end -}}
+{{ range unions .Decls }}union {{ lowerFirstWord .Name }}
+{{ end -}}
+{{ range services .Decls }}service {{ rpad .Name 6 }}|
+{{ end -}}
+{{ range structs .Decls }}struct {{ snakeCase .Name }} {{ titleFirstWord "userInfo" }}
+{{ range .Fields }}{{ .Name }}: {{ typeKind .Type }} {{ typeName .Type }} {{ localName .Type }}
+{{- with elemType .Type }} elem={{ typeName . }}/{{ localName . }}{{ end }}
+{{- with keyType .Type }} key={{ typeName . }}{{ end }}
+{{ end }}{{ end -}}
+{{ join .Doc "+" }}
+{{ end }}{{ end }}`
+
+	files := generate(t, "funcs.tmpl", content, Options{})
+	expected := `const Max
+enum Color
+struct UserInfo
+union Value
+service Store
+const MAX
+enum color
+union value
+service Store |
+struct user_info UserInfo
+ID: int int64 int64
+Tags: array []string []string elem=string/string
+Attrs: map map[string]b.Item map[string]b.Item elem=b.Item/Item key=string
+Parent: pointer *UserInfo *UserInfo elem=UserInfo/UserInfo
+Color: enum Color Color
+first+second
+`
+	if files["a.txt"] != expected {
+		t.Errorf("unexpected output:\n%s\nexpected:\n%s", files["a.txt"], expected)
+	}
+}
@@ -0,0 +1,351 @@
+package lsp
+
+import (
+	"encoding/json"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/mprot/mprotc/internal/gen"
+	"github.com/mprot/mprotc/schema"
+)
+
+var builtinTypes = []string{
+	"bool", "int", "int8", "int16", "int32", "int64",
+	"uint", "uint8", "uint16", "uint32", "uint64",
+	"float32", "float64", "string", "bytes", "raw", "time", "map",
+}
+
+// qualifiedPrefix matches the text before the cursor, if an imported type
+// name is completed.
+var qualifiedPrefix = regexp.MustCompile(`([A-Za-z_][A-Za-z0-9_]*)\.[A-Za-z0-9_]*$`)
+
+func (s *server) hover(params json.RawMessage) (interface{}, error) {
+	var p textDocumentPositionParams
+	if err := unmarshalParams(params, &p); err != nil {
+		return nil, err
+	}
+
+	pf := s.ws.parse(uriToPath(p.TextDocument.URI))
+	id := pf.identAt(p.Position)
+	if id == nil {
+		return nil, nil
+	}
+	path, name := pf.target(*id)
+	if path == "" {
+		return nil, nil
+	}
+	decl := findDecl(s.ws.parse(path).file, name)
+	if decl == nil {
+		return nil, nil
+	}
+
+	text := "```mprot\n" + declSignature(decl) + "\n```"
+	if doc := declDoc(decl); len(doc) != 0 {
+		text += "\n\n" + strings.Join(doc, "\n")
+	}
+	r := pf.textRange(id.Pos, id.End)
+	return hover{
+		Contents: markupContent{Kind: "markdown", Value: text},
+		Range:    &r,
+	}, nil
+}
+
+func (s *server) definition(params json.RawMessage) (interface{}, error) {
+	var p textDocumentPositionParams
+	if err := unmarshalParams(params, &p); err != nil {
+		return nil, err
+	}
+
+	pf := s.ws.parse(uriToPath(p.TextDocument.URI))
+	if id := pf.identAt(p.Position); id != nil {
+		path, name := pf.target(*id)
+		if path == "" {
+			return nil, nil
+		}
+		target := s.ws.parse(path)
+		if decl := target.declaration(name); decl != nil {
+			return target.location(decl), nil
+		}
+		return nil, nil
+	}
+
+	// import declarations refer to the imported file
+	if pf.file != nil {
+		for _, imp := range pf.file.Imports {
+			if imp.Pos().Line == p.Position.Line+1 {
+				return location{URI: pathToURI(pf.importPath(imp))}, nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+func (s *server) references(params json.RawMessage) (interface{}, error) {
+	var p referenceParams
+	if err := unmarshalParams(params, &p); err != nil {
+		return nil, err
+	}
+
+	pf := s.ws.parse(uriToPath(p.TextDocument.URI))
+	id := pf.identAt(p.Position)
+	if id == nil {
+		return nil, nil
+	}
+	path, name := pf.target(*id)
+	if path == "" {
+		return nil, nil
+	}
+
+	locations := []location{}
+	for _, filePath := range s.ws.files() {
+		f := s.ws.parse(filePath)
+		for i := range f.idents {
+			ref := &f.idents[i]
+			if ref.Declaring && !p.Context.IncludeDeclaration {
+				continue
+			}
+			if refPath, refName := f.target(*ref); refPath == path && refName == name {
+				locations = append(locations, f.location(ref))
+			}
+		}
+	}
+
+	sort.Slice(locations, func(i, j int) bool {
+		l, r := locations[i], locations[j]
+		switch {
+		case l.URI != r.URI:
+			return l.URI < r.URI
+		case l.Range.Start.Line != r.Range.Start.Line:
+			return l.Range.Start.Line < r.Range.Start.Line
+		default:
+			return l.Range.Start.Character < r.Range.Start.Character
+		}
+	})
+	return locations, nil
+}
+
+func (s *server) completion(params json.RawMessage) (interface{}, error) {
+	var p textDocumentPositionParams
+	if err := unmarshalParams(params, &p); err != nil {
+		return nil, err
+	}
+
+	pf := s.ws.parse(uriToPath(p.TextDocument.URI))
+	if pf.file == nil || p.Position.Line >= len(pf.lines) {
+		return []completionItem{}, nil
+	}
+
+	line := pf.lines[p.Position.Line]
+	_, col := pf.schemaPos(p.Position)
+	prefix := string([]rune(line)[:col-1])
+
+	items := []completionItem{}
+	switch {
+	case inString(prefix):
+		for _, key := range tagKeys(pf.file) {
+			items = append(items, completionItem{Label: key, Kind: completionKindProperty})
+		}
+
+	case qualifiedPrefix.MatchString(prefix):
+		qualifier := qualifiedPrefix.FindStringSubmatch(prefix)[1]
+		if imp := pf.file.Imports[qualifier]; imp != nil {
+			imported := s.ws.parse(pf.importPath(imp))
+			items = append(items, declItems(imported)...)
+		}
+
+	default:
+		for _, name := range builtinTypes {
+			items = append(items, completionItem{Label: name, Kind: completionKindKeyword})
+		}
+		items = append(items, declItems(pf)...)
+		for name, imp := range pf.file.Imports {
+			items = append(items, completionItem{Label: name, Kind: completionKindModule, Detail: imp.Path})
+		}
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].Label < items[j].Label
+	})
+	return items, nil
+}
+
+func (s *server) formatting(params json.RawMessage) (interface{}, error) {
+	var p documentFormattingParams
+	if err := unmarshalParams(params, &p); err != nil {
+		return nil, err
+	}
+
+	pf := s.ws.parse(uriToPath(p.TextDocument.URI))
+	if pf.lines == nil {
+		return nil, nil
+	}
+	formatted, err := schema.Format(pf.path, []byte(pf.text))
+	if err != nil {
+		return nil, nil // syntax errors are reported as diagnostics
+	}
+
+	edits := []textEdit{}
+	if string(formatted) != pf.text {
+		edits = append(edits, textEdit{
+			Range:   textRange{End: pf.endPosition()},
+			NewText: string(formatted),
+		})
+	}
+	return edits, nil
+}
+
+// inString reports whether the end of the given line prefix is inside of a
+// string literal.
+func inString(prefix string) bool {
+	inside := false
+	for i := 0; i < len(prefix); i++ {
+		switch prefix[i] {
+		case '\\':
+			i++ // skip escaped character
+		case '"':
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// tagKeys returns the known tag keys and the tag keys used in the file.
+func tagKeys(f *schema.File) []string {
+	keys := map[string]struct{}{"deprecated": {}}
+	addTags := func(tags schema.Tags) {
+		for key := range tags {
+			keys[key] = struct{}{}
+		}
+	}
+
+	for _, decl := range f.Decls {
+		switch decl := decl.(type) {
+		case *schema.Enum:
+			for _, e := range decl.Enumerators {
+				addTags(e.Tags)
+			}
+		case *schema.Struct:
+			for _, field := range decl.Fields {
+				addTags(field.Tags)
+			}
+		case *schema.Union:
+			for _, branch := range decl.Branches {
+				addTags(branch.Tags)
+			}
+		case *schema.Service:
+			for _, method := range decl.Methods {
+				addTags(method.Tags)
+			}
+		}
+	}
+
+	res := make([]string, 0, len(keys))
+	for key := range keys {
+		res = append(res, key)
+	}
+	sort.Strings(res)
+	return res
+}
+
+func declItems(pf *parsedFile) []completionItem {
+	var items []completionItem
+	for _, id := range pf.idents {
+		if !id.Declaring {
+			continue
+		}
+
+		item := completionItem{Label: id.Name}
+		switch id.Type.Decl.(type) {
+		case *schema.Enum:
+			item.Kind, item.Detail = completionKindEnum, "enum"
+		case *schema.Struct:
+			item.Kind, item.Detail = completionKindStruct, "struct"
+		case *schema.Union:
+			item.Kind, item.Detail = completionKindClass, "union"
+		case *schema.Service:
+			item.Kind, item.Detail = completionKindInterface, "service"
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+func findDecl(f *schema.File, name string) schema.Decl {
+	if f == nil {
+		return nil
+	}
+	for _, decl := range f.Decls {
+		if t := schema.DeclType(decl); t != nil && t.Name() == name {
+			return decl
+		}
+	}
+	return nil
+}
+
+func declDoc(decl schema.Decl) []string {
+	switch decl := decl.(type) {
+	case *schema.Enum:
+		return decl.Doc
+	case *schema.Struct:
+		return decl.Doc
+	case *schema.Union:
+		return decl.Doc
+	case *schema.Service:
+		return decl.Doc
+	default:
+		return nil
+	}
+}
+
+// declSignature returns the declaration without tags and comments.
+func declSignature(decl schema.Decl) string {
+	var lines []string
+	switch decl := decl.(type) {
+	case *schema.Enum:
+		lines = append(lines, "enum "+decl.Name+" {")
+		for _, e := range decl.Enumerators {
+			lines = append(lines, "\t"+e.Name)
+		}
+
+	case *schema.Struct:
+		width := 0
+		for _, field := range decl.Fields {
+			if len(field.Name) > width {
+				width = len(field.Name)
+			}
+		}
+		lines = append(lines, "struct "+decl.Name+" {")
+		for _, field := range decl.Fields {
+			lines = append(lines, "\t"+gen.RPad(field.Name, width)+" "+typeName(field.Type))
+		}
+
+	case *schema.Union:
+		lines = append(lines, "union "+decl.Name+" {")
+		for _, branch := range decl.Branches {
+			lines = append(lines, "\t"+typeName(branch.Type))
+		}
+
+	case *schema.Service:
+		lines = append(lines, "service "+decl.Name+" {")
+		for _, method := range decl.Methods {
+			args := make([]string, 0, len(method.Args))
+			for _, arg := range method.Args {
+				args = append(args, typeName(arg))
+			}
+			m := "\t" + method.Name + "(" + strings.Join(args, ", ") + ")"
+			if method.Return != nil {
+				m += " " + typeName(method.Return)
+			}
+			lines = append(lines, m)
+		}
+	}
+	return strings.Join(append(lines, "}"), "\n")
+}
+
+func typeName(t schema.Type) string {
+	if t == nil {
+		return "?"
+	}
+	return t.Name()
+}
@@ -0,0 +1,91 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+)
+
+// JSON-RPC error codes.
+const (
+	codeParseError     = -32700
+	codeInvalidRequest = -32600
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+	codeRequestFailed  = -32803
+)
+
+// message is a JSON-RPC request, response, or notification. Notifications do
+// not have an id.
+type message struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id,omitempty"`
+	Method  string           `json:"method,omitempty"`
+	Params  json.RawMessage  `json:"params,omitempty"`
+	Result  interface{}      `json:"result,omitempty"`
+	Error   *responseError   `json:"error,omitempty"`
+}
+
+type responseError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *responseError) Error() string {
+	return e.Message
+}
+
+// conn reads and writes JSON-RPC messages with the base protocol of the
+// language server protocol, i.e. each message is preceded by a header with
+// its content length.
+type conn struct {
+	r *textproto.Reader
+	w io.Writer
+}
+
+func newConn(r io.Reader, w io.Writer) *conn {
+	return &conn{
+		r: textproto.NewReader(bufio.NewReader(r)),
+		w: w,
+	}
+}
+
+func (c *conn) read() (*message, error) {
+	header, err := c.r.ReadMIMEHeader()
+	if err != nil {
+		return nil, err
+	}
+
+	length, err := strconv.Atoi(header.Get("Content-Length"))
+	if err != nil || length < 0 {
+		return nil, fmt.Errorf("invalid content length %q", header.Get("Content-Length"))
+	}
+
+	content := make([]byte, length)
+	if _, err := io.ReadFull(c.r.R, content); err != nil {
+		return nil, err
+	}
+
+	var msg message
+	if err := json.Unmarshal(content, &msg); err != nil {
+		return &message{Error: &responseError{Code: codeParseError, Message: err.Error()}}, nil
+	}
+	return &msg, nil
+}
+
+func (c *conn) write(msg *message) error {
+	msg.JSONRPC = "2.0"
+	content, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	if _, err := fmt.Fprintf(c.w, "Content-Length: %d\r\n\r\n", len(content)); err != nil {
+		return err
+	}
+	_, err = c.w.Write(content)
+	return err
+}
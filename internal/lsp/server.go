@@ -0,0 +1,236 @@
+// Package lsp implements a language server for mprot schema files, which
+// communicates with the editor using the language server protocol.
+package lsp
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"sort"
+)
+
+// Options holds the options of the language server.
+type Options struct {
+	IncludeDirs []string // directories searched for imported schema files
+}
+
+type server struct {
+	conn     *conn
+	opts     Options
+	ws       *workspace
+	shutdown bool
+}
+
+type handler func(s *server, params json.RawMessage) (interface{}, error)
+
+var handlers = map[string]handler{
+	"initialize":              (*server).initialize,
+	"shutdown":                (*server).shutdownRequest,
+	"textDocument/hover":      (*server).hover,
+	"textDocument/definition": (*server).definition,
+	"textDocument/references": (*server).references,
+	"textDocument/completion": (*server).completion,
+	"textDocument/formatting": (*server).formatting,
+}
+
+var notificationHandlers = map[string]func(s *server, params json.RawMessage) error{
+	"textDocument/didOpen":   (*server).didOpen,
+	"textDocument/didChange": (*server).didChange,
+	"textDocument/didSave":   (*server).didSave,
+	"textDocument/didClose":  (*server).didClose,
+}
+
+// errExitWithoutShutdown is returned by Serve, if the exit notification is
+// received before the shutdown request.
+var errExitWithoutShutdown = errors.New("exit without shutdown")
+
+// Serve runs the language server, which reads the messages of the client from
+// r and writes its messages to w. It returns after the exit notification or
+// if the connection is closed.
+func Serve(r io.Reader, w io.Writer, opts Options) error {
+	s := &server{
+		conn: newConn(r, w),
+		opts: opts,
+	}
+	s.ws = newWorkspace(".", opts.IncludeDirs)
+
+	for {
+		msg, err := s.conn.read()
+		switch {
+		case err == io.EOF:
+			return nil
+		case err != nil:
+			return err
+		case msg.Error != nil: // parse error
+			if err := s.conn.write(&message{Error: msg.Error}); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if msg.Method == "exit" {
+			if !s.shutdown {
+				return errExitWithoutShutdown
+			}
+			return nil
+		}
+		if err := s.handle(msg); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *server) handle(msg *message) error {
+	if msg.ID == nil {
+		if h, has := notificationHandlers[msg.Method]; has {
+			return h(s, msg.Params)
+		}
+		return nil // ignore unknown notifications
+	}
+
+	resp := &message{ID: msg.ID}
+	h, has := handlers[msg.Method]
+	switch {
+	case !has:
+		resp.Error = &responseError{Code: codeMethodNotFound, Message: "method not found: " + msg.Method}
+	case s.shutdown:
+		resp.Error = &responseError{Code: codeInvalidRequest, Message: "server is shut down"}
+	default:
+		result, err := h(s, msg.Params)
+		var respErr *responseError
+		switch {
+		case errors.As(err, &respErr):
+			resp.Error = respErr
+		case err != nil:
+			resp.Error = &responseError{Code: codeRequestFailed, Message: err.Error()}
+		case result == nil:
+			resp.Result = json.RawMessage("null")
+		default:
+			resp.Result = result
+		}
+	}
+	return s.conn.write(resp)
+}
+
+func (s *server) notify(method string, params interface{}) error {
+	data, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	return s.conn.write(&message{Method: method, Params: data})
+}
+
+func unmarshalParams(params json.RawMessage, v interface{}) error {
+	if err := json.Unmarshal(params, v); err != nil {
+		return &responseError{Code: codeInvalidParams, Message: err.Error()}
+	}
+	return nil
+}
+
+func (s *server) initialize(params json.RawMessage) (interface{}, error) {
+	var p initializeParams
+	if err := unmarshalParams(params, &p); err != nil {
+		return nil, err
+	}
+
+	root := p.RootPath
+	if p.RootURI != "" {
+		root = uriToPath(p.RootURI)
+	}
+	if root == "" {
+		root, _ = os.Getwd()
+	}
+	s.ws = newWorkspace(root, s.opts.IncludeDirs)
+
+	return map[string]interface{}{
+		"capabilities": map[string]interface{}{
+			"textDocumentSync":           1, // full
+			"hoverProvider":              true,
+			"definitionProvider":         true,
+			"referencesProvider":         true,
+			"documentFormattingProvider": true,
+			"completionProvider": map[string]interface{}{
+				"triggerCharacters": []string{".", `"`, " "},
+			},
+		},
+		"serverInfo": map[string]string{
+			"name": "mprotc",
+		},
+	}, nil
+}
+
+func (s *server) shutdownRequest(params json.RawMessage) (interface{}, error) {
+	s.shutdown = true
+	return nil, nil
+}
+
+func (s *server) didOpen(params json.RawMessage) error {
+	var p didOpenParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil // ignore invalid notifications
+	}
+	s.ws.docs[uriToPath(p.TextDocument.URI)] = p.TextDocument.Text
+	return s.publishDiagnostics()
+}
+
+func (s *server) didChange(params json.RawMessage) error {
+	var p didChangeParams
+	if err := json.Unmarshal(params, &p); err != nil || len(p.ContentChanges) == 0 {
+		return nil
+	}
+	s.ws.docs[uriToPath(p.TextDocument.URI)] = p.ContentChanges[len(p.ContentChanges)-1].Text
+	return s.publishDiagnostics()
+}
+
+func (s *server) didSave(params json.RawMessage) error {
+	return s.publishDiagnostics()
+}
+
+func (s *server) didClose(params json.RawMessage) error {
+	var p didCloseParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil
+	}
+	delete(s.ws.docs, uriToPath(p.TextDocument.URI))
+	return s.notify("textDocument/publishDiagnostics", publishDiagnosticsParams{
+		URI:         p.TextDocument.URI,
+		Diagnostics: []diagnostic{},
+	})
+}
+
+// publishDiagnostics publishes the diagnostics of all open documents. The
+// diagnostics of a document may change, if one of its imports changes.
+func (s *server) publishDiagnostics() error {
+	paths := make([]string, 0, len(s.ws.docs))
+	for path := range s.ws.docs {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		pf := s.ws.parse(path)
+		diags := []diagnostic{}
+		for _, e := range pf.errs {
+			if e.Pos.File != "" && e.Pos.File != path {
+				continue
+			}
+			diags = append(diags, diagnostic{
+				Range:    pf.textRange(e.Pos, e.End),
+				Severity: severityError,
+				Code:     string(e.Code),
+				Source:   "mprotc",
+				Message:  e.Text,
+			})
+		}
+
+		err := s.notify("textDocument/publishDiagnostics", publishDiagnosticsParams{
+			URI:         pathToURI(path),
+			Diagnostics: diags,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
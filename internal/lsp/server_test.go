@@ -0,0 +1,259 @@
+package lsp
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/mprot/mprotc/schema"
+)
+
+const testTypes = `package types
+
+// User is a user.
+struct User {
+	Name string "1"
+	Age  int    "2"
+}
+`
+
+const testAPI = `package api
+
+import "types.mprot"
+
+union Event {
+	types.User "1"
+	Created    "2"
+}
+
+struct Created {
+	By types.User "1 deprecated"
+}
+`
+
+type testClient struct {
+	t      *testing.T
+	conn   *conn
+	nextID int
+	done   chan error
+}
+
+func newTestClient(t *testing.T) *testClient {
+	clientR, serverW := io.Pipe()
+	serverR, clientW := io.Pipe()
+
+	c := &testClient{
+		t:    t,
+		conn: newConn(clientR, clientW),
+		done: make(chan error, 1),
+	}
+	go func() {
+		c.done <- Serve(serverR, serverW, Options{})
+		serverW.Close()
+	}()
+	return c
+}
+
+// call sends a request and returns the result. Notifications sent by the
+// server in the meantime are skipped.
+func (c *testClient) call(method string, params interface{}, result interface{}) *responseError {
+	c.nextID++
+	id := json.RawMessage(strconv.Itoa(c.nextID))
+	c.send(&message{ID: &id, Method: method}, params)
+
+	for {
+		msg := c.read()
+		if msg.ID == nil {
+			continue
+		}
+		if msg.Error != nil {
+			return msg.Error
+		}
+		data, _ := json.Marshal(msg.Result)
+		if err := json.Unmarshal(data, result); err != nil {
+			c.t.Fatalf("unexpected result of %s: %s", method, data)
+		}
+		return nil
+	}
+}
+
+func (c *testClient) notify(method string, params interface{}) {
+	c.send(&message{Method: method}, params)
+}
+
+func (c *testClient) send(msg *message, params interface{}) {
+	data, err := json.Marshal(params)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	msg.Params = data
+	if err := c.conn.write(msg); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+func (c *testClient) read() *message {
+	msg, err := c.conn.read()
+	if err != nil {
+		c.t.Fatalf("unexpected read error: %v", err)
+	}
+	return msg
+}
+
+func (c *testClient) diagnostics() publishDiagnosticsParams {
+	for {
+		msg := c.read()
+		if msg.Method == "textDocument/publishDiagnostics" {
+			var p publishDiagnosticsParams
+			if err := json.Unmarshal(msg.Params, &p); err != nil {
+				c.t.Fatal(err)
+			}
+			return p
+		}
+	}
+}
+
+func TestServer(t *testing.T) {
+	dir := t.TempDir()
+	typesPath := filepath.Join(dir, "types.mprot")
+	apiPath := filepath.Join(dir, "api.mprot")
+	if err := os.WriteFile(typesPath, []byte(testTypes), 0666); err != nil {
+		t.Fatal(err)
+	}
+	typesURI, apiURI := pathToURI(typesPath), pathToURI(apiPath)
+
+	c := newTestClient(t)
+
+	var initResult map[string]interface{}
+	if err := c.call("initialize", initializeParams{RootURI: pathToURI(dir)}, &initResult); err != nil {
+		t.Fatalf("unexpected initialize error: %v", err)
+	}
+	c.notify("initialized", struct{}{})
+
+	// diagnostics
+	c.notify("textDocument/didOpen", didOpenParams{TextDocument: textDocumentItem{
+		URI:  apiURI,
+		Text: strings.Replace(testAPI, `Created    "2"`, `Missing    "2"`, 1),
+	}})
+	diags := c.diagnostics()
+	if diags.URI != apiURI || len(diags.Diagnostics) != 1 || diags.Diagnostics[0].Code != "undefined-type" {
+		t.Fatalf("unexpected diagnostics: %+v", diags)
+	}
+	if r := diags.Diagnostics[0].Range; r.Start != (position{Line: 6, Character: 1}) || r.End != (position{Line: 6, Character: 8}) {
+		t.Errorf("unexpected diagnostic range: %+v", r)
+	}
+
+	c.notify("textDocument/didChange", map[string]interface{}{
+		"textDocument":   map[string]interface{}{"uri": apiURI, "version": 2},
+		"contentChanges": []map[string]string{{"text": testAPI}},
+	})
+	if diags := c.diagnostics(); len(diags.Diagnostics) != 0 {
+		t.Fatalf("unexpected diagnostics: %+v", diags)
+	}
+
+	at := func(line, char int) textDocumentPositionParams {
+		return textDocumentPositionParams{
+			TextDocument: textDocumentIdentifier{URI: apiURI},
+			Position:     position{Line: line, Character: char},
+		}
+	}
+
+	// hover
+	var h hover
+	if err := c.call("textDocument/hover", at(5, 8), &h); err != nil {
+		t.Fatalf("unexpected hover error: %v", err)
+	}
+	if !strings.Contains(h.Contents.Value, "struct User {\n\tName string\n\tAge  int\n}") || !strings.Contains(h.Contents.Value, "User is a user.") {
+		t.Errorf("unexpected hover: %q", h.Contents.Value)
+	}
+
+	// definition
+	var loc location
+	if err := c.call("textDocument/definition", at(10, 7), &loc); err != nil {
+		t.Fatalf("unexpected definition error: %v", err)
+	}
+	if loc.URI != typesURI || loc.Range.Start != (position{Line: 3, Character: 7}) {
+		t.Errorf("unexpected definition: %+v", loc)
+	}
+	if err := c.call("textDocument/definition", at(6, 2), &loc); err != nil {
+		t.Fatalf("unexpected definition error: %v", err)
+	}
+	if loc.URI != apiURI || loc.Range.Start != (position{Line: 9, Character: 7}) {
+		t.Errorf("unexpected local definition: %+v", loc)
+	}
+
+	// references
+	var refs []location
+	refParams := referenceParams{textDocumentPositionParams: at(5, 9)}
+	refParams.Context.IncludeDeclaration = true
+	if err := c.call("textDocument/references", refParams, &refs); err != nil {
+		t.Fatalf("unexpected references error: %v", err)
+	}
+	if len(refs) != 3 || refs[0].URI != apiURI || refs[2].URI != typesURI {
+		t.Errorf("unexpected references: %+v", refs)
+	}
+
+	// completion
+	labels := func(pos textDocumentPositionParams) []string {
+		var items []completionItem
+		if err := c.call("textDocument/completion", pos, &items); err != nil {
+			t.Fatalf("unexpected completion error: %v", err)
+		}
+		var res []string
+		for _, item := range items {
+			res = append(res, item.Label)
+		}
+		return strings.Fields(strings.Join(res, " "))
+	}
+	if res := labels(at(10, 10)); strings.Join(res, ",") != "User" {
+		t.Errorf("unexpected qualified completion: %v", res)
+	}
+	if res := strings.Join(labels(at(10, 4)), ","); !strings.Contains(res, "Created,Event") || !strings.Contains(res, "int64") || !strings.Contains(res, "types") {
+		t.Errorf("unexpected completion: %v", res)
+	}
+	if res := labels(at(10, 18)); strings.Join(res, ",") != "deprecated" {
+		t.Errorf("unexpected tag completion: %v", res)
+	}
+
+	// formatting
+	c.notify("textDocument/didChange", map[string]interface{}{
+		"textDocument":   map[string]interface{}{"uri": apiURI, "version": 3},
+		"contentChanges": []map[string]string{{"text": strings.Replace(testAPI, "Created    \"2\"", "Created \"2\"", 1)}},
+	})
+	c.diagnostics()
+
+	var edits []textEdit
+	if err := c.call("textDocument/formatting", documentFormattingParams{TextDocument: textDocumentIdentifier{URI: apiURI}}, &edits); err != nil {
+		t.Fatalf("unexpected formatting error: %v", err)
+	}
+	if len(edits) != 1 || edits[0].NewText != testAPI || edits[0].Range.End != (position{Line: 12, Character: 0}) {
+		t.Errorf("unexpected formatting edits: %+v", edits)
+	}
+
+	// shutdown
+	var null interface{}
+	if err := c.call("shutdown", nil, &null); err != nil {
+		t.Fatalf("unexpected shutdown error: %v", err)
+	}
+	c.notify("exit", nil)
+	if err := <-c.done; err != nil {
+		t.Errorf("unexpected serve error: %v", err)
+	}
+}
+
+func TestPositions(t *testing.T) {
+	pf := &parsedFile{lines: []string{"aä\U0001F600b"}}
+	for col, char := range map[int]int{1: 0, 2: 1, 3: 2, 4: 4, 5: 5} {
+		pos := pf.position(schema.Pos{Line: 1, Column: col})
+		if pos.Character != char {
+			t.Errorf("unexpected character for column %d: %d (expected %d)", col, pos.Character, char)
+		}
+		if _, c := pf.schemaPos(pos); c != col {
+			t.Errorf("unexpected column for character %d: %d (expected %d)", char, c, col)
+		}
+	}
+}
@@ -0,0 +1,232 @@
+package lsp
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/mprot/mprotc/schema"
+)
+
+// parsedFile holds a parsed schema file and its identifiers.
+type parsedFile struct {
+	path   string
+	text   string
+	lines  []string
+	file   *schema.File // nil if the file cannot be read
+	idents []schema.Ident
+	errs   schema.ErrorList
+}
+
+// workspace holds the open documents and the parsed schema files. Open
+// documents take precedence over the files on disk.
+type workspace struct {
+	root   string
+	config schema.Config
+	docs   map[string]string      // path => text of open document
+	parsed map[string]*parsedFile // path => parsed file
+}
+
+func newWorkspace(root string, includeDirs []string) *workspace {
+	absIncludeDirs := make([]string, 0, len(includeDirs))
+	for _, dir := range includeDirs {
+		if abs, err := filepath.Abs(dir); err == nil {
+			dir = abs
+		}
+		absIncludeDirs = append(absIncludeDirs, dir)
+	}
+
+	return &workspace{
+		root:   root,
+		config: schema.Config{IncludeDirs: absIncludeDirs, Jobs: 1},
+		docs:   make(map[string]string),
+		parsed: make(map[string]*parsedFile),
+	}
+}
+
+// parse returns the parsed schema file at the given path. The result is
+// cached as long as the text of the file does not change.
+func (ws *workspace) parse(path string) *parsedFile {
+	text, open := ws.docs[path]
+	if !open {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			delete(ws.parsed, path)
+			return &parsedFile{path: path}
+		}
+		text = string(data)
+	}
+
+	if pf := ws.parsed[path]; pf != nil && pf.text == text {
+		return pf
+	}
+
+	pf := &parsedFile{
+		path:  path,
+		text:  text,
+		lines: strings.Split(text, "\n"),
+	}
+	f, idents, err := ws.config.ParseFile(path, []byte(text))
+	if errs, ok := err.(schema.ErrorList); ok {
+		pf.errs = errs
+	} else if err != nil {
+		pf.errs = schema.ErrorList{{Pos: schema.Pos{File: path, Line: 1, Column: 1}, Text: err.Error()}}
+	}
+	pf.file, pf.idents = f, idents
+	ws.parsed[path] = pf
+	return pf
+}
+
+// files returns the paths of all schema files of the workspace, including
+// the open documents.
+func (ws *workspace) files() []string {
+	seen := make(map[string]struct{})
+	var paths []string
+	add := func(path string) {
+		if _, has := seen[path]; !has {
+			seen[path] = struct{}{}
+			paths = append(paths, path)
+		}
+	}
+
+	if ws.root != "" {
+		filenames, _ := schema.Glob(os.DirFS(ws.root), []string{"**/*.mprot"})
+		for _, filename := range filenames {
+			add(filepath.Join(ws.root, filepath.FromSlash(filename)))
+		}
+	}
+	for path := range ws.docs {
+		add(path)
+	}
+	return paths
+}
+
+// target returns the path of the file and the name of the type, which the
+// identifier refers to. If the identifier cannot be resolved, an empty path
+// is returned.
+func (pf *parsedFile) target(id schema.Ident) (string, string) {
+	if !id.Type.Imported() {
+		if id.Type.Decl == nil {
+			return "", ""
+		}
+		return pf.path, id.Name
+	}
+
+	imp, ok := id.Type.Decl.(*schema.Import)
+	if !ok || imp == nil {
+		return "", ""
+	}
+	return pf.importPath(imp), id.Type.LocalName()
+}
+
+func (pf *parsedFile) importPath(imp *schema.Import) string {
+	if imp.IncludeDir != "" {
+		return filepath.Join(imp.IncludeDir, filepath.FromSlash(imp.Path))
+	}
+	return filepath.Join(filepath.Dir(pf.path), filepath.FromSlash(imp.Path))
+}
+
+// identAt returns the identifier at the given position or nil.
+func (pf *parsedFile) identAt(pos position) *schema.Ident {
+	line, col := pf.schemaPos(pos)
+	for i := range pf.idents {
+		id := &pf.idents[i]
+		if id.Pos.Line == line && id.Pos.Column <= col && col <= id.End.Column {
+			return id
+		}
+	}
+	return nil
+}
+
+// declaration returns the declaring identifier of the type with the given
+// name or nil.
+func (pf *parsedFile) declaration(name string) *schema.Ident {
+	for i := range pf.idents {
+		if id := &pf.idents[i]; id.Declaring && id.Name == name {
+			return id
+		}
+	}
+	return nil
+}
+
+// schemaPos converts a protocol position into a schema line and column.
+func (pf *parsedFile) schemaPos(pos position) (int, int) {
+	col := 1
+	if pos.Line < len(pf.lines) {
+		units := 0
+		for _, r := range pf.lines[pos.Line] {
+			if units >= pos.Character {
+				break
+			}
+			units += runeLen16(r)
+			col++
+		}
+	}
+	return pos.Line + 1, col
+}
+
+// position converts a schema position into a protocol position.
+func (pf *parsedFile) position(pos schema.Pos) position {
+	line := pos.Line - 1
+	if line < 0 {
+		return position{}
+	}
+
+	char := 0
+	if line < len(pf.lines) {
+		s := pf.lines[line]
+		for i := 1; i < pos.Column && s != ""; i++ {
+			r, n := utf8.DecodeRuneInString(s)
+			char += runeLen16(r)
+			s = s[n:]
+		}
+	}
+	return position{Line: line, Character: char}
+}
+
+// runeLen16 returns the number of UTF-16 code units of the rune.
+func runeLen16(r rune) int {
+	if r >= 0x10000 {
+		return 2
+	}
+	return 1
+}
+
+func (pf *parsedFile) textRange(pos, end schema.Pos) textRange {
+	return textRange{Start: pf.position(pos), End: pf.position(end)}
+}
+
+func (pf *parsedFile) location(id *schema.Ident) location {
+	return location{URI: pathToURI(pf.path), Range: pf.textRange(id.Pos, id.End)}
+}
+
+// endPosition returns the position after the last character of the text.
+func (pf *parsedFile) endPosition() position {
+	last := len(pf.lines) - 1
+	return position{Line: last, Character: len(utf16.Encode([]rune(pf.lines[last])))}
+}
+
+func uriToPath(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return uri
+	}
+
+	path := u.Path
+	if runtime.GOOS == "windows" && len(path) > 2 && path[0] == '/' && path[2] == ':' {
+		path = path[1:] // /C:/dir => C:/dir
+	}
+	return filepath.Clean(filepath.FromSlash(path))
+}
+
+func pathToURI(path string) string {
+	path = filepath.ToSlash(path)
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return (&url.URL{Scheme: "file", Path: path}).String()
+}
@@ -0,0 +1,258 @@
+// Package msgpack implements a minimal MessagePack codec for generic values
+// as produced by encoding/json, i.e. nil, bool, numbers, strings, slices of
+// values, and maps with string keys.
+package msgpack
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+)
+
+var errShortBuffer = errors.New("msgpack: unexpected end of data")
+
+// Marshal encodes the given value as MessagePack. The supported types are
+// nil, bool, int64, float64, json.Number, string, []interface{}, and
+// map[string]interface{}. Map keys are encoded in sorted order.
+func Marshal(v interface{}) ([]byte, error) {
+	return appendValue(nil, v)
+}
+
+// Unmarshal decodes a MessagePack value. Integers are returned as int64,
+// floating-point numbers as float64, arrays as []interface{}, and maps as
+// map[string]interface{}.
+func Unmarshal(data []byte) (interface{}, error) {
+	d := decoder{data: data}
+	v, err := d.value()
+	if err == nil && d.off != len(d.data) {
+		err = fmt.Errorf("msgpack: %d trailing bytes", len(d.data)-d.off)
+	}
+	return v, err
+}
+
+func appendValue(b []byte, v interface{}) ([]byte, error) {
+	switch v := v.(type) {
+	case nil:
+		return append(b, 0xc0), nil
+	case bool:
+		if v {
+			return append(b, 0xc3), nil
+		}
+		return append(b, 0xc2), nil
+	case int64:
+		return appendInt(b, v), nil
+	case float64:
+		b = append(b, 0xcb)
+		return binary.BigEndian.AppendUint64(b, math.Float64bits(v)), nil
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return appendInt(b, i), nil
+		}
+		f, err := v.Float64()
+		if err != nil {
+			return nil, err
+		}
+		return appendValue(b, f)
+	case string:
+		b = appendHeader(b, len(v), 0xa0, 31, 0xd9, 0xda, 0xdb)
+		return append(b, v...), nil
+	case []interface{}:
+		b = appendHeader(b, len(v), 0x90, 15, 0, 0xdc, 0xdd)
+		for _, elem := range v {
+			var err error
+			if b, err = appendValue(b, elem); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		b = appendHeader(b, len(v), 0x80, 15, 0, 0xde, 0xdf)
+		for _, key := range keys {
+			var err error
+			if b, err = appendValue(b, key); err != nil {
+				return nil, err
+			}
+			if b, err = appendValue(b, v[key]); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	default:
+		return nil, fmt.Errorf("msgpack: unsupported type %T", v)
+	}
+}
+
+func appendInt(b []byte, i int64) []byte {
+	switch {
+	case i >= 0 && i <= 127:
+		return append(b, byte(i))
+	case i < 0 && i >= -32:
+		return append(b, byte(i))
+	default:
+		b = append(b, 0xd3)
+		return binary.BigEndian.AppendUint64(b, uint64(i))
+	}
+}
+
+// appendHeader appends the header of a string, an array, or a map. If a code
+// is zero, the respective size class is not supported by the type.
+func appendHeader(b []byte, n int, fix byte, fixMax int, code8, code16, code32 byte) []byte {
+	switch {
+	case n <= fixMax:
+		return append(b, fix|byte(n))
+	case code8 != 0 && n <= math.MaxUint8:
+		return append(b, code8, byte(n))
+	case n <= math.MaxUint16:
+		b = append(b, code16)
+		return binary.BigEndian.AppendUint16(b, uint16(n))
+	default:
+		b = append(b, code32)
+		return binary.BigEndian.AppendUint32(b, uint32(n))
+	}
+}
+
+type decoder struct {
+	data []byte
+	off  int
+}
+
+func (d *decoder) value() (interface{}, error) {
+	c, err := d.byte()
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case c <= 0x7f:
+		return int64(c), nil
+	case c >= 0xe0:
+		return int64(int8(c)), nil
+	case c&0xf0 == 0x80:
+		return d.mapping(int(c & 0x0f))
+	case c&0xf0 == 0x90:
+		return d.array(int(c & 0x0f))
+	case c&0xe0 == 0xa0:
+		return d.str(int(c & 0x1f))
+	}
+
+	switch c {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xca:
+		u, err := d.uint(4)
+		return float64(math.Float32frombits(uint32(u))), err
+	case 0xcb:
+		u, err := d.uint(8)
+		return math.Float64frombits(u), err
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		u, err := d.uint(1 << (c - 0xcc))
+		if u > math.MaxInt64 {
+			return nil, fmt.Errorf("msgpack: integer overflow")
+		}
+		return int64(u), err
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		size := 1 << (c - 0xd0)
+		u, err := d.uint(size)
+		shift := 64 - 8*size
+		return int64(u<<shift) >> shift, err
+	case 0xd9, 0xda, 0xdb:
+		n, err := d.uint(1 << (c - 0xd9))
+		if err != nil {
+			return nil, err
+		}
+		return d.str(int(n))
+	case 0xdc, 0xdd:
+		n, err := d.uint(2 << (c - 0xdc))
+		if err != nil {
+			return nil, err
+		}
+		return d.array(int(n))
+	case 0xde, 0xdf:
+		n, err := d.uint(2 << (c - 0xde))
+		if err != nil {
+			return nil, err
+		}
+		return d.mapping(int(n))
+	default:
+		return nil, fmt.Errorf("msgpack: unsupported type code 0x%02x", c)
+	}
+}
+
+func (d *decoder) byte() (byte, error) {
+	if d.off >= len(d.data) {
+		return 0, errShortBuffer
+	}
+	c := d.data[d.off]
+	d.off++
+	return c, nil
+}
+
+func (d *decoder) uint(size int) (uint64, error) {
+	if len(d.data)-d.off < size {
+		return 0, errShortBuffer
+	}
+	var u uint64
+	for _, c := range d.data[d.off : d.off+size] {
+		u = u<<8 | uint64(c)
+	}
+	d.off += size
+	return u, nil
+}
+
+func (d *decoder) str(n int) (string, error) {
+	if n < 0 || len(d.data)-d.off < n {
+		return "", errShortBuffer
+	}
+	s := string(d.data[d.off : d.off+n])
+	d.off += n
+	return s, nil
+}
+
+func (d *decoder) array(n int) ([]interface{}, error) {
+	if n < 0 || n > len(d.data)-d.off {
+		return nil, errShortBuffer
+	}
+	res := make([]interface{}, 0, n)
+	for i := 0; i < n; i++ {
+		v, err := d.value()
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, v)
+	}
+	return res, nil
+}
+
+func (d *decoder) mapping(n int) (map[string]interface{}, error) {
+	if n < 0 || n > len(d.data)-d.off {
+		return nil, errShortBuffer
+	}
+	res := make(map[string]interface{}, n)
+	for i := 0; i < n; i++ {
+		k, err := d.value()
+		if err != nil {
+			return nil, err
+		}
+		key, ok := k.(string)
+		if !ok {
+			return nil, fmt.Errorf("msgpack: unsupported map key type %T", k)
+		}
+		if res[key], err = d.value(); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,65 @@
+package msgpack
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRoundtrip(t *testing.T) {
+	long := strings.Repeat("x", 300)
+	values := []interface{}{
+		nil,
+		true,
+		false,
+		int64(0),
+		int64(127),
+		int64(-32),
+		int64(-33),
+		int64(1 << 40),
+		int64(-1 << 62),
+		3.25,
+		"",
+		"foo",
+		long,
+		[]interface{}{int64(1), "two", nil},
+		map[string]interface{}{"a": int64(1), "b": []interface{}{true}, long: map[string]interface{}{}},
+	}
+
+	for _, v := range values {
+		data, err := Marshal(v)
+		if err != nil {
+			t.Fatalf("unexpected marshal error for %v: %v", v, err)
+		}
+		res, err := Unmarshal(data)
+		if err != nil {
+			t.Fatalf("unexpected unmarshal error for %v: %v", v, err)
+		}
+		if !reflect.DeepEqual(res, v) {
+			t.Errorf("unexpected value: %#v (expected %#v)", res, v)
+		}
+	}
+}
+
+func TestMarshalNumber(t *testing.T) {
+	data, err := Marshal([]interface{}{json.Number("12"), json.Number("1.5")})
+	if err != nil {
+		t.Fatalf("unexpected marshal error: %v", err)
+	}
+	res, err := Unmarshal(data)
+	if err != nil {
+		t.Fatalf("unexpected unmarshal error: %v", err)
+	}
+	if expected := []interface{}{int64(12), 1.5}; !reflect.DeepEqual(res, expected) {
+		t.Errorf("unexpected value: %#v", res)
+	}
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	for _, data := range [][]byte{{}, {0x92, 0x01}, {0xc1}, {0x81, 0x01, 0x01}, {0x01, 0x02}} {
+		if _, err := Unmarshal(data); err == nil {
+			t.Errorf("expected error for % x, got none", data)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+// Package parallel distributes indexed work over a bounded number of
+// goroutines.
+package parallel
+
+import (
+	"runtime"
+	"sync"
+)
+
+// Each calls fn for all indices from 0 to n-1. The calls are distributed over
+// at most jobs goroutines. If jobs is zero or negative, runtime.GOMAXPROCS(0)
+// is used. Each returns after all calls are finished.
+func Each(jobs int, n int, fn func(i int)) {
+	if jobs <= 0 {
+		jobs = runtime.GOMAXPROCS(0)
+	}
+	if jobs == 1 || n <= 1 {
+		for i := 0; i < n; i++ {
+			fn(i)
+		}
+		return
+	}
+
+	var wg sync.WaitGroup
+	indices := make(chan int)
+	for j := 0; j < jobs && j < n; j++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indices {
+				fn(i)
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		indices <- i
+	}
+	close(indices)
+	wg.Wait()
+}
+
+// FirstError calls fn for all indices like Each and returns the error with
+// the lowest index, so the result does not depend on the scheduling of the
+// goroutines.
+func FirstError(jobs int, n int, fn func(i int) error) error {
+	errs := make([]error, n)
+	Each(jobs, n, func(i int) {
+		errs[i] = fn(i)
+	})
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
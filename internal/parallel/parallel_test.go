@@ -0,0 +1,63 @@
+package parallel
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+)
+
+func TestEach(t *testing.T) {
+	for _, jobs := range []int{-1, 0, 1, 3, 100} {
+		const n = 50
+
+		var (
+			calls   [n]int32
+			running int32
+			maxRun  int32
+		)
+		Each(jobs, n, func(i int) {
+			r := atomic.AddInt32(&running, 1)
+			for {
+				m := atomic.LoadInt32(&maxRun)
+				if r <= m || atomic.CompareAndSwapInt32(&maxRun, m, r) {
+					break
+				}
+			}
+			atomic.AddInt32(&calls[i], 1)
+			atomic.AddInt32(&running, -1)
+		})
+
+		for i, c := range calls {
+			if c != 1 {
+				t.Errorf("unexpected number of calls for index %d with %d jobs: %d", i, jobs, c)
+			}
+		}
+		if jobs > 0 && int(maxRun) > jobs {
+			t.Errorf("unexpected number of concurrent calls with %d jobs: %d", jobs, maxRun)
+		}
+	}
+}
+
+func TestFirstError(t *testing.T) {
+	for _, jobs := range []int{1, 4, 16} {
+		for n := 0; n < 20; n++ {
+			err := FirstError(jobs, 16, func(i int) error {
+				if i%5 == 3 {
+					return fmt.Errorf("error %d", i)
+				}
+				return nil
+			})
+			if err == nil || err.Error() != "error 3" {
+				t.Fatalf("unexpected error with %d jobs: %v", jobs, err)
+			}
+		}
+	}
+
+	if err := FirstError(4, 16, func(i int) error { return nil }); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := FirstError(4, 0, func(i int) error { return errors.New("called") }); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,121 @@
+// Package plugin defines the protocol between mprotc and external code
+// generators. If mprotc is invoked with a language it does not know, it looks
+// for an executable named mprotc-gen-<language> in the directories of the
+// PATH environment variable.
+//
+// The plugin receives a JSON encoded Request on its standard input, which
+// holds the descriptor of the schema files to generate and the options given
+// on the command line (--opt name=value) or in the project file. It has to
+// write a JSON encoded Response to its standard output, which holds either
+// the generated files or the diagnostics of the failed generation. Anything
+// written to the standard error is passed through to the user. A non-zero exit
+// status is treated as failure.
+//
+// A plugin written in Go can use Main to handle the protocol:
+//
+//	func main() {
+//		plugin.Main(func(req *plugin.Request) (*plugin.Response, error) {
+//			s, err := req.Descriptor.Schema()
+//			if err != nil {
+//				return nil, err
+//			}
+//			...
+//		})
+//	}
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/mprot/mprotc/schema"
+)
+
+// Version is the version of the plugin protocol.
+const Version = 1
+
+// Request is sent from mprotc to the plugin.
+type Request struct {
+	Version    int                `json:"version"`
+	Language   string             `json:"language"`
+	Options    map[string]string  `json:"options"`
+	Descriptor *schema.Descriptor `json:"descriptor"` // schema files to generate
+}
+
+// Response is sent from the plugin back to mprotc. If the response holds any
+// diagnostics, the generation failed and none of the files is written.
+type Response struct {
+	Files       []File       `json:"files,omitempty"`
+	Diagnostics []Diagnostic `json:"diagnostics,omitempty"`
+}
+
+// File is a generated file.
+type File struct {
+	Name    string `json:"name"`             // slash-separated and relative to the output directory
+	Source  string `json:"source,omitempty"` // schema file the content was generated from
+	Content string `json:"content"`
+}
+
+// Diagnostic describes an error found by the plugin. The file is the name of
+// the schema file as given in the descriptor. The file and the position are
+// optional.
+type Diagnostic struct {
+	File    string `json:"file,omitempty"`
+	Line    int    `json:"line,omitempty"`
+	Column  int    `json:"column,omitempty"`
+	Message string `json:"message"`
+}
+
+// Errorf returns a diagnostic for the given position of a schema file.
+func Errorf(pos schema.Pos, format string, args ...interface{}) Diagnostic {
+	return Diagnostic{
+		File:    filepath.ToSlash(pos.File),
+		Line:    pos.Line,
+		Column:  pos.Column,
+		Message: fmt.Sprintf(format, args...),
+	}
+}
+
+// ReadRequest reads a request from r.
+func ReadRequest(r io.Reader) (*Request, error) {
+	var req Request
+	if err := json.NewDecoder(r).Decode(&req); err != nil {
+		return nil, fmt.Errorf("invalid plugin request: %v", err)
+	}
+	if req.Version != Version {
+		return nil, fmt.Errorf("unsupported plugin protocol version %d", req.Version)
+	}
+	if req.Descriptor == nil {
+		req.Descriptor = &schema.Descriptor{Version: schema.DescriptorVersion}
+	}
+	return &req, nil
+}
+
+// WriteResponse writes a response to w.
+func WriteResponse(w io.Writer, resp *Response) error {
+	return json.NewEncoder(w).Encode(resp)
+}
+
+// Main reads the request from the standard input, calls generate, and writes
+// the returned response to the standard output. If an error occurs, it is
+// printed to the standard error and the process exits with a non-zero status.
+func Main(generate func(req *Request) (*Response, error)) {
+	req, err := ReadRequest(os.Stdin)
+	if err == nil {
+		var resp *Response
+		if resp, err = generate(req); err == nil {
+			if resp == nil {
+				resp = &Response{}
+			}
+			err = WriteResponse(os.Stdout, resp)
+		}
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", filepath.Base(os.Args[0]), err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,71 @@
+package plugin
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mprot/mprotc/schema"
+)
+
+func TestReadRequest(t *testing.T) {
+	s, err := schema.ParseSources(map[string]string{
+		"a.mprot": "package a\n\nstruct S {\n\tX int \"1\"\n}\n",
+	})
+	if err != nil {
+		t.Fatalf("unexpected parse error: %v", err)
+	}
+
+	data, err := json.Marshal(Request{
+		Version:    Version,
+		Language:   "test",
+		Options:    map[string]string{"opt": "val"},
+		Descriptor: schema.NewDescriptor(s),
+	})
+	if err != nil {
+		t.Fatalf("unexpected marshal error: %v", err)
+	}
+
+	req, err := ReadRequest(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if req.Language != "test" || req.Options["opt"] != "val" {
+		t.Errorf("unexpected request: %+v", req)
+	}
+	if d := req.Descriptor; len(d.Files) != 1 || d.Files[0].Name != "a.mprot" || len(d.Files[0].Decls) != 1 || d.Files[0].Decls[0].Name != "S" {
+		t.Errorf("unexpected descriptor: %+v", d)
+	}
+
+	if _, err := ReadRequest(strings.NewReader(`{"version": 99}`)); err == nil {
+		t.Error("expected error for unsupported version, got none")
+	}
+	if _, err := ReadRequest(strings.NewReader(`{`)); err == nil {
+		t.Error("expected error for invalid request, got none")
+	}
+}
+
+func TestWriteResponse(t *testing.T) {
+	resp := &Response{
+		Files:       []File{{Name: "a.txt", Source: "a.mprot", Content: "content\n"}},
+		Diagnostics: []Diagnostic{Errorf(schema.Pos{File: "a.mprot", Line: 1, Column: 2}, "error %d", 1)},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteResponse(&buf, resp); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var decoded Response
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("unexpected unmarshal error: %v", err)
+	}
+	if !reflect.DeepEqual(&decoded, resp) {
+		t.Errorf("unexpected response: %+v (expected %+v)", decoded, resp)
+	}
+	if d := decoded.Diagnostics[0]; d.Message != "error 1" || d.File != "a.mprot" {
+		t.Errorf("unexpected diagnostic: %+v", d)
+	}
+}
@@ -0,0 +1,154 @@
+// Package registry provides the runtime type registry for Go code generated
+// by mprotc with the --registry option. The generated code registers all
+// enums, structs, and unions of a package in its init function, so that the
+// types can be looked up and instantiated by their type id at runtime.
+//
+// A created value implements the Encoder and Decoder interfaces of the
+// msgpack-go package, which allows decoding messages dynamically:
+//
+//	v, err := registry.New("github.com/example/api", "user_created")
+//	if err != nil {
+//		return err
+//	}
+//	err = v.(msgpack.Decoder).DecodeMsgpack(r)
+package registry
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Kind describes the kind of a registered type.
+type Kind string
+
+// Kinds of registered types.
+const (
+	EnumKind   Kind = "enum"
+	StructKind Kind = "struct"
+	UnionKind  Kind = "union"
+)
+
+// Type describes a registered type of a generated package.
+type Type struct {
+	ID          string // type id as returned by the TypeID method
+	Name        string // name of the schema declaration
+	Kind        Kind
+	Doc         string
+	Enumerators []Enumerator // enums only
+	Fields      []Field      // structs only
+	Branches    []Branch     // unions only
+
+	// New creates a pointer to a new zero value of the type.
+	New func() interface{}
+}
+
+// Enumerator describes an enumerator of an enum.
+type Enumerator struct {
+	Name  string
+	Value int64
+}
+
+// Field describes a field of a struct.
+type Field struct {
+	Name    string
+	Ordinal int64
+	Type    string // schema type name
+}
+
+// Branch describes a branch of a union.
+type Branch struct {
+	Ordinal int64
+	Type    string // schema type name
+	TypeID  string
+}
+
+// Package holds the registered types of a generated package.
+type Package struct {
+	Path  string // import path of the package
+	types map[string]*Type
+}
+
+// Lookup returns the type with the given type id. If there is no such type,
+// nil will be returned.
+func (p *Package) Lookup(id string) *Type {
+	mtx.RLock()
+	defer mtx.RUnlock()
+	return p.types[id]
+}
+
+// Types returns all registered types of the package sorted by their id.
+func (p *Package) Types() []*Type {
+	mtx.RLock()
+	defer mtx.RUnlock()
+
+	types := make([]*Type, 0, len(p.types))
+	for _, t := range p.types {
+		types = append(types, t)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i].ID < types[j].ID })
+	return types
+}
+
+var (
+	mtx      sync.RWMutex
+	packages = make(map[string]*Package) // import path => package
+)
+
+// Register registers the given types for the package with the given import
+// path. It is called by the generated code and panics if a type id is
+// registered twice for the same package.
+func Register(path string, types ...Type) {
+	mtx.Lock()
+	defer mtx.Unlock()
+
+	pkg := packages[path]
+	if pkg == nil {
+		pkg = &Package{Path: path, types: make(map[string]*Type)}
+		packages[path] = pkg
+	}
+
+	for i := range types {
+		t := types[i]
+		if _, has := pkg.types[t.ID]; has {
+			panic(fmt.Sprintf("registry: duplicate type id %q in package %s", t.ID, path))
+		}
+		pkg.types[t.ID] = &t
+	}
+}
+
+// Lookup returns the registered package with the given import path. If there
+// is no such package, nil will be returned.
+func Lookup(path string) *Package {
+	mtx.RLock()
+	defer mtx.RUnlock()
+	return packages[path]
+}
+
+// Packages returns all registered packages sorted by their import path.
+func Packages() []*Package {
+	mtx.RLock()
+	defer mtx.RUnlock()
+
+	pkgs := make([]*Package, 0, len(packages))
+	for _, pkg := range packages {
+		pkgs = append(pkgs, pkg)
+	}
+	sort.Slice(pkgs, func(i, j int) bool { return pkgs[i].Path < pkgs[j].Path })
+	return pkgs
+}
+
+// New creates a new value of the type with the given type id, which is
+// registered for the package with the given import path.
+func New(path string, id string) (interface{}, error) {
+	pkg := Lookup(path)
+	if pkg == nil {
+		return nil, fmt.Errorf("registry: unknown package %s", path)
+	}
+
+	t := pkg.Lookup(id)
+	if t == nil {
+		return nil, fmt.Errorf("registry: unknown type id %q in package %s", id, path)
+	}
+	return t.New(), nil
+}
@@ -0,0 +1,53 @@
+package registry
+
+import "testing"
+
+type testStruct struct {
+	X int
+}
+
+func TestRegister(t *testing.T) {
+	Register("example.com/test",
+		Type{
+			ID:     "test_struct",
+			Name:   "TestStruct",
+			Kind:   StructKind,
+			Fields: []Field{{Name: "X", Ordinal: 1, Type: "int"}},
+			New:    func() interface{} { return new(testStruct) },
+		},
+	)
+
+	pkg := Lookup("example.com/test")
+	if pkg == nil {
+		t.Fatal("package not registered")
+	}
+	if typ := pkg.Lookup("test_struct"); typ == nil || typ.Name != "TestStruct" || len(typ.Fields) != 1 {
+		t.Errorf("unexpected type: %+v", typ)
+	}
+
+	v, err := New("example.com/test", "test_struct")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := v.(*testStruct); !ok {
+		t.Errorf("unexpected value type: %T", v)
+	}
+
+	if _, err := New("example.com/test", "unknown"); err == nil {
+		t.Error("expected error for unknown type id, got none")
+	}
+	if _, err := New("example.com/unknown", "test_struct"); err == nil {
+		t.Error("expected error for unknown package, got none")
+	}
+}
+
+func TestRegisterDuplicate(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic for duplicate type id")
+		}
+	}()
+
+	typ := Type{ID: "dup", New: func() interface{} { return nil }}
+	Register("example.com/dup", typ, typ)
+}
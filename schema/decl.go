@@ -1,14 +1,18 @@
 package schema
 
-// Decl describes an interface for a declaration.
+// Decl describes an interface for a declaration. Pos returns the position
+// of the declaration keyword and End the position after the declared name,
+// or Pos if the extent of the declaration is unknown.
 type Decl interface {
 	Pos() Pos
+	End() Pos
 	validate(r errorReporter)
 }
 
 // Package holds the data of an mprot package declaration.
 type Package struct {
 	pos  Pos
+	end  Pos
 	Name string
 }
 
@@ -17,15 +21,22 @@ func (p *Package) Pos() Pos {
 	return p.pos
 }
 
+// End implements the Decl interface.
+func (p *Package) End() Pos {
+	return p.end
+}
+
 func (p *Package) validate(r errorReporter) {
 	// do nothing
 }
 
 // Import holds information about an mprot import declaration.
 type Import struct {
-	pos  Pos
-	Path string
-	Name string
+	pos        Pos
+	end        Pos
+	Path       string
+	Name       string
+	IncludeDir string // include directory the import was found in, empty if relative to the importing file
 }
 
 // Pos implements the Decl interface.
@@ -33,6 +44,11 @@ func (i *Import) Pos() Pos {
 	return i.pos
 }
 
+// End implements the Decl interface.
+func (i *Import) End() Pos {
+	return i.end
+}
+
 func (i *Import) validate(r errorReporter) {
 	// do nothing
 }
@@ -40,6 +56,7 @@ func (i *Import) validate(r errorReporter) {
 // Const holds the data of an mprot constant.
 type Const struct {
 	pos   Pos
+	end   Pos
 	Doc   []string
 	Name  string
 	Type  Type
@@ -51,20 +68,38 @@ func (c *Const) Pos() Pos {
 	return c.pos
 }
 
+// End implements the Decl interface.
+func (c *Const) End() Pos {
+	return c.end
+}
+
 func (c *Const) validate(r errorReporter) {
 	// The correct types are already handled by the parser.
 }
 
 // Enumerator holds the data of an enumerator value.
 type Enumerator struct {
+	pos   Pos
+	end   Pos
 	Name  string
 	Value int64
 	Tags  Tags
 }
 
+// Pos returns the position of the enumerator.
+func (e Enumerator) Pos() Pos {
+	return e.pos
+}
+
+// End returns the position after the enumerator.
+func (e Enumerator) End() Pos {
+	return e.end
+}
+
 // Enum holds the data of an mprot enumeration.
 type Enum struct {
 	pos         Pos
+	end         Pos
 	Doc         []string
 	Name        string
 	Enumerators []Enumerator
@@ -75,11 +110,16 @@ func (e *Enum) Pos() Pos {
 	return e.pos
 }
 
+// End implements the Decl interface.
+func (e *Enum) End() Pos {
+	return e.end
+}
+
 func (e *Enum) validate(r errorReporter) {
 	enumerators := make(map[string]struct{}, len(e.Enumerators))
 	for _, en := range e.Enumerators {
 		if _, has := enumerators[en.Name]; has {
-			r.errorf("duplicate enumerator %s in enum %s", en.Name, e.Name)
+			r.reportf(en.pos, en.end, CodeDuplicateEnumerator, "duplicate enumerator %s in enum %s", en.Name, e.Name)
 		}
 		enumerators[en.Name] = struct{}{}
 	}
@@ -87,15 +127,28 @@ func (e *Enum) validate(r errorReporter) {
 
 // Field holds the data of a struct field.
 type Field struct {
+	pos     Pos
+	end     Pos
 	Name    string
 	Type    Type
 	Ordinal int64
 	Tags    Tags
 }
 
+// Pos returns the position of the field.
+func (f Field) Pos() Pos {
+	return f.pos
+}
+
+// End returns the position after the field.
+func (f Field) End() Pos {
+	return f.end
+}
+
 // Struct holds the data of an mprot struct.
 type Struct struct {
 	pos    Pos
+	end    Pos
 	Doc    []string
 	Name   string
 	Fields []Field
@@ -106,18 +159,23 @@ func (s *Struct) Pos() Pos {
 	return s.pos
 }
 
+// End implements the Decl interface.
+func (s *Struct) End() Pos {
+	return s.end
+}
+
 func (s *Struct) validate(r errorReporter) {
 	fields := make(map[string]struct{}, len(s.Fields))
 	ordinals := make(map[int64]struct{}, len(s.Fields))
 	for _, f := range s.Fields {
 		if _, has := fields[f.Name]; has {
-			r.errorf("duplicate field %s in struct %s", f.Name, s.Name)
+			r.reportf(f.pos, f.end, CodeDuplicateField, "duplicate field %s in struct %s", f.Name, s.Name)
 		} else if _, has := ordinals[f.Ordinal]; has && f.Ordinal != 0 {
-			r.errorf("duplicate ordinal %d for field %s in struct %s", f.Ordinal, f.Name, s.Name)
+			r.reportf(f.pos, f.end, CodeDuplicateOrdinal, "duplicate ordinal %d for field %s in struct %s", f.Ordinal, f.Name, s.Name)
 		}
 
 		if isService(f.Type) {
-			r.errorf("service field %s in struct %s", f.Name, s.Name)
+			r.reportf(f.pos, f.end, CodeInvalidFieldType, "service field %s in struct %s", f.Name, s.Name)
 		}
 
 		fields[f.Name] = struct{}{}
@@ -127,14 +185,27 @@ func (s *Struct) validate(r errorReporter) {
 
 // Branch holds the data for a union branch.
 type Branch struct {
+	pos     Pos
+	end     Pos
 	Type    Type
 	Ordinal int64
 	Tags    Tags
 }
 
+// Pos returns the position of the branch.
+func (b Branch) Pos() Pos {
+	return b.pos
+}
+
+// End returns the position after the branch.
+func (b Branch) End() Pos {
+	return b.end
+}
+
 // Union holds the data of an mprot union.
 type Union struct {
 	pos      Pos
+	end      Pos
 	Doc      []string
 	Name     string
 	Branches []Branch
@@ -145,9 +216,14 @@ func (u *Union) Pos() Pos {
 	return u.pos
 }
 
+// End implements the Decl interface.
+func (u *Union) End() Pos {
+	return u.end
+}
+
 func (u *Union) validate(r errorReporter) {
 	if len(u.Branches) == 0 {
-		r.errorf("union %s does not contain a branch", u.Name)
+		r.reportf(u.pos, u.end, CodeEmptyUnion, "union %s does not contain a branch", u.Name)
 		return
 	}
 
@@ -158,45 +234,45 @@ func (u *Union) validate(r errorReporter) {
 		typeid := b.Type.typeid()
 		switch typ := b.Type.(type) {
 		case *Pointer:
-			r.errorf("pointer branch %s in union %s", typ.Name(), u.Name)
+			r.reportf(b.pos, b.end, CodeInvalidBranchType, "pointer branch %s in union %s", typ.Name(), u.Name)
 		case *Int:
 			if hasNumericBranch {
-				r.errorf("duplicate numeric branch %s in union %s", typ.Name(), u.Name)
+				r.reportf(b.pos, b.end, CodeDuplicateBranch, "duplicate numeric branch %s in union %s", typ.Name(), u.Name)
 			}
 			hasNumericBranch = true
 		case *Float:
 			if hasNumericBranch {
-				r.errorf("duplicate numeric branch %s in union %s", typ.Name(), u.Name)
+				r.reportf(b.pos, b.end, CodeDuplicateBranch, "duplicate numeric branch %s in union %s", typ.Name(), u.Name)
 			}
 			hasNumericBranch = true
 		case *Raw:
-			r.errorf("raw branch in union %s", u.Name)
+			r.reportf(b.pos, b.end, CodeInvalidBranchType, "raw branch in union %s", u.Name)
 		case *DefinedType:
 			if _, has := branches[typeid]; has {
-				r.errorf("duplicate branch %s in union %s", typeid, u.Name)
+				r.reportf(b.pos, b.end, CodeDuplicateBranch, "duplicate branch %s in union %s", typeid, u.Name)
 			} else {
 				switch typ.Decl.(type) {
 				case *Enum:
 					if hasNumericBranch {
-						r.errorf("duplicate numeric branch %s in union %s", typ.Name(), u.Name)
+						r.reportf(b.pos, b.end, CodeDuplicateBranch, "duplicate numeric branch %s in union %s", typ.Name(), u.Name)
 					}
 					hasNumericBranch = true
 				case *Union:
-					r.errorf("union branch %s in union %s", typ.Name(), u.Name)
+					r.reportf(b.pos, b.end, CodeInvalidBranchType, "union branch %s in union %s", typ.Name(), u.Name)
 					continue
 				case *Service:
-					r.errorf("service branch %s in union %s", typ.Name(), u.Name)
+					r.reportf(b.pos, b.end, CodeInvalidBranchType, "service branch %s in union %s", typ.Name(), u.Name)
 					continue
 				}
 			}
 		default:
 			if _, has := branches[typeid]; has {
-				r.errorf("duplicate branch %s in union %s (only one %s branch is allowed)", typ.Name(), u.Name, typeid)
+				r.reportf(b.pos, b.end, CodeDuplicateBranch, "duplicate branch %s in union %s (only one %s branch is allowed)", typ.Name(), u.Name, typeid)
 			}
 		}
 
 		if _, has := ordinals[b.Ordinal]; has && b.Ordinal != 0 {
-			r.errorf("duplicate ordinal %d for branch %s in union %s", b.Ordinal, b.Type.Name(), u.Name)
+			r.reportf(b.pos, b.end, CodeDuplicateOrdinal, "duplicate ordinal %d for branch %s in union %s", b.Ordinal, b.Type.Name(), u.Name)
 		}
 
 		branches[typeid] = struct{}{}
@@ -206,6 +282,8 @@ func (u *Union) validate(r errorReporter) {
 
 // Method holds the data for a service methods.
 type Method struct {
+	pos     Pos
+	end     Pos
 	Doc     []string
 	Name    string
 	Args    []Type
@@ -214,9 +292,20 @@ type Method struct {
 	Tags    Tags
 }
 
+// Pos returns the position of the method.
+func (m Method) Pos() Pos {
+	return m.pos
+}
+
+// End returns the position after the method.
+func (m Method) End() Pos {
+	return m.end
+}
+
 // Service holds the data of an mprot service.
 type Service struct {
 	pos     Pos
+	end     Pos
 	Doc     []string
 	Name    string
 	Methods []Method
@@ -227,20 +316,25 @@ func (s *Service) Pos() Pos {
 	return s.pos
 }
 
+// End implements the Decl interface.
+func (s *Service) End() Pos {
+	return s.end
+}
+
 func (s *Service) validate(r errorReporter) {
 	methods := make(map[string]struct{}, len(s.Methods))
 	for _, m := range s.Methods {
 		if _, has := methods[m.Name]; has {
-			r.errorf("duplicate method %s in service %s", m.Name, s.Name)
+			r.reportf(m.pos, m.end, CodeDuplicateMethod, "duplicate method %s in service %s", m.Name, s.Name)
 		}
 
 		for _, arg := range m.Args {
 			if isService(arg) {
-				r.errorf("argument in method %s of service %s must not be a service", m.Name, s.Name)
+				r.reportf(m.pos, m.end, CodeInvalidMethodType, "argument in method %s of service %s must not be a service", m.Name, s.Name)
 			}
 		}
 		if isService(m.Return) {
-			r.errorf("method %s of service %s must not return a service type", m.Name, s.Name)
+			r.reportf(m.pos, m.end, CodeInvalidMethodType, "method %s of service %s must not return a service type", m.Name, s.Name)
 		}
 
 		methods[m.Name] = struct{}{}
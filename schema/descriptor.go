@@ -0,0 +1,549 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+
+	"github.com/mprot/mprotc/internal/msgpack"
+)
+
+// DescriptorVersion is the version of the descriptor format.
+const DescriptorVersion = 1
+
+// Descriptor is the serializable representation of a schema. A descriptor can
+// be encoded as JSON or as MessagePack and loaded back into a schema.
+type Descriptor struct {
+	Version int              `json:"version"`
+	Files   []FileDescriptor `json:"files"`
+}
+
+// FileDescriptor describes a schema file.
+type FileDescriptor struct {
+	Name    string             `json:"name"` // slash-separated
+	Doc     []string           `json:"doc,omitempty"`
+	Package PackageDescriptor  `json:"package"`
+	Imports []ImportDescriptor `json:"imports,omitempty"`
+	Decls   []DeclDescriptor   `json:"decls,omitempty"`
+}
+
+// PosDescriptor describes a position within a schema file.
+type PosDescriptor struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+// PackageDescriptor describes the package declaration of a file.
+type PackageDescriptor struct {
+	Pos  PosDescriptor  `json:"pos"`
+	End  *PosDescriptor `json:"end,omitempty"` // after the package name
+	Name string         `json:"name"`
+}
+
+// ImportDescriptor describes an import declaration.
+type ImportDescriptor struct {
+	Pos        PosDescriptor  `json:"pos"`
+	End        *PosDescriptor `json:"end,omitempty"` // after the import path
+	Name       string         `json:"name"`
+	Path       string         `json:"path"`
+	IncludeDir string         `json:"includeDir,omitempty"`
+}
+
+// DeclDescriptor describes a declaration. The kind of the declaration is
+// either const, enum, struct, union, or service.
+type DeclDescriptor struct {
+	Kind        string                 `json:"kind"`
+	Pos         PosDescriptor          `json:"pos"`
+	End         *PosDescriptor         `json:"end,omitempty"` // after the declared name
+	Doc         []string               `json:"doc,omitempty"`
+	Name        string                 `json:"name"`
+	Type        *TypeDescriptor        `json:"type,omitempty"`  // const
+	Value       string                 `json:"value,omitempty"` // const
+	Enumerators []EnumeratorDescriptor `json:"enumerators,omitempty"`
+	Fields      []FieldDescriptor      `json:"fields,omitempty"`
+	Branches    []BranchDescriptor     `json:"branches,omitempty"`
+	Methods     []MethodDescriptor     `json:"methods,omitempty"`
+}
+
+// EnumeratorDescriptor describes an enumerator of an enum.
+type EnumeratorDescriptor struct {
+	Pos   *PosDescriptor    `json:"pos,omitempty"`
+	End   *PosDescriptor    `json:"end,omitempty"`
+	Name  string            `json:"name"`
+	Value int64             `json:"value"`
+	Tags  map[string]string `json:"tags,omitempty"`
+}
+
+// FieldDescriptor describes a field of a struct.
+type FieldDescriptor struct {
+	Pos     *PosDescriptor    `json:"pos,omitempty"`
+	End     *PosDescriptor    `json:"end,omitempty"`
+	Name    string            `json:"name"`
+	Type    *TypeDescriptor   `json:"type"`
+	Ordinal int64             `json:"ordinal"`
+	Tags    map[string]string `json:"tags,omitempty"`
+}
+
+// BranchDescriptor describes a branch of a union.
+type BranchDescriptor struct {
+	Pos     *PosDescriptor    `json:"pos,omitempty"`
+	End     *PosDescriptor    `json:"end,omitempty"`
+	Type    *TypeDescriptor   `json:"type"`
+	Ordinal int64             `json:"ordinal"`
+	Tags    map[string]string `json:"tags,omitempty"`
+}
+
+// MethodDescriptor describes a method of a service.
+type MethodDescriptor struct {
+	Pos     *PosDescriptor    `json:"pos,omitempty"`
+	End     *PosDescriptor    `json:"end,omitempty"`
+	Doc     []string          `json:"doc,omitempty"`
+	Name    string            `json:"name"`
+	Args    []*TypeDescriptor `json:"args,omitempty"`
+	Return  *TypeDescriptor   `json:"return,omitempty"`
+	Ordinal int64             `json:"ordinal"`
+	Tags    map[string]string `json:"tags,omitempty"`
+}
+
+// TypeDescriptor describes a type. The kind of the type is either bool, int,
+// float, string, bytes, raw, time, array, map, pointer, or defined. Defined
+// types are referenced by name and the name of the import, if the type is
+// imported.
+type TypeDescriptor struct {
+	Kind     string          `json:"kind"`
+	Bits     int             `json:"bits,omitempty"`     // int, float
+	Unsigned bool            `json:"unsigned,omitempty"` // int
+	Size     int             `json:"size,omitempty"`     // array
+	Key      *TypeDescriptor `json:"key,omitempty"`      // map
+	Value    *TypeDescriptor `json:"value,omitempty"`    // array, map, pointer
+	Name     string          `json:"name,omitempty"`     // defined
+	Import   string          `json:"import,omitempty"`   // defined
+}
+
+// NewDescriptor creates the descriptor of the given schema.
+func NewDescriptor(s Schema) *Descriptor {
+	d := &Descriptor{
+		Version: DescriptorVersion,
+		Files:   make([]FileDescriptor, 0, len(s)),
+	}
+
+	for _, f := range s {
+		fd := FileDescriptor{
+			Name: filepath.ToSlash(f.Name),
+			Doc:  f.Doc,
+		}
+		if f.Package != nil {
+			fd.Package = PackageDescriptor{Pos: posDescriptor(f.Package.pos), End: endDescriptor(f.Package.pos, f.Package.end), Name: f.Package.Name}
+		}
+		for _, imp := range sortedImports(f) {
+			fd.Imports = append(fd.Imports, ImportDescriptor{
+				Pos:        posDescriptor(imp.pos),
+				End:        endDescriptor(imp.pos, imp.end),
+				Name:       imp.Name,
+				Path:       imp.Path,
+				IncludeDir: imp.IncludeDir,
+			})
+		}
+		for _, decl := range f.Decls {
+			fd.Decls = append(fd.Decls, declDescriptor(decl))
+		}
+		d.Files = append(d.Files, fd)
+	}
+	return d
+}
+
+// MarshalMsgpack encodes the descriptor as MessagePack.
+func (d *Descriptor) MarshalMsgpack() ([]byte, error) {
+	v, err := genericValue(d)
+	if err != nil {
+		return nil, err
+	}
+	return msgpack.Marshal(v)
+}
+
+// UnmarshalMsgpack decodes the descriptor from MessagePack.
+func (d *Descriptor) UnmarshalMsgpack(data []byte) error {
+	v, err := msgpack.Unmarshal(data)
+	if err != nil {
+		return err
+	}
+	js, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(js, d)
+}
+
+// ReadDescriptor reads a descriptor, which is either encoded as JSON or as
+// MessagePack. The encoding is detected automatically.
+func ReadDescriptor(r io.Reader) (*Descriptor, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &Descriptor{}
+	if trimmed := bytes.TrimSpace(data); len(trimmed) != 0 && trimmed[0] == '{' {
+		err = json.Unmarshal(data, d)
+	} else {
+		err = d.UnmarshalMsgpack(data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema descriptor: %v", err)
+	}
+	return d, nil
+}
+
+// LoadDescriptor reads a descriptor and loads it into a schema.
+func LoadDescriptor(r io.Reader) (Schema, error) {
+	d, err := ReadDescriptor(r)
+	if err != nil {
+		return nil, err
+	}
+	return d.Schema()
+}
+
+// Schema loads the descriptor into a schema. All defined types are resolved
+// as if the schema was parsed from its files.
+func (d *Descriptor) Schema() (Schema, error) {
+	if d.Version != DescriptorVersion {
+		return nil, fmt.Errorf("unsupported schema descriptor version %d", d.Version)
+	}
+
+	s := make(Schema, 0, len(d.Files))
+	errs := ErrorList{}
+	for _, fd := range d.Files {
+		l := descriptorLoader{
+			filename: fd.Name,
+			types:    make(map[string]*DefinedType),
+			errs:     &errs,
+		}
+		s = append(s, l.load(fd))
+	}
+	errs.sort()
+	return s, errs.err()
+}
+
+func genericValue(v interface{}) (interface{}, error) {
+	js, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var res interface{}
+	dec := json.NewDecoder(bytes.NewReader(js))
+	dec.UseNumber()
+	err = dec.Decode(&res)
+	return res, err
+}
+
+func posDescriptor(pos Pos) PosDescriptor {
+	return PosDescriptor{Line: pos.Line, Column: pos.Column}
+}
+
+// memberPosDescriptor returns the descriptor of the position of a member, or
+// nil if the position is unknown.
+func memberPosDescriptor(pos Pos) *PosDescriptor {
+	if pos.Line == 0 {
+		return nil
+	}
+	pd := posDescriptor(pos)
+	return &pd
+}
+
+// endDescriptor returns the descriptor of an end position, or nil if the end
+// position is unknown.
+func endDescriptor(pos, end Pos) *PosDescriptor {
+	if end.Line == 0 || end == pos {
+		return nil
+	}
+	pd := posDescriptor(end)
+	return &pd
+}
+
+func declDescriptor(decl Decl) DeclDescriptor {
+	switch decl := decl.(type) {
+	case *Const:
+		return DeclDescriptor{
+			Kind:  "const",
+			Pos:   posDescriptor(decl.pos),
+			End:   endDescriptor(decl.pos, decl.end),
+			Doc:   decl.Doc,
+			Name:  decl.Name,
+			Type:  typeDescriptor(decl.Type),
+			Value: decl.Value,
+		}
+
+	case *Enum:
+		d := DeclDescriptor{Kind: "enum", Pos: posDescriptor(decl.pos), End: endDescriptor(decl.pos, decl.end), Doc: decl.Doc, Name: decl.Name}
+		for _, e := range decl.Enumerators {
+			d.Enumerators = append(d.Enumerators, EnumeratorDescriptor{
+				Pos:   memberPosDescriptor(e.pos),
+				End:   endDescriptor(e.pos, e.end),
+				Name:  e.Name,
+				Value: e.Value,
+				Tags:  e.Tags,
+			})
+		}
+		return d
+
+	case *Struct:
+		d := DeclDescriptor{Kind: "struct", Pos: posDescriptor(decl.pos), End: endDescriptor(decl.pos, decl.end), Doc: decl.Doc, Name: decl.Name}
+		for _, f := range decl.Fields {
+			d.Fields = append(d.Fields, FieldDescriptor{
+				Pos:     memberPosDescriptor(f.pos),
+				End:     endDescriptor(f.pos, f.end),
+				Name:    f.Name,
+				Type:    typeDescriptor(f.Type),
+				Ordinal: f.Ordinal,
+				Tags:    f.Tags,
+			})
+		}
+		return d
+
+	case *Union:
+		d := DeclDescriptor{Kind: "union", Pos: posDescriptor(decl.pos), End: endDescriptor(decl.pos, decl.end), Doc: decl.Doc, Name: decl.Name}
+		for _, b := range decl.Branches {
+			d.Branches = append(d.Branches, BranchDescriptor{
+				Pos:     memberPosDescriptor(b.pos),
+				End:     endDescriptor(b.pos, b.end),
+				Type:    typeDescriptor(b.Type),
+				Ordinal: b.Ordinal,
+				Tags:    b.Tags,
+			})
+		}
+		return d
+
+	case *Service:
+		d := DeclDescriptor{Kind: "service", Pos: posDescriptor(decl.pos), End: endDescriptor(decl.pos, decl.end), Doc: decl.Doc, Name: decl.Name}
+		for _, m := range decl.Methods {
+			md := MethodDescriptor{
+				Pos:     memberPosDescriptor(m.pos),
+				End:     endDescriptor(m.pos, m.end),
+				Doc:     m.Doc,
+				Name:    m.Name,
+				Return:  typeDescriptor(m.Return),
+				Ordinal: m.Ordinal,
+				Tags:    m.Tags,
+			}
+			for _, arg := range m.Args {
+				md.Args = append(md.Args, typeDescriptor(arg))
+			}
+			d.Methods = append(d.Methods, md)
+		}
+		return d
+
+	default:
+		panic(fmt.Sprintf("unsupported declaration type %T", decl))
+	}
+}
+
+func typeDescriptor(t Type) *TypeDescriptor {
+	switch t := t.(type) {
+	case nil:
+		return nil
+	case *Bool:
+		return &TypeDescriptor{Kind: "bool"}
+	case *Int:
+		return &TypeDescriptor{Kind: "int", Bits: t.Bits, Unsigned: t.Unsigned}
+	case *Float:
+		return &TypeDescriptor{Kind: "float", Bits: t.Bits}
+	case *String:
+		return &TypeDescriptor{Kind: "string"}
+	case *Bytes:
+		return &TypeDescriptor{Kind: "bytes"}
+	case *Raw:
+		return &TypeDescriptor{Kind: "raw"}
+	case *Time:
+		return &TypeDescriptor{Kind: "time"}
+	case *Array:
+		return &TypeDescriptor{Kind: "array", Size: t.Size, Value: typeDescriptor(t.Value)}
+	case *Map:
+		return &TypeDescriptor{Kind: "map", Key: typeDescriptor(t.Key), Value: typeDescriptor(t.Value)}
+	case *Pointer:
+		return &TypeDescriptor{Kind: "pointer", Value: typeDescriptor(t.Value)}
+	case *DefinedType:
+		return &TypeDescriptor{Kind: "defined", Name: t.name, Import: t.pkg}
+	default:
+		panic(fmt.Sprintf("unsupported type %T", t))
+	}
+}
+
+type descriptorLoader struct {
+	filename string
+	imports  map[string]*Import
+	types    map[string]*DefinedType // type name => type
+	errs     *ErrorList
+}
+
+func (l *descriptorLoader) load(fd FileDescriptor) *File {
+	pkgPos := l.pos(fd.Package.Pos)
+	f := &File{
+		Name:    filepath.FromSlash(fd.Name),
+		Doc:     fd.Doc,
+		Package: &Package{pos: pkgPos, end: l.end(pkgPos, fd.Package.End), Name: fd.Package.Name},
+		Imports: make(map[string]*Import, len(fd.Imports)),
+		Decls:   make([]Decl, 0, len(fd.Decls)),
+	}
+	l.imports = f.Imports
+
+	for _, imp := range fd.Imports {
+		pos := l.pos(imp.Pos)
+		f.Imports[imp.Name] = &Import{pos: pos, end: l.end(pos, imp.End), Name: imp.Name, Path: imp.Path, IncludeDir: imp.IncludeDir}
+	}
+
+	// declare all types first to resolve forward references
+	descs := make([]DeclDescriptor, 0, len(fd.Decls))
+	for _, dd := range fd.Decls {
+		decl := l.newDecl(dd)
+		if decl == nil {
+			l.errorf(dd.Pos, CodeUnsupportedType, "unsupported declaration kind %q", dd.Kind)
+			continue
+		}
+		if typ := DeclType(decl); typ != nil {
+			l.types[typ.name] = typ
+		}
+		f.Decls = append(f.Decls, decl)
+		descs = append(descs, dd)
+	}
+
+	for i, decl := range f.Decls {
+		l.fillDecl(decl, descs[i])
+	}
+	return f
+}
+
+func (l *descriptorLoader) newDecl(dd DeclDescriptor) Decl {
+	pos, end := l.pos(dd.Pos), l.end(l.pos(dd.Pos), dd.End)
+	switch dd.Kind {
+	case "const":
+		return &Const{pos: pos, end: end, Doc: dd.Doc, Name: dd.Name, Value: dd.Value}
+	case "enum":
+		return &Enum{pos: pos, end: end, Doc: dd.Doc, Name: dd.Name}
+	case "struct":
+		return &Struct{pos: pos, end: end, Doc: dd.Doc, Name: dd.Name}
+	case "union":
+		return &Union{pos: pos, end: end, Doc: dd.Doc, Name: dd.Name}
+	case "service":
+		return &Service{pos: pos, end: end, Doc: dd.Doc, Name: dd.Name}
+	default:
+		return nil
+	}
+}
+
+func (l *descriptorLoader) fillDecl(decl Decl, dd DeclDescriptor) {
+	switch decl := decl.(type) {
+	case *Const:
+		decl.Type = l.typ(dd.Pos, dd.Type)
+	case *Enum:
+		for _, e := range dd.Enumerators {
+			pos, end := l.memberPos(dd.Pos, e.Pos, e.End)
+			decl.Enumerators = append(decl.Enumerators, Enumerator{pos: pos, end: end, Name: e.Name, Value: e.Value, Tags: tags(e.Tags)})
+		}
+	case *Struct:
+		for _, f := range dd.Fields {
+			pos, end := l.memberPos(dd.Pos, f.Pos, f.End)
+			decl.Fields = append(decl.Fields, Field{pos: pos, end: end, Name: f.Name, Type: l.typ(dd.Pos, f.Type), Ordinal: f.Ordinal, Tags: tags(f.Tags)})
+		}
+	case *Union:
+		for _, b := range dd.Branches {
+			pos, end := l.memberPos(dd.Pos, b.Pos, b.End)
+			decl.Branches = append(decl.Branches, Branch{pos: pos, end: end, Type: l.typ(dd.Pos, b.Type), Ordinal: b.Ordinal, Tags: tags(b.Tags)})
+		}
+	case *Service:
+		for _, m := range dd.Methods {
+			pos, end := l.memberPos(dd.Pos, m.Pos, m.End)
+			method := Method{pos: pos, end: end, Doc: m.Doc, Name: m.Name, Ordinal: m.Ordinal, Tags: tags(m.Tags)}
+			for _, arg := range m.Args {
+				method.Args = append(method.Args, l.typ(dd.Pos, arg))
+			}
+			if m.Return != nil {
+				method.Return = l.typ(dd.Pos, m.Return)
+			}
+			decl.Methods = append(decl.Methods, method)
+		}
+	}
+}
+
+func (l *descriptorLoader) typ(pos PosDescriptor, td *TypeDescriptor) Type {
+	if td == nil {
+		l.errorf(pos, CodeUnsupportedType, "missing type")
+		return nil
+	}
+
+	switch td.Kind {
+	case "bool":
+		return &Bool{}
+	case "int":
+		return &Int{Bits: td.Bits, Unsigned: td.Unsigned}
+	case "float":
+		return &Float{Bits: td.Bits}
+	case "string":
+		return &String{}
+	case "bytes":
+		return &Bytes{}
+	case "raw":
+		return &Raw{}
+	case "time":
+		return &Time{}
+	case "array":
+		return &Array{Size: td.Size, Value: l.typ(pos, td.Value)}
+	case "map":
+		return &Map{Key: l.typ(pos, td.Key), Value: l.typ(pos, td.Value)}
+	case "pointer":
+		return &Pointer{Value: l.typ(pos, td.Value)}
+	case "defined":
+		if td.Import != "" {
+			imp := l.imports[td.Import]
+			if imp == nil {
+				l.errorf(pos, CodeUndefinedType, "undefined type %s.%s", td.Import, td.Name)
+				return &DefinedType{pkg: td.Import, name: td.Name}
+			}
+			return &DefinedType{pkg: td.Import, name: td.Name, Decl: imp}
+		}
+		if typ := l.types[td.Name]; typ != nil {
+			return typ
+		}
+		l.errorf(pos, CodeUndefinedType, "undefined type %s", td.Name)
+		return &DefinedType{name: td.Name}
+	default:
+		l.errorf(pos, CodeUnsupportedType, "unsupported type kind %q", td.Kind)
+		return nil
+	}
+}
+
+func (l *descriptorLoader) pos(pd PosDescriptor) Pos {
+	return Pos{File: l.filename, Line: pd.Line, Column: pd.Column}
+}
+
+// memberPos returns the position and the end position of a member. Without a
+// position descriptor, the position of the declaration is used.
+func (l *descriptorLoader) memberPos(declPos PosDescriptor, pd *PosDescriptor, end *PosDescriptor) (Pos, Pos) {
+	pos := l.pos(declPos)
+	if pd != nil {
+		pos = l.pos(*pd)
+	}
+	return pos, l.end(pos, end)
+}
+
+// end returns the end position of the given descriptor. Without a descriptor,
+// the end position is unknown and equals the start position.
+func (l *descriptorLoader) end(pos Pos, pd *PosDescriptor) Pos {
+	if pd == nil {
+		return pos
+	}
+	return l.pos(*pd)
+}
+
+func (l *descriptorLoader) errorf(pd PosDescriptor, code ErrorCode, format string, args ...interface{}) {
+	l.errs.add(l.pos(pd), Pos{}, code, fmt.Sprintf(format, args...))
+}
+
+func tags(t map[string]string) Tags {
+	if t == nil {
+		return Tags{}
+	}
+	return t
+}
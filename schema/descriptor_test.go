@@ -0,0 +1,114 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDescriptorRoundtrip(t *testing.T) {
+	s, err := ParseSources(map[string]string{
+		"a.mprot": `// Package doc.
+package foo
+
+import "b.mprot"
+import c "c.mprot"
+
+const Pi = 3.14
+
+// E is an enum.
+enum E {
+	X "1 deprecated"
+	Y "2"
+}
+
+struct S {
+	A *T              "1"
+	B map[string][]E  "2"
+	C [4]b.Ext        ` + "`3 json:\"c\"`" + `
+	D time            "4"
+}
+
+union U {
+	S     "1"
+	c.Ext "2"
+}
+
+service Svc {
+	// M does something.
+	M(S, int32) U "1"
+	N()           "2"
+}
+
+struct T {
+	X uint16 "1"
+}
+`,
+		"b.mprot": "package b\nstruct Ext {\n\tX bytes \"1\"\n}\n",
+	})
+	if err != nil {
+		t.Fatalf("unexpected parsing error: %v", err)
+	}
+
+	d := NewDescriptor(s)
+
+	t.Run("json", func(t *testing.T) {
+		data, err := json.Marshal(d)
+		if err != nil {
+			t.Fatalf("unexpected marshal error: %v", err)
+		}
+		loaded, err := LoadDescriptor(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("unexpected load error: %v", err)
+		}
+		if !reflect.DeepEqual(loaded, s) {
+			t.Errorf("unexpected schema loaded from json descriptor")
+		}
+	})
+
+	t.Run("msgpack", func(t *testing.T) {
+		data, err := d.MarshalMsgpack()
+		if err != nil {
+			t.Fatalf("unexpected marshal error: %v", err)
+		}
+		loaded, err := LoadDescriptor(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("unexpected load error: %v", err)
+		}
+		if !reflect.DeepEqual(loaded, s) {
+			t.Errorf("unexpected schema loaded from msgpack descriptor")
+		}
+	})
+}
+
+func TestLoadDescriptorErrors(t *testing.T) {
+	tests := []struct {
+		descriptor string
+		err        string
+	}{
+		{
+			descriptor: `{"version": 2}`,
+			err:        "unsupported schema descriptor version 2",
+		},
+		{
+			descriptor: `{"version": 1, "files": [{"name": "a.mprot", "decls": [{"kind": "struct", "pos": {"line": 2, "column": 1}, "name": "S", "fields": [{"name": "X", "type": {"kind": "defined", "name": "T"}, "ordinal": 1}]}]}]}`,
+			err:        "a.mprot:2:1: undefined type T",
+		},
+		{
+			descriptor: "\xc1",
+			err:        "invalid schema descriptor: msgpack: unsupported type code 0xc1",
+		},
+	}
+
+	for _, test := range tests {
+		_, err := LoadDescriptor(strings.NewReader(test.descriptor))
+		switch {
+		case err == nil:
+			t.Errorf("expected error %q, got none", test.err)
+		case err.Error() != test.err:
+			t.Errorf("unexpected error: %v (expected %q)", err, test.err)
+		}
+	}
+}
@@ -0,0 +1,27 @@
+// Package schema implements the parser and the data model of mprot schema
+// definitions.
+//
+// A schema consists of several files, which are parsed with Parse, ParseFS,
+// or ParseSources. Each file holds its package declaration, its imports, and
+// a list of declarations (constants, enums, structs, unions, and services).
+// The declarations refer to types, which are either builtin types like Int
+// or String, composite types like Array or Map, or defined types referring
+// to a declaration of the same file or to an import. Walk can be used to
+// traverse all type references of a schema.
+//
+// # Compatibility
+//
+// The exported API of this package is stable within a major version of the
+// mprotc module, i.e. exported identifiers will neither be removed nor be
+// changed in an incompatible way. However, the following additions are not
+// considered as breaking changes:
+//
+//   - new fields of the declaration and type structures,
+//   - new declaration types implementing Decl,
+//   - new type kinds implementing Type,
+//   - new error codes.
+//
+// Code which switches on the dynamic type of a Decl or a Type should
+// therefore handle unknown types gracefully. The Decl and Type interfaces
+// cannot be implemented outside of this package.
+package schema
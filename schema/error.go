@@ -0,0 +1,147 @@
+package schema
+
+import (
+	"fmt"
+	"sort"
+)
+
+const (
+	errInvalidOctNumber      = errorString("invalid octal number")
+	errInvalidHexNumber      = errorString("invalid hexadecimal number")
+	errInvalidFloatNumber    = errorString("invalid floating-point number")
+	errStringNotTerminated   = errorString("string not terminated")
+	errCommentNotTerminated  = errorString("comment not terminated")
+	errInvalidEscapeSequence = errorString("invalid escape sequence")
+	errNullChar              = errorString("invalid character nul")
+	errInvalidEncoding       = errorString("invalid encoding")
+	errBufferOverflow        = errorString("buffer overflow")
+	errInvalidBom            = errorString("invalid byte order mark")
+)
+
+// ErrorCode identifies a specific kind of schema error. The codes are
+// stable and can be used by tools to classify the reported errors.
+type ErrorCode string
+
+// Error codes reported when parsing and validating a schema.
+const (
+	CodeInvalidToken        ErrorCode = "invalid-token"
+	CodeUnexpectedToken     ErrorCode = "unexpected-token"
+	CodeInvalidImport       ErrorCode = "invalid-import"
+	CodeDuplicateImport     ErrorCode = "duplicate-import"
+	CodeUnresolvedImport    ErrorCode = "unresolved-import"
+	CodeMissingTag          ErrorCode = "missing-tag"
+	CodeInvalidOrdinal      ErrorCode = "invalid-ordinal"
+	CodeInvalidTag          ErrorCode = "invalid-tag"
+	CodeInvalidArraySize    ErrorCode = "invalid-array-size"
+	CodeUnsupportedType     ErrorCode = "unsupported-type"
+	CodeUndefinedType       ErrorCode = "undefined-type"
+	CodeRedeclaredType      ErrorCode = "redeclared-type"
+	CodeDuplicateEnumerator ErrorCode = "duplicate-enumerator"
+	CodeDuplicateField      ErrorCode = "duplicate-field"
+	CodeDuplicateBranch     ErrorCode = "duplicate-branch"
+	CodeDuplicateMethod     ErrorCode = "duplicate-method"
+	CodeDuplicateOrdinal    ErrorCode = "duplicate-ordinal"
+	CodeInvalidFieldType    ErrorCode = "invalid-field-type"
+	CodeInvalidBranchType   ErrorCode = "invalid-branch-type"
+	CodeInvalidMethodType   ErrorCode = "invalid-method-type"
+	CodeEmptyUnion          ErrorCode = "empty-union"
+	CodeConflictingPackage  ErrorCode = "conflicting-package"
+	CodeRedeclaredName      ErrorCode = "redeclared-name"
+	CodeDuplicateTypeID     ErrorCode = "duplicate-type-id"
+)
+
+type errorReporter interface {
+	reportf(pos Pos, end Pos, code ErrorCode, format string, args ...interface{})
+}
+
+type errorString string
+
+func errorf(format string, args ...interface{}) error {
+	return errorString(fmt.Sprintf(format, args...))
+}
+
+func (e errorString) Error() string {
+	return string(e)
+}
+
+// Error describes an error at a specific position of a schema file.
+// The end position is exclusive and equals the start position, if the
+// extent of the erroneous text is unknown.
+type Error struct {
+	Pos  Pos
+	End  Pos
+	Code ErrorCode
+	Text string
+}
+
+func (e Error) Error() string {
+	return e.Pos.String() + ": " + e.Text
+}
+
+// ErrorList holds a list of errors.
+type ErrorList []Error
+
+func (e ErrorList) Error() string {
+	switch len(e) {
+	case 0:
+		return "no errors"
+	case 1:
+		return e[0].Error()
+	case 2:
+		return fmt.Sprintf("%v (and 1 more error)", e[0])
+	default:
+		return fmt.Sprintf("%v (and %d more errors)", e[0], len(e)-1)
+	}
+}
+
+func (e ErrorList) err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+func (e *ErrorList) add(pos Pos, end Pos, code ErrorCode, text string) {
+	if end.Line == 0 {
+		end = pos
+	}
+	*e = append(*e, Error{
+		Pos:  pos,
+		End:  end,
+		Code: code,
+		Text: text,
+	})
+}
+
+func (e ErrorList) concat(el ErrorList) ErrorList {
+	return append(e, el...)
+}
+
+// collect adds the given error to the list, if it is an error list. All
+// other errors are returned unchanged.
+func (e *ErrorList) collect(err error) error {
+	if err == nil {
+		return nil
+	}
+	if el, ok := err.(ErrorList); ok {
+		*e = e.concat(el)
+		return nil
+	}
+	return err
+}
+
+func (e ErrorList) sort() {
+	sort.SliceStable(e, func(i, j int) bool {
+		left, right := e[i].Pos, e[j].Pos
+		switch {
+		case left.File != right.File:
+			return left.File < right.File
+		case left.Line != right.Line:
+			return left.Line < right.Line
+		case left.Column != right.Column:
+			return left.Column < right.Column
+		default:
+			return false
+		}
+	})
+}
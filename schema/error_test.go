@@ -24,6 +24,22 @@ func TestPosError(t *testing.T) {
 	}
 }
 
+func TestErrorListEnd(t *testing.T) {
+	var errs ErrorList
+	errs.add(Pos{Line: 2, Column: 4}, Pos{}, CodeUndefinedType, "foo")
+	errs.add(Pos{Line: 3, Column: 1}, Pos{Line: 3, Column: 5}, CodeUndefinedType, "bar")
+
+	if end := errs[0].End; end != errs[0].Pos {
+		t.Errorf("unexpected end position: %v", end)
+	}
+	if end := errs[1].End; end != (Pos{Line: 3, Column: 5}) {
+		t.Errorf("unexpected end position: %v", end)
+	}
+	if code := errs[1].Code; code != CodeUndefinedType {
+		t.Errorf("unexpected error code: %s", code)
+	}
+}
+
 func TestErrorList(t *testing.T) {
 	var errs ErrorList
 
@@ -34,7 +50,7 @@ func TestErrorList(t *testing.T) {
 		t.Errorf("unexpected error message: %q", msg)
 	}
 
-	errs.add(Pos{Line: 2, Column: 4}, "foo")
+	errs.add(Pos{Line: 2, Column: 4}, Pos{}, CodeInvalidToken, "foo")
 	if err := errs.err(); err == nil {
 		t.Error("expected error, got none")
 	}
@@ -42,7 +58,7 @@ func TestErrorList(t *testing.T) {
 		t.Errorf("unexpected error message: %q", msg)
 	}
 
-	errs.add(Pos{Line: 3, Column: 1}, "bar")
+	errs.add(Pos{Line: 3, Column: 1}, Pos{}, CodeInvalidToken, "bar")
 	if err := errs.err(); err == nil {
 		t.Error("expected error, got none")
 	}
@@ -50,7 +66,7 @@ func TestErrorList(t *testing.T) {
 		t.Errorf("unexpected error message: %q", msg)
 	}
 
-	errs.add(Pos{Line: 4, Column: 3}, "baz")
+	errs.add(Pos{Line: 4, Column: 3}, Pos{}, CodeInvalidToken, "baz")
 	if err := errs.err(); err == nil {
 		t.Error("expected error, got none")
 	}
@@ -0,0 +1,336 @@
+package schema
+
+import (
+	"bytes"
+	"strings"
+	"unicode/utf8"
+)
+
+// Format formats the given schema source in the canonical layout. Comments
+// and the grouping of declarations and members by blank lines are kept. The
+// names, types, and tag strings of the members of a block are aligned in
+// columns. The filename is only used for the positions of reported errors.
+//
+// The source has to be syntactically correct, otherwise the syntax errors
+// will be returned. Semantic errors, e.g. undefined types, are ignored.
+func Format(filename string, src []byte) ([]byte, error) {
+	var p parser
+	if _, err := p.Parse(bytes.NewReader(src), filename); err != nil {
+		if errs := syntaxErrors(err); errs != nil {
+			return nil, errs
+		}
+	}
+
+	var t tokenizer
+	t.Reset(bytes.NewReader(src), filename, 4096)
+
+	var toks []ftoken
+	for {
+		tok, lit, pos := t.Next()
+		if tok == eof || tok == invalid {
+			break
+		}
+		if tok != semicol {
+			toks = append(toks, ftoken{tok: tok, lit: lit, pos: pos})
+		}
+	}
+
+	f := formatter{toks: toks}
+	f.formatFile()
+	return f.buf.Bytes(), nil
+}
+
+func syntaxErrors(err error) error {
+	errs, ok := err.(ErrorList)
+	if !ok {
+		return err
+	}
+
+	var res ErrorList
+	for _, e := range errs {
+		switch e.Code {
+		case CodeInvalidToken, CodeUnexpectedToken, CodeMissingTag, CodeInvalidTag:
+			res = append(res, e)
+		}
+	}
+	return res.err()
+}
+
+type ftoken struct {
+	tok token
+	lit string
+	pos Pos
+}
+
+func (t ftoken) endLine() int {
+	return t.pos.Line + strings.Count(t.lit, "\n")
+}
+
+// row holds the aligned cells of a block member.
+type row struct {
+	cells   []string
+	comment string
+}
+
+type formatter struct {
+	toks     []ftoken
+	idx      int
+	buf      bytes.Buffer
+	lastLine int // last source line of the previous item (0 for none)
+}
+
+func (f *formatter) formatFile() {
+	for f.idx < len(f.toks) {
+		t := f.peek()
+		f.separate(t.pos.Line)
+
+		switch t.tok {
+		case comment:
+			f.printComment("", f.next())
+		case packg:
+			f.next()
+			f.printLine("", "package "+f.next().lit)
+		case imprt:
+			f.next()
+			line := "import "
+			if f.peek().tok == ident {
+				line += f.next().lit + " "
+			}
+			f.printLine("", line+f.next().lit)
+		case constant:
+			f.next()
+			name := f.next().lit
+			f.next() // assign
+			f.printLine("", "const "+name+" = "+f.next().lit)
+		case enum, strct, union, service:
+			f.formatBlock()
+		default:
+			// cannot happen for syntactically correct sources
+			f.printLine("", f.next().lit)
+		}
+	}
+}
+
+func (f *formatter) formatBlock() {
+	kind := f.next().tok
+	name := f.next().lit
+	f.next() // lbrace
+
+	if t := f.peek(); t.tok == rbrace {
+		f.next()
+		f.printLine("", string(kind)+" "+name+" {}")
+		return
+	}
+	f.printLine("", string(kind)+" "+name+" {")
+
+	var rows []row
+	first := true
+	for f.idx < len(f.toks) && f.peek().tok != rbrace {
+		t := f.peek()
+		if !first && t.pos.Line > f.lastLine+1 {
+			f.flushRows(rows)
+			rows = rows[:0]
+			f.buf.WriteByte('\n')
+		}
+		first = false
+
+		if t.tok == comment {
+			f.flushRows(rows)
+			rows = rows[:0]
+			f.printComment("\t", f.next())
+			continue
+		}
+
+		var r row
+		switch kind {
+		case enum:
+			r.cells = []string{f.next().lit, ""}
+		case strct:
+			r.cells = []string{f.next().lit, f.typeString(), ""}
+		case union:
+			r.cells = []string{f.typeString(), ""}
+		case service:
+			sig := f.next().lit + f.next().lit // name and lparen
+			for f.peek().tok != rparen {
+				if f.peek().tok == comma {
+					f.next()
+					sig += ", "
+					continue
+				}
+				sig += f.typeString()
+			}
+			sig += f.next().lit // rparen
+			if ret := f.typeString(); ret != "" {
+				sig += " " + ret
+			}
+			r.cells = []string{sig, ""}
+		}
+		tag := f.next()
+		r.cells[len(r.cells)-1] = normalizeTag(tag.lit)
+		r.comment = f.trailingComment(tag.pos.Line)
+		rows = append(rows, r)
+	}
+	f.flushRows(rows)
+
+	if f.idx < len(f.toks) {
+		f.next() // rbrace
+	}
+	f.printLine("", "}")
+}
+
+// typeString returns the string representation of the type at the current
+// position. The type ends at the next tag string, comma, or right parenthesis.
+// An empty string is returned, if there is no type at the current position.
+func (f *formatter) typeString() string {
+	var sb strings.Builder
+	for f.idx < len(f.toks) {
+		switch f.peek().tok {
+		case strlit, comma, rparen:
+			return sb.String()
+		case comment:
+			f.next() // comments within types are dropped
+		default:
+			sb.WriteString(f.next().lit)
+		}
+	}
+	return sb.String()
+}
+
+func (f *formatter) flushRows(rows []row) {
+	if len(rows) == 0 {
+		return
+	}
+
+	ncols := len(rows[0].cells)
+	widths := make([]int, ncols)
+	for _, r := range rows {
+		for i, cell := range r.cells {
+			if n := utf8.RuneCountInString(cell); n > widths[i] {
+				widths[i] = n
+			}
+		}
+	}
+
+	lines := make([]string, len(rows))
+	commentCol := 0
+	for i, r := range rows {
+		var sb strings.Builder
+		for j, cell := range r.cells {
+			if widths[j] == 0 {
+				continue
+			}
+			if sb.Len() != 0 {
+				sb.WriteByte(' ')
+			}
+			sb.WriteString(cell)
+			if j < ncols-1 {
+				sb.WriteString(strings.Repeat(" ", widths[j]-utf8.RuneCountInString(cell)))
+			}
+		}
+		lines[i] = sb.String()
+		if r.comment != "" {
+			if n := utf8.RuneCountInString(lines[i]); n > commentCol {
+				commentCol = n
+			}
+		}
+	}
+
+	for i, r := range rows {
+		line := lines[i]
+		if r.comment != "" {
+			line += strings.Repeat(" ", commentCol-utf8.RuneCountInString(line)) + " " + r.comment
+		} else {
+			line = strings.TrimRight(line, " ")
+		}
+		f.buf.WriteString("\t" + line + "\n")
+	}
+}
+
+// separate writes a blank line, if the item at the given source line was
+// separated by blank lines from the previous item.
+func (f *formatter) separate(line int) {
+	if f.lastLine != 0 && line > f.lastLine+1 {
+		f.buf.WriteByte('\n')
+	}
+}
+
+func (f *formatter) printLine(indent string, line string) {
+	last := f.toks[f.idx-1]
+	if c := f.trailingComment(last.endLine()); c != "" {
+		line += " " + c
+	}
+	f.buf.WriteString(indent + line + "\n")
+}
+
+func (f *formatter) printComment(indent string, c ftoken) {
+	lit := c.lit
+	if strings.HasPrefix(lit, "//") {
+		lit = trimTrailingSpaces(lit)
+	}
+	f.buf.WriteString(indent + lit + "\n")
+	f.lastLine = c.endLine()
+}
+
+// trailingComment returns the comment which follows the current position on
+// the given source line.
+func (f *formatter) trailingComment(line int) string {
+	f.lastLine = line
+	if f.idx < len(f.toks) {
+		if t := f.peek(); t.tok == comment && t.pos.Line == line {
+			f.next()
+			f.lastLine = t.endLine()
+			if strings.HasPrefix(t.lit, "//") {
+				return trimTrailingSpaces(t.lit)
+			}
+			return t.lit
+		}
+	}
+	return ""
+}
+
+func (f *formatter) peek() ftoken {
+	return f.toks[f.idx]
+}
+
+func (f *formatter) next() ftoken {
+	t := f.toks[f.idx]
+	f.idx++
+	return t
+}
+
+// normalizeTag trims the tag string and separates the ordinal and the tags
+// by single spaces. Quoted tag values are left unchanged.
+func normalizeTag(lit string) string {
+	delim, s := lit[:1], lit[1:len(lit)-1]
+
+	var sb strings.Builder
+	sb.WriteString(delim)
+	space := false
+	quoted := false
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		switch {
+		case quoted:
+			if ch == '\\' && i+1 < len(s) {
+				sb.WriteByte(ch)
+				i++
+				ch = s[i]
+			} else if ch == '"' {
+				quoted = false
+			}
+		case ch == ' ' || ch == '\t':
+			space = true
+			continue
+		case ch == '"':
+			quoted = true
+		}
+
+		if space && sb.Len() > 1 {
+			sb.WriteByte(' ')
+		}
+		space = false
+		sb.WriteByte(ch)
+	}
+	sb.WriteString(delim)
+	return sb.String()
+}
@@ -0,0 +1,107 @@
+package schema
+
+import "testing"
+
+func TestFormat(t *testing.T) {
+	const input = `// package comment
+package   foo
+import "external1.mprot"
+import ext    "external2.mprot"
+
+
+// line comment
+
+const CS =   "foo"
+const CI = 7 // seven
+
+/*
+	my struct
+*/
+struct S {   // trailing
+	B bool ` + "`" + ` 1   tagkey:"tag  val" ` + "`" + `
+	Int  int    "2"  // comment
+
+	// group
+	LongName   map[string]ext.X "3"
+	P *E "4"
+}
+enum E { A "1"; B "2"
+	LongName    "-3"
+}
+enum F {}
+union U {
+	S            "1"
+	[]S   "3"
+}
+service Svc {
+	// F1 doc
+	F1()                        "1"
+	F2() int   "2"
+	F3(ext.X,string, float32)  "3"
+}
+`
+
+	const expected = `// package comment
+package foo
+import "external1.mprot"
+import ext "external2.mprot"
+
+// line comment
+
+const CS = "foo"
+const CI = 7 // seven
+
+/*
+	my struct
+*/
+struct S { // trailing
+	B   bool ` + "`" + `1 tagkey:"tag  val"` + "`" + `
+	Int int  "2" // comment
+
+	// group
+	LongName map[string]ext.X "3"
+	P        *E               "4"
+}
+enum E {
+	A        "1"
+	B        "2"
+	LongName "-3"
+}
+enum F {}
+union U {
+	S   "1"
+	[]S "3"
+}
+service Svc {
+	// F1 doc
+	F1()                       "1"
+	F2() int                   "2"
+	F3(ext.X, string, float32) "3"
+}
+`
+
+	res, err := Format("", []byte(input))
+	if err != nil {
+		t.Fatalf("unexpected format error: %v", err)
+	}
+	if string(res) != expected {
+		t.Errorf("unexpected formatted source:\n%s", res)
+	}
+
+	res, err = Format("", res)
+	if err != nil {
+		t.Fatalf("unexpected format error: %v", err)
+	}
+	if string(res) != expected {
+		t.Errorf("formatting is not idempotent:\n%s", res)
+	}
+}
+
+func TestFormatErrors(t *testing.T) {
+	if _, err := Format("", []byte("package foo\nstruct S {\n\tA int\n}\n")); err == nil {
+		t.Error("expected syntax error, got none")
+	}
+	if _, err := Format("", []byte("package foo\nstruct S {\n\tA X \"1\"\n}\n")); err != nil {
+		t.Errorf("unexpected format error: %v", err)
+	}
+}
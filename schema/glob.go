@@ -0,0 +1,477 @@
+package schema
+
+import (
+	"errors"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// ignoreFile is the name of the files holding the patterns of files and
+// directories, which are skipped when walking a directory.
+const ignoreFile = ".mprotignore"
+
+type fileset map[string]struct{}
+
+func (fs fileset) add(filename string) {
+	fs[filename] = struct{}{}
+}
+
+func (fs fileset) size() int {
+	return len(fs)
+}
+
+func (fs fileset) filenames() []string {
+	res := make([]string, 0, len(fs))
+	for f := range fs {
+		res = append(res, f)
+	}
+	sort.Strings(res)
+	return res
+}
+
+// Glob returns the sorted names of all files of fsys, which match the given
+// glob patterns. The returned names are slash-separated paths relative to the
+// root of fsys.
+//
+// A pattern consists of slash-separated components. A component matches a
+// single file or directory name and may contain the wildcards '*' and '?'
+// and character classes like [a-z] or [!a-z]. The component "**" matches
+// zero or more directories, or all files recursively if it is the last
+// component. Brace alternatives like {api,events} expand a pattern into one
+// pattern per alternative. A pattern prefixed with '!' excludes all matching
+// files from the result, regardless of the order of the patterns.
+//
+// Files and directories listed in a .mprotignore file are skipped when
+// walking the directory of the ignore file and all of its subdirectories.
+// Each line of an ignore file holds a pattern relative to the directory of
+// the ignore file. A pattern without a slash matches a name at any depth,
+// a pattern with a trailing slash matches directories only. Empty lines and
+// lines starting with '#' are ignored.
+func Glob(fsys fs.FS, patterns []string) ([]string, error) {
+	fset, err := glob(fsys, patterns)
+	if err != nil {
+		return nil, err
+	}
+	return fset.filenames(), nil
+}
+
+// glob returns all files of fsys which match one of the given patterns. The
+// returned filenames are slash-separated paths relative to the root of fsys.
+func glob(fsys fs.FS, patterns []string) (fileset, error) {
+	g := globber{
+		fsys:    fsys,
+		ignores: make(map[string][]ignoreRule),
+	}
+
+	fset := make(fileset)
+	var excludes []globPattern
+	for _, pattern := range patterns {
+		if filepath.Separator != '/' {
+			pattern = strings.ReplaceAll(pattern, string(filepath.Separator), "/")
+		}
+		exclude := strings.HasPrefix(pattern, "!")
+		pattern = strings.TrimPrefix(pattern, "!")
+
+		for _, pattern := range expandBraces(pattern) {
+			components := strings.Split(pattern, "/")
+			if exclude {
+				p, err := compileGlobPattern(components)
+				if err != nil {
+					return nil, err
+				}
+				excludes = append(excludes, p)
+				continue
+			}
+
+			if err := g.glob(components, 0, ".", fset); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for filename := range fset {
+		names := strings.Split(filename, "/")
+		for _, p := range excludes {
+			if p.match(names) {
+				delete(fset, filename)
+				break
+			}
+		}
+	}
+	return fset, nil
+}
+
+type globber struct {
+	fsys    fs.FS
+	ignores map[string][]ignoreRule // directory => ignore rules
+}
+
+func (g *globber) glob(components []string, idx int, rootPath string, fset fileset) error {
+	if idx == len(components) {
+		return nil
+	}
+
+	isLast := idx == len(components)-1
+	switch components[idx] {
+	case "":
+		return nil
+
+	case "*":
+		if isLast {
+			files, err := g.readFiles(rootPath)
+			if err != nil {
+				return err
+			}
+
+			for _, f := range files {
+				fset.add(path.Join(rootPath, f.Name()))
+			}
+			return nil
+		} else {
+			// walk over all subdirs
+			subdirs, err := g.readSubdirs(rootPath)
+			if err != nil {
+				return err
+			}
+
+			for _, subdir := range subdirs {
+				err = g.glob(components, idx+1, path.Join(rootPath, subdir.Name()), fset)
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+
+	case "**":
+		if isLast {
+			return g.walkFiles(rootPath, fset)
+		}
+
+		err := g.glob(components, idx+1, rootPath, fset)
+		if err != nil {
+			return err
+		}
+
+		subdirs, err := g.readSubdirs(rootPath)
+		if err != nil {
+			return err
+		}
+
+		for _, subdir := range subdirs {
+			err = g.glob(components, idx, path.Join(rootPath, subdir.Name()), fset)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+
+	default:
+		rx, err := globRegexp(components[idx])
+		if err != nil {
+			return err
+		}
+
+		if isLast {
+			files, err := g.readFiles(rootPath)
+			if err != nil {
+				return err
+			}
+
+			for _, f := range files {
+				if name := f.Name(); rx.MatchString(name) {
+					fset.add(path.Join(rootPath, name))
+				}
+			}
+		} else {
+			subdirs, err := g.readSubdirs(rootPath)
+			if err != nil {
+				return err
+			}
+
+			for _, subdir := range subdirs {
+				if name := subdir.Name(); rx.MatchString(name) {
+					err = g.glob(components, idx+1, path.Join(rootPath, name), fset)
+					if err != nil {
+						return err
+					}
+				}
+			}
+		}
+		return nil
+	}
+}
+
+// walkFiles adds all files of the given directory and its subdirectories.
+func (g *globber) walkFiles(dirname string, fset fileset) error {
+	files, err := g.readFiles(dirname)
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		fset.add(path.Join(dirname, f.Name()))
+	}
+
+	subdirs, err := g.readSubdirs(dirname)
+	if err != nil {
+		return err
+	}
+	for _, subdir := range subdirs {
+		if err := g.walkFiles(path.Join(dirname, subdir.Name()), fset); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (g *globber) readSubdirs(dirname string) ([]fs.DirEntry, error) {
+	return g.readdir(dirname, func(entry fs.DirEntry) bool {
+		return entry.IsDir()
+	})
+}
+
+func (g *globber) readFiles(dirname string) ([]fs.DirEntry, error) {
+	return g.readdir(dirname, func(info fs.DirEntry) bool {
+		return !info.IsDir()
+	})
+}
+
+// readdir reads the entries of the given directory, which pass the filter
+// and are not ignored.
+func (g *globber) readdir(dirname string, filter func(fs.DirEntry) bool) ([]fs.DirEntry, error) {
+	rules, err := g.ignoreRules(dirname)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := fs.ReadDir(g.fsys, dirname)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]fs.DirEntry, 0, len(entries))
+	for _, entry := range entries {
+		if !filter(entry) || entry.Name() == ignoreFile {
+			continue
+		}
+		if ignored(rules, path.Join(dirname, entry.Name()), entry.IsDir()) {
+			continue
+		}
+		res = append(res, entry)
+	}
+	return res, nil
+}
+
+// ignoreRules returns the rules of all ignore files, which apply to the
+// entries of the given directory.
+func (g *globber) ignoreRules(dirname string) ([]ignoreRule, error) {
+	if rules, has := g.ignores[dirname]; has {
+		return rules, nil
+	}
+
+	var rules []ignoreRule
+	if dirname != "." {
+		parentRules, err := g.ignoreRules(path.Dir(dirname))
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, parentRules...)
+	}
+
+	filename := path.Join(dirname, ignoreFile)
+	data, err := fs.ReadFile(g.fsys, filename)
+	switch {
+	case err == nil:
+		ownRules, err := parseIgnoreFile(dirname, string(data))
+		if err != nil {
+			return nil, errorf("%s: %v", filename, err)
+		}
+		rules = append(rules, ownRules...)
+	case !errors.Is(err, fs.ErrNotExist):
+		return nil, err
+	}
+
+	g.ignores[dirname] = rules
+	return rules, nil
+}
+
+type ignoreRule struct {
+	base    string // slash-separated directory of the ignore file
+	pattern globPattern
+	dirOnly bool
+}
+
+func parseIgnoreFile(base string, content string) ([]ignoreRule, error) {
+	var rules []ignoreRule
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		dirOnly := strings.HasSuffix(line, "/")
+		line = strings.TrimSuffix(line, "/")
+		if strings.Contains(line, "/") {
+			line = strings.TrimPrefix(line, "/")
+		} else {
+			line = "**/" + line
+		}
+
+		for _, pattern := range expandBraces(line) {
+			p, err := compileGlobPattern(strings.Split(pattern, "/"))
+			if err != nil {
+				return nil, err
+			}
+			rules = append(rules, ignoreRule{base: base, pattern: p, dirOnly: dirOnly})
+		}
+	}
+	return rules, nil
+}
+
+func (r ignoreRule) matches(name string, isDir bool) bool {
+	if r.dirOnly && !isDir {
+		return false
+	}
+	if r.base != "." {
+		if !strings.HasPrefix(name, r.base+"/") {
+			return false
+		}
+		name = name[len(r.base)+1:]
+	}
+	return r.pattern.match(strings.Split(name, "/"))
+}
+
+func ignored(rules []ignoreRule, name string, isDir bool) bool {
+	for _, r := range rules {
+		if r.matches(name, isDir) {
+			return true
+		}
+	}
+	return false
+}
+
+// globPattern is a compiled glob pattern, which matches slash-separated
+// paths. The component "**" is represented by a nil regular expression.
+type globPattern []*regexp.Regexp
+
+func compileGlobPattern(components []string) (globPattern, error) {
+	p := make(globPattern, 0, len(components))
+	for _, c := range components {
+		switch c {
+		case "", ".":
+			continue
+		case "**":
+			p = append(p, nil)
+		default:
+			rx, err := globRegexp(c)
+			if err != nil {
+				return nil, err
+			}
+			p = append(p, rx)
+		}
+	}
+	return p, nil
+}
+
+func (p globPattern) match(names []string) bool {
+	switch {
+	case len(p) == 0:
+		return len(names) == 0
+	case p[0] == nil && len(p) == 1:
+		return len(names) != 0 // a trailing ** matches everything below
+	case p[0] == nil:
+		for i := 0; i <= len(names); i++ {
+			if p[1:].match(names[i:]) {
+				return true
+			}
+		}
+		return false
+	default:
+		return len(names) != 0 && p[0].MatchString(names[0]) && p[1:].match(names[1:])
+	}
+}
+
+// expandBraces expands the first brace alternatives of the pattern and
+// returns one pattern for each alternative. Nested and subsequent braces are
+// expanded recursively. Unbalanced braces are kept as they are.
+func expandBraces(pattern string) []string {
+	start := strings.IndexByte(pattern, '{')
+	if start < 0 {
+		return []string{pattern}
+	}
+
+	var alternatives []string
+	depth := 0
+	last := start + 1
+	for i := start + 1; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '{':
+			depth++
+		case ',':
+			if depth == 0 {
+				alternatives = append(alternatives, pattern[last:i])
+				last = i + 1
+			}
+		case '}':
+			if depth != 0 {
+				depth--
+				continue
+			}
+
+			alternatives = append(alternatives, pattern[last:i])
+			var res []string
+			for _, alt := range alternatives {
+				res = append(res, expandBraces(pattern[:start]+alt+pattern[i+1:])...)
+			}
+			return res
+		}
+	}
+	return []string{pattern}
+}
+
+func globRegexp(s string) (*regexp.Regexp, error) {
+	var rx strings.Builder
+	rx.WriteByte('^')
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '*':
+			if i+1 < len(s) && s[i+1] == '*' {
+				return nil, errorString("invalid glob token '**'")
+			}
+			rx.WriteString(".*")
+
+		case '?':
+			rx.WriteByte('.')
+
+		case '[':
+			rx.WriteByte('[')
+			if i++; i < len(s) {
+				if s[i] == '!' {
+					rx.WriteByte('^')
+				} else {
+					rx.WriteByte(s[i])
+				}
+				for i++; i < len(s) && s[i] != ']'; i++ {
+					rx.WriteByte(s[i])
+				}
+				if i < len(s) {
+					rx.WriteByte(']')
+				}
+			}
+
+		default:
+			rx.WriteString(regexp.QuoteMeta(s[i : i+1]))
+		}
+	}
+	rx.WriteByte('$')
+
+	res, err := regexp.Compile(rx.String())
+	if err != nil {
+		return nil, errorf("invalid glob token '%s'", s)
+	}
+	return res, nil
+}
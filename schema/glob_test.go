@@ -0,0 +1,93 @@
+package schema
+
+import (
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+func TestGlob(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a.mprot":                  {},
+		"b.txt":                    {},
+		"api/c.mprot":              {},
+		"api/v1/d.mprot":           {},
+		"events/e.mprot":           {},
+		"other/f.mprot":            {},
+		"testdata/g.mprot":         {},
+		"testdata/sub/h.mprot":     {},
+		"ignored/.mprotignore":     {Data: []byte("# comment\n\n*.gen.mprot\nsub/\n")},
+		"ignored/i.mprot":          {},
+		"ignored/i.gen.mprot":      {},
+		"ignored/sub/j.mprot":      {},
+		"ignored/deep/k.gen.mprot": {},
+	}
+
+	tests := []struct {
+		patterns []string
+		expected []string
+	}{
+		{
+			patterns: []string{"*.mprot"},
+			expected: []string{"a.mprot"},
+		},
+		{
+			patterns: []string{"a.mpro"},
+			expected: []string{},
+		},
+		{
+			patterns: []string{"**/*.mprot", "!testdata/**", "!ignored/**"},
+			expected: []string{"a.mprot", "api/c.mprot", "api/v1/d.mprot", "events/e.mprot", "other/f.mprot"},
+		},
+		{
+			patterns: []string{"{api,events}/**/*.mprot"},
+			expected: []string{"api/c.mprot", "api/v1/d.mprot", "events/e.mprot"},
+		},
+		{
+			patterns: []string{"api/{c,v1/{d,x}}.mprot"},
+			expected: []string{"api/c.mprot", "api/v1/d.mprot"},
+		},
+		{
+			patterns: []string{"testdata/**"},
+			expected: []string{"testdata/g.mprot", "testdata/sub/h.mprot"},
+		},
+		{
+			patterns: []string{"ignored/**"},
+			expected: []string{"ignored/i.mprot"},
+		},
+		{
+			patterns: []string{"**/*.mprot", "!**/{api,testdata,ignored}/**", "!a.*"},
+			expected: []string{"events/e.mprot", "other/f.mprot"},
+		},
+	}
+
+	for _, test := range tests {
+		filenames, err := Glob(fsys, test.patterns)
+		if err != nil {
+			t.Errorf("unexpected error for %v: %v", test.patterns, err)
+			continue
+		}
+		if !reflect.DeepEqual(filenames, test.expected) {
+			t.Errorf("unexpected files for %v: %v (expected %v)", test.patterns, filenames, test.expected)
+		}
+	}
+}
+
+func TestExpandBraces(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		expected []string
+	}{
+		{pattern: "a/b", expected: []string{"a/b"}},
+		{pattern: "{a,b}/c", expected: []string{"a/c", "b/c"}},
+		{pattern: "{a,b}/{c,d}", expected: []string{"a/c", "a/d", "b/c", "b/d"}},
+		{pattern: "x{a,{b,c}}", expected: []string{"xa", "xb", "xc"}},
+		{pattern: "{a,b", expected: []string{"{a,b"}},
+	}
+
+	for _, test := range tests {
+		if res := expandBraces(test.pattern); !reflect.DeepEqual(res, test.expected) {
+			t.Errorf("unexpected expansion of %q: %v (expected %v)", test.pattern, res, test.expected)
+		}
+	}
+}
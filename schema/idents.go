@@ -0,0 +1,50 @@
+package schema
+
+import (
+	"bytes"
+	"path/filepath"
+	"sort"
+)
+
+// Ident describes an identifier of a schema file, which declares or refers to
+// an enum, struct, union, or service. The name of an imported type includes
+// the import qualifier, e.g. "pkg.Type", and its type refers to the import
+// declaration.
+type Ident struct {
+	Name      string
+	Pos       Pos
+	End       Pos // exclusive
+	Type      *DefinedType
+	Declaring bool // declaration of the type?
+}
+
+// ParseFile parses a single schema file from its source. The imports of the
+// file are resolved relative to the directory of the file and in the include
+// directories of the configuration, but the imported files are not parsed.
+//
+// In addition to the parsed file, all identifiers of declared and referenced
+// types are returned in the order of their appearance. Even if errors are
+// reported, the file and the identifiers are returned for the parsed parts,
+// which makes the function suitable for editors and other tools.
+func (c Config) ParseFile(filename string, src []byte) (*File, []Ident, error) {
+	p := parser{}
+	f, err := p.Parse(bytes.NewReader(src), filename)
+
+	errs := ErrorList{}
+	if err = errs.collect(err); err != nil {
+		return nil, nil, err
+	}
+
+	r := newOSImportResolver("", c.IncludeDirs)
+	r.resolve(f, filepath.ToSlash(filename), &errs)
+	errs.sort()
+
+	idents := p.refs
+	sort.Slice(idents, func(i, j int) bool {
+		if idents[i].Pos.Line != idents[j].Pos.Line {
+			return idents[i].Pos.Line < idents[j].Pos.Line
+		}
+		return idents[i].Pos.Column < idents[j].Pos.Column
+	})
+	return f, idents, errs.err()
+}
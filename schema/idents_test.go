@@ -0,0 +1,78 @@
+package schema
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseFile(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "b.mprot"), []byte("package b\nstruct B {}\n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	src := `package a
+
+import "b.mprot"
+import "missing.mprot"
+
+struct S {
+	X T   "1"
+	Y b.B "2"
+	Z U   "3"
+}
+
+union T {
+	S "1"
+}
+`
+	filename := filepath.Join(dir, "a.mprot")
+	f, idents, err := Config{}.ParseFile(filename, []byte(src))
+	if f == nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	errs, ok := err.(ErrorList)
+	if !ok || len(errs) != 2 {
+		t.Fatalf("unexpected errors: %v", err)
+	}
+	if errs[0].Code != CodeUnresolvedImport || errs[0].Pos.Line != 4 {
+		t.Errorf("unexpected first error: %v", errs[0])
+	}
+	if errs[1].Code != CodeUndefinedType || errs[1].Pos.Line != 9 {
+		t.Errorf("unexpected second error: %v", errs[1])
+	}
+	if imp := f.Imports["b"]; imp == nil || imp.IncludeDir != "" {
+		t.Errorf("unexpected import: %+v", imp)
+	}
+
+	expected := []struct {
+		name      string
+		line, col int
+		declaring bool
+		decl      bool
+	}{
+		{name: "S", line: 6, col: 8, declaring: true, decl: true},
+		{name: "T", line: 7, col: 4, decl: true},
+		{name: "b.B", line: 8, col: 4, decl: true},
+		{name: "U", line: 9, col: 4},
+		{name: "T", line: 12, col: 7, declaring: true, decl: true},
+		{name: "S", line: 13, col: 2, decl: true},
+	}
+	if len(idents) != len(expected) {
+		t.Fatalf("unexpected number of identifiers: %d (expected %d)", len(idents), len(expected))
+	}
+	for i, exp := range expected {
+		id := idents[i]
+		if id.Name != exp.name || id.Pos.Line != exp.line || id.Pos.Column != exp.col || id.Declaring != exp.declaring {
+			t.Errorf("unexpected identifier %d: %+v", i, id)
+		}
+		if id.End.Column != exp.col+len(exp.name) {
+			t.Errorf("unexpected end of identifier %d: %v", i, id.End)
+		}
+		if (id.Type.Decl != nil) != exp.decl {
+			t.Errorf("unexpected declaration of identifier %d: %v", i, id.Type.Decl)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package schema
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// importResolver locates the imported schema files. An import path is
+// searched relative to the directory of the importing file first and then
+// in the include directories in the given order. The first existing file
+// wins.
+type importResolver struct {
+	root        string   // slash-separated root of the importing files
+	includeDirs []string // slash-separated
+	exists      func(name string) bool
+}
+
+func newOSImportResolver(rootDir string, includeDirs []string) *importResolver {
+	r := &importResolver{
+		root: filepath.ToSlash(rootDir),
+		exists: func(name string) bool {
+			info, err := os.Stat(filepath.FromSlash(name))
+			return err == nil && !info.IsDir()
+		},
+	}
+	for _, dir := range includeDirs {
+		r.includeDirs = append(r.includeDirs, filepath.ToSlash(dir))
+	}
+	return r
+}
+
+func newFSImportResolver(fsys fs.FS, includeDirs []string) *importResolver {
+	return &importResolver{
+		includeDirs: includeDirs,
+		exists: func(name string) bool {
+			info, err := fs.Stat(fsys, strings.TrimPrefix(name, "./"))
+			return err == nil && !info.IsDir()
+		},
+	}
+}
+
+// resolve resolves the imports of the file with the given slash-separated
+// name. Imports which cannot be found are reported with all searched
+// locations.
+func (r *importResolver) resolve(f *File, filename string, errs *ErrorList) {
+	for _, imp := range sortedImports(f) {
+		candidates := make([]string, 0, 1+len(r.includeDirs))
+		candidates = append(candidates, path.Join(r.root, path.Dir(filename), imp.Path))
+		for _, dir := range r.includeDirs {
+			candidates = append(candidates, path.Join(dir, imp.Path))
+		}
+
+		found := false
+		for i, candidate := range candidates {
+			if r.exists(candidate) {
+				if i > 0 {
+					imp.IncludeDir = filepath.FromSlash(r.includeDirs[i-1])
+				}
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			for i := range candidates {
+				candidates[i] = filepath.FromSlash(candidates[i])
+			}
+			errs.add(imp.pos, Pos{}, CodeUnresolvedImport, fmt.Sprintf("import %q not found (searched %s)", imp.Path, strings.Join(candidates, ", ")))
+		}
+	}
+}
+
+func sortedImports(f *File) []*Import {
+	imports := make([]*Import, 0, len(f.Imports))
+	for _, imp := range f.Imports {
+		imports = append(imports, imp)
+	}
+	sort.Slice(imports, func(i, j int) bool {
+		return imports[i].Name < imports[j].Name
+	})
+	return imports
+}
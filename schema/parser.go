@@ -3,10 +3,10 @@ package schema
 import (
 	"fmt"
 	"io"
-	"os"
 	"path"
 	"strconv"
 	"strings"
+	"unicode/utf8"
 )
 
 type unresolved struct {
@@ -19,20 +19,12 @@ type parser struct {
 	tok        token
 	lit        string
 	pos        Pos
+	end        Pos // end of the previous token
 	doc        []string
 	errs       ErrorList
 	idents     map[string]*DefinedType // type name => type
 	unresolved []unresolved
-}
-
-func (p *parser) ParseFile(filename string) (*File, error) {
-	f, err := os.Open(filename)
-	if err != nil {
-		return nil, err
-	}
-	defer f.Close()
-
-	return p.Parse(f, filename)
+	refs       []Ident // identifiers of declared and referenced types
 }
 
 func (p *parser) Parse(r io.Reader, filename string) (*File, error) {
@@ -41,6 +33,7 @@ func (p *parser) Parse(r io.Reader, filename string) (*File, error) {
 	p.errs = ErrorList{}
 	p.idents = make(map[string]*DefinedType)
 	p.unresolved = p.unresolved[:0]
+	p.refs = nil
 	p.next() // scan initial tok, lit, and pos
 
 	f := &File{Name: filename}
@@ -51,11 +44,13 @@ func (p *parser) Parse(r io.Reader, filename string) (*File, error) {
 
 	// resolve yet unresolved identifiers
 	for _, unresolved := range p.unresolved {
-		if unresolved.typ.Imported() {
-			unresolved.typ.Decl = f.Imports[unresolved.typ.pkg]
+		if imp := f.Imports[unresolved.typ.pkg]; imp != nil && unresolved.typ.Imported() {
+			unresolved.typ.Decl = imp
 		}
 		if unresolved.typ.Decl == nil {
-			p.errorfpos(unresolved.pos, "undefined type %s", unresolved.typ.Name())
+			end := unresolved.pos
+			end.Column += utf8.RuneCountInString(unresolved.typ.Name())
+			p.errorfpos(unresolved.pos, end, CodeUndefinedType, "undefined type %s", unresolved.typ.Name())
 		}
 	}
 
@@ -67,7 +62,9 @@ func (p *parser) parsePackage() *Package {
 	pkg := &Package{pos: p.pos}
 
 	p.expect(packg)
+	namePos := p.pos
 	pkg.Name = p.parseIdent()
+	pkg.end = endPos(namePos, pkg.Name)
 	p.expect(semicol)
 	return pkg
 }
@@ -85,6 +82,7 @@ func (p *parser) parseImports() map[string]*Import {
 		}
 
 		imp.Path = p.lit[1 : len(p.lit)-1] // trim delimiters
+		imp.end = endPos(p.pos, p.lit)
 		p.expect(strlit)
 
 		if imp.Name == "" {
@@ -93,9 +91,9 @@ func (p *parser) parseImports() map[string]*Import {
 		}
 
 		if imp.Path == "" || imp.Name == "" {
-			p.errorf("invalid import path %q", imp.Path)
+			p.errorf(CodeInvalidImport, "invalid import path %q", imp.Path)
 		} else if _, has := imports[imp.Name]; has {
-			p.errorf("import %q already defined", imp.Name)
+			p.errorf(CodeDuplicateImport, "import %q already defined", imp.Name)
 		} else {
 			imports[imp.Name] = imp
 		}
@@ -127,10 +125,10 @@ func (p *parser) parseDecls() []Decl {
 			p.scanError()
 			p.next()
 		case ident:
-			p.errorf("unexpected identifier %q", p.lit)
+			p.errorf(CodeUnexpectedToken, "unexpected identifier %q", p.lit)
 			p.skipStatement()
 		default:
-			p.errorf("unexpected token %q", p.lit)
+			p.errorf(CodeUnexpectedToken, "unexpected token %q", p.lit)
 			p.next()
 		}
 	}
@@ -140,20 +138,22 @@ func (p *parser) parseConst() *Const {
 	c := &Const{pos: p.pos, Doc: p.docComments()}
 
 	p.expect(constant)
+	namePos := p.pos
 	c.Name = p.parseIdent()
+	c.end = endPos(namePos, c.Name)
 	p.expect(assign)
 	switch p.tok {
 	case intlit:
-		c.Type = p.resolve("int64")
+		c.Type = p.resolve("int64", p.pos)
 		c.Value = p.lit
 	case floatlit:
-		c.Type = p.resolve("float64")
+		c.Type = p.resolve("float64", p.pos)
 		c.Value = p.lit
 	case strlit:
-		c.Type = p.resolve("string")
+		c.Type = p.resolve("string", p.pos)
 		c.Value = p.lit[1 : len(p.lit)-1] // trim delimiters
 	default:
-		p.errorf("unexpected token %q in constant declaration", p.lit)
+		p.errorf(CodeUnexpectedToken, "unexpected token %q in constant declaration", p.lit)
 	}
 	p.next()
 	p.expect(semicol)
@@ -164,16 +164,22 @@ func (p *parser) parseEnum() *Enum {
 	e := &Enum{pos: p.pos, Doc: p.docComments()}
 
 	p.expect(enum)
+	namePos := p.pos
 	e.Name = p.parseIdent()
+	e.end = endPos(namePos, e.Name)
 	p.expect(lbrace)
 
 	for p.tok == ident {
+		pos := p.pos
 		name := p.parseIdent()
 		value, tags := p.parseTagString(true)
+		end := p.end
 		p.expect(semicol)
 
 		if name != "" {
 			e.Enumerators = append(e.Enumerators, Enumerator{
+				pos:   pos,
+				end:   end,
 				Name:  name,
 				Value: value,
 				Tags:  tags,
@@ -184,7 +190,7 @@ func (p *parser) parseEnum() *Enum {
 	p.expect(rbrace)
 	p.expect(semicol)
 
-	p.register(e.Name, e)
+	p.declare(e.Name, namePos, e)
 	return e
 }
 
@@ -192,17 +198,23 @@ func (p *parser) parseStruct() *Struct {
 	s := &Struct{pos: p.pos, Doc: p.docComments()}
 
 	p.expect(strct)
+	namePos := p.pos
 	s.Name = p.parseIdent()
+	s.end = endPos(namePos, s.Name)
 	p.expect(lbrace)
 
 	for p.tok != rbrace && p.tok != eof {
+		pos := p.pos
 		name := p.parseIdent()
 		typ := p.parseType()
 		ordinal, tags := p.parseTagString(false)
+		end := p.end
 		p.expect(semicol)
 
 		if name != "" {
 			s.Fields = append(s.Fields, Field{
+				pos:     pos,
+				end:     end,
 				Name:    name,
 				Type:    typ,
 				Ordinal: ordinal,
@@ -214,7 +226,7 @@ func (p *parser) parseStruct() *Struct {
 	p.expect(rbrace)
 	p.expect(semicol)
 
-	p.register(s.Name, s)
+	p.declare(s.Name, namePos, s)
 	return s
 }
 
@@ -222,16 +234,22 @@ func (p *parser) parseUnion() *Union {
 	u := &Union{pos: p.pos, Doc: p.docComments()}
 
 	p.expect(union)
+	namePos := p.pos
 	u.Name = p.parseIdent()
+	u.end = endPos(namePos, u.Name)
 	p.expect(lbrace)
 
 	for p.tok != rbrace && p.tok != eof {
+		pos := p.pos
 		typ := p.parseType()
 		ordinal, tags := p.parseTagString(false)
+		end := p.end
 		p.expect(semicol)
 
 		if typ != nil {
 			u.Branches = append(u.Branches, Branch{
+				pos:     pos,
+				end:     end,
 				Type:    typ,
 				Ordinal: ordinal,
 				Tags:    tags,
@@ -242,7 +260,7 @@ func (p *parser) parseUnion() *Union {
 	p.expect(rbrace)
 	p.expect(semicol)
 
-	p.register(u.Name, u)
+	p.declare(u.Name, namePos, u)
 	return u
 }
 
@@ -250,11 +268,14 @@ func (p *parser) parseService() *Service {
 	s := &Service{pos: p.pos, Doc: p.docComments()}
 
 	p.expect(service)
+	namePos := p.pos
 	s.Name = p.parseIdent()
+	s.end = endPos(namePos, s.Name)
 	p.expect(lbrace)
 
 	for p.tok != rbrace && p.tok != eof {
 		doc := p.docComments()
+		pos := p.pos
 		methodName := p.parseIdent()
 		p.expect(lparen)
 
@@ -269,10 +290,13 @@ func (p *parser) parseService() *Service {
 		p.expect(rparen)
 		ret := p.tryParseType()
 		ordinal, tags := p.parseTagString(false)
+		end := p.end
 		p.expect(semicol)
 
 		if methodName != "" {
 			s.Methods = append(s.Methods, Method{
+				pos:     pos,
+				end:     end,
 				Doc:     doc,
 				Name:    methodName,
 				Args:    args,
@@ -286,14 +310,14 @@ func (p *parser) parseService() *Service {
 	p.expect(rbrace)
 	p.expect(semicol)
 
-	p.register(s.Name, s)
+	p.declare(s.Name, namePos, s)
 	return s
 }
 
 func (p *parser) parseTagString(negativeOrdinals bool) (int64, Tags) {
 	switch {
 	case p.tok == semicol:
-		p.errorf("missing tag string")
+		p.errorf(CodeMissingTag, "missing tag string")
 		return 0, nil
 	case p.tok != strlit:
 		p.expect(strlit)
@@ -313,7 +337,7 @@ func (p *parser) parseTagString(negativeOrdinals bool) (int64, Tags) {
 	}
 	ordinal, err := strconv.ParseInt(lit[:i], 10, 64)
 	if err != nil || (!negativeOrdinals && ordinal <= 0) {
-		p.errorf("invalid ordinal %q", lit[:i])
+		p.errorf(CodeInvalidOrdinal, "invalid ordinal %q", lit[:i])
 	}
 	lit = lit[i:]
 	i = 0
@@ -341,7 +365,7 @@ func (p *parser) parseTagString(negativeOrdinals bool) (int64, Tags) {
 			continue
 		}
 		if i == 0 || i+1 == len(lit) || lit[i] != ':' || lit[i+1] != '"' {
-			p.errorf("invalid tag format %s", p.lit)
+			p.errorf(CodeInvalidTag, "invalid tag format %s", p.lit)
 			break
 		}
 		key := lit[:i]
@@ -356,7 +380,7 @@ func (p *parser) parseTagString(negativeOrdinals bool) (int64, Tags) {
 			i++
 		}
 		if i >= len(lit) {
-			p.errorf("tag value string not closed for %q", key)
+			p.errorf(CodeInvalidTag, "tag value string not closed for %q", key)
 			break
 		}
 		tags[key] = lit[1:i]
@@ -370,7 +394,7 @@ func (p *parser) parseType() Type {
 	typ := p.tryParseType()
 	if typ == nil {
 		if p.tok != invalid { // invalid already reported an error
-			p.errorf("unexpected token %q", p.lit)
+			p.errorf(CodeUnexpectedToken, "unexpected token %q", p.lit)
 		}
 		p.next()
 	}
@@ -384,7 +408,7 @@ func (p *parser) tryParseType() Type {
 		var size uint64
 		if p.tok == intlit {
 			if sz, err := strconv.ParseUint(p.lit, 10, 32); err != nil || sz <= 0 {
-				p.errorf("invalid array size %s", p.lit)
+				p.errorf(CodeInvalidArraySize, "invalid array size %s", p.lit)
 			} else {
 				size = sz
 			}
@@ -393,7 +417,7 @@ func (p *parser) tryParseType() Type {
 		p.expect(rbrack)
 		val := p.parseType()
 		if _, ok := val.(*Array); ok {
-			p.errorf("array type []%s not supported", val.Name())
+			p.errorf(CodeUnsupportedType, "array type []%s not supported", val.Name())
 		}
 		return &Array{Size: int(size), Value: val}
 
@@ -405,7 +429,7 @@ func (p *parser) tryParseType() Type {
 		_, isMap := val.(*Map)
 		_, isRaw := val.(*Raw)
 		if isPtr || isArr || isMap || isRaw {
-			p.errorf("pointer type *%s not supported", val.Name())
+			p.errorf(CodeUnsupportedType, "pointer type *%s not supported", val.Name())
 		}
 		return &Pointer{Value: val}
 
@@ -418,7 +442,7 @@ func (p *parser) tryParseType() Type {
 		return &Map{Key: key, Value: val}
 
 	case ident:
-		name := p.lit
+		name, pos := p.lit, p.pos
 		p.next()
 		if p.tok == period {
 			p.next()
@@ -427,7 +451,11 @@ func (p *parser) tryParseType() Type {
 
 			name += "." + lit
 		}
-		return p.resolve(name)
+		typ := p.resolve(name, pos)
+		if dt, ok := typ.(*DefinedType); ok {
+			p.refs = append(p.refs, Ident{Name: name, Pos: pos, End: endPos(pos, name), Type: dt})
+		}
+		return typ
 
 	default:
 		return nil
@@ -445,7 +473,7 @@ func (p *parser) parseIdent() string {
 	return ident
 }
 
-func (p *parser) resolve(ident string) Type {
+func (p *parser) resolve(ident string, pos Pos) Type {
 	switch ident {
 	case "bool":
 		return &Bool{}
@@ -488,7 +516,7 @@ func (p *parser) resolve(ident string) Type {
 		typ := p.register(ident, nil)
 		p.unresolved = append(p.unresolved, unresolved{
 			typ: typ,
-			pos: p.pos,
+			pos: pos,
 		})
 		return typ
 	}
@@ -503,38 +531,51 @@ func (p *parser) register(name string, decl Decl) *DefinedType {
 	case typ.Decl == nil:
 		typ.Decl = decl
 	default:
-		p.errorf("type %s redeclared (see position %s)", name, typ.Decl.Pos())
+		p.errorf(CodeRedeclaredType, "type %s redeclared (see position %s)", name, typ.Decl.Pos())
 	}
 	return typ
 }
 
+// declare registers the declaration and records its declaring identifier.
+func (p *parser) declare(name string, pos Pos, decl Decl) {
+	typ := p.register(name, decl)
+	if name != "" {
+		p.refs = append(p.refs, Ident{Name: name, Pos: pos, End: endPos(pos, name), Type: typ, Declaring: true})
+	}
+}
+
 func (p *parser) expect(tok token) {
 	if p.tok != tok {
 		switch {
 		case p.tok == invalid:
 			p.scanError()
 		case p.lit == "\n":
-			p.errorf("unexpected newline (%s expected)", tok)
+			p.errorf(CodeUnexpectedToken, "unexpected newline (%s expected)", tok)
 		default:
-			p.errorf("unexpected token %q (%s expected)", p.lit, tok)
+			p.errorf(CodeUnexpectedToken, "unexpected token %q (%s expected)", p.lit, tok)
 		}
 	}
 	p.next()
 }
 
 func (p *parser) scanError() {
-	p.errorf(p.t.Err().Error())
+	p.errorf(CodeInvalidToken, "%s", p.t.Err().Error())
+}
+
+func (p *parser) errorf(code ErrorCode, format string, args ...interface{}) {
+	p.errorfpos(p.pos, endPos(p.pos, p.lit), code, format, args...)
 }
 
-func (p *parser) errorf(format string, args ...interface{}) {
-	p.errorfpos(p.pos, format, args...)
+func (p *parser) errorfpos(pos Pos, end Pos, code ErrorCode, format string, args ...interface{}) {
+	p.errs.add(pos, end, code, fmt.Sprintf(format, args...))
 }
 
-func (p *parser) errorfpos(pos Pos, format string, args ...interface{}) {
-	p.errs.add(pos, fmt.Sprintf(format, args...))
+func (p *parser) reportf(pos Pos, end Pos, code ErrorCode, format string, args ...interface{}) {
+	p.errorfpos(pos, end, code, format, args...)
 }
 
 func (p *parser) next() {
+	p.end = endPos(p.pos, p.lit)
 	p.tok, p.lit, p.pos = p.t.Next()
 	p.scanDocComment()
 }
@@ -89,11 +89,13 @@ func TestParse(t *testing.T) {
 	imports := [...]*Import{
 		{
 			pos:  Pos{Line: 5, Column: 2},
+			end:  Pos{Line: 5, Column: 26},
 			Path: "external1.mprot",
 			Name: "external1",
 		},
 		{
 			pos:  Pos{Line: 6, Column: 2},
+			end:  Pos{Line: 6, Column: 30},
 			Path: "external2.mprot",
 			Name: "ext",
 		},
@@ -102,18 +104,21 @@ func TestParse(t *testing.T) {
 	consts := [...]*Const{
 		{
 			pos:   Pos{Line: 10, Column: 2},
+			end:   Pos{Line: 10, Column: 10},
 			Name:  "CS",
 			Type:  &String{},
 			Value: "foo",
 		},
 		{
 			pos:   Pos{Line: 11, Column: 2},
+			end:   Pos{Line: 11, Column: 10},
 			Name:  "CI",
 			Type:  &Int{Bits: 64},
 			Value: "7",
 		},
 		{
 			pos:   Pos{Line: 13, Column: 2},
+			end:   Pos{Line: 13, Column: 10},
 			Doc:   []string{"constant doc comment"},
 			Name:  "CF",
 			Type:  &Float{Bits: 64},
@@ -124,12 +129,13 @@ func TestParse(t *testing.T) {
 	enums := [...]*Enum{
 		{
 			pos:  Pos{Line: 54, Column: 2},
+			end:  Pos{Line: 54, Column: 8},
 			Doc:  []string{"my enum", "doc comment"},
 			Name: "E",
 			Enumerators: []Enumerator{
-				{Name: "Val1", Value: 1, Tags: Tags{}},
-				{Name: "Val2", Value: 2, Tags: Tags{}},
-				{Name: "Val3", Value: 3, Tags: Tags{}},
+				{pos: Pos{Line: 55, Column: 3}, end: Pos{Line: 55, Column: 11}, Name: "Val1", Value: 1, Tags: Tags{}},
+				{pos: Pos{Line: 56, Column: 3}, end: Pos{Line: 56, Column: 11}, Name: "Val2", Value: 2, Tags: Tags{}},
+				{pos: Pos{Line: 57, Column: 3}, end: Pos{Line: 57, Column: 11}, Name: "Val3", Value: 3, Tags: Tags{}},
 			},
 		},
 	}
@@ -137,36 +143,37 @@ func TestParse(t *testing.T) {
 	structs := [...]*Struct{
 		{
 			pos:  Pos{Line: 20, Column: 2},
+			end:  Pos{Line: 20, Column: 10},
 			Doc:  []string{"\t\tmy struct", "\t\tdoc comment", "", "another doc line"},
 			Name: "S",
 			Fields: []Field{
-				{Name: "B", Type: &Bool{}, Ordinal: 1, Tags: Tags{"tagkey": "tagval"}},
-				{Name: "I", Type: &Int{}, Ordinal: 2, Tags: Tags{"tagkey": "f\\too"}},
-				{Name: "I8", Type: &Int{Bits: 8}, Ordinal: 3, Tags: Tags{"tagkey": ""}},
-				{Name: "I16", Type: &Int{Bits: 16}, Ordinal: 4, Tags: Tags{}},
-				{Name: "I32", Type: &Int{Bits: 32}, Ordinal: 5, Tags: Tags{}},
-				{Name: "I64", Type: &Int{Bits: 64}, Ordinal: 6, Tags: Tags{}},
-				{Name: "UI", Type: &Int{Unsigned: true}, Ordinal: 7, Tags: Tags{}},
-				{Name: "UI8", Type: &Int{Bits: 8, Unsigned: true}, Ordinal: 8, Tags: Tags{}},
-				{Name: "UI16", Type: &Int{Bits: 16, Unsigned: true}, Ordinal: 9, Tags: Tags{}},
-				{Name: "UI32", Type: &Int{Bits: 32, Unsigned: true}, Ordinal: 10, Tags: Tags{}},
-				{Name: "UI64", Type: &Int{Bits: 64, Unsigned: true}, Ordinal: 11, Tags: Tags{}},
-				{Name: "F32", Type: &Float{Bits: 32}, Ordinal: 12, Tags: Tags{}},
-				{Name: "F64", Type: &Float{Bits: 64}, Ordinal: 13, Tags: Tags{}},
-				{Name: "S", Type: &String{}, Ordinal: 14, Tags: Tags{}},
-				{Name: "Bin", Type: &Bytes{}, Ordinal: 15, Tags: Tags{}},
-				{Name: "Raw", Type: &Raw{}, Ordinal: 16, Tags: Tags{}},
-				{Name: "AI", Type: &Array{Value: &Int{}}, Ordinal: 17, Tags: Tags{}},
-				{Name: "AF", Type: &Array{Value: &Float{Bits: 32}}, Ordinal: 18, Tags: Tags{}},
-				{Name: "AS", Type: &Array{Size: 2, Value: &String{}}, Ordinal: 19, Tags: Tags{}},
-				{Name: "MSS", Type: &Map{Key: &String{}, Value: &String{}}, Ordinal: 20, Tags: Tags{}},
-				{Name: "MFI", Type: &Map{Key: &Float{Bits: 64}, Value: &Int{}}, Ordinal: 21, Tags: Tags{}},
-				{Name: "T", Type: &Time{}, Ordinal: 22, Tags: Tags{}},
-				{Name: "PS", Type: &Pointer{Value: &String{}}, Ordinal: 23, Tags: Tags{}},
-				{Name: "PE", Type: &Pointer{Value: &DefinedType{name: "E", Decl: enums[0]}}, Ordinal: 24, Tags: Tags{}},
-				{Name: "E", Type: &DefinedType{name: "E", Decl: enums[0]}, Ordinal: 25, Tags: Tags{}},
-				{Name: "X1", Type: &DefinedType{pkg: "external1", name: "X", Decl: imports[0]}, Ordinal: 26, Tags: Tags{}},
-				{Name: "X2", Type: &DefinedType{pkg: "ext", name: "X", Decl: imports[1]}, Ordinal: 27, Tags: Tags{}},
+				{pos: Pos{Line: 21, Column: 3}, end: Pos{Line: 21, Column: 48}, Name: "B", Type: &Bool{}, Ordinal: 1, Tags: Tags{"tagkey": "tagval"}},
+				{pos: Pos{Line: 22, Column: 3}, end: Pos{Line: 22, Column: 46}, Name: "I", Type: &Int{}, Ordinal: 2, Tags: Tags{"tagkey": "f\\too"}},
+				{pos: Pos{Line: 23, Column: 3}, end: Pos{Line: 23, Column: 38}, Name: "I8", Type: &Int{Bits: 8}, Ordinal: 3, Tags: Tags{"tagkey": ""}},
+				{pos: Pos{Line: 24, Column: 3}, end: Pos{Line: 24, Column: 31}, Name: "I16", Type: &Int{Bits: 16}, Ordinal: 4, Tags: Tags{}},
+				{pos: Pos{Line: 25, Column: 3}, end: Pos{Line: 25, Column: 31}, Name: "I32", Type: &Int{Bits: 32}, Ordinal: 5, Tags: Tags{}},
+				{pos: Pos{Line: 26, Column: 3}, end: Pos{Line: 26, Column: 31}, Name: "I64", Type: &Int{Bits: 64}, Ordinal: 6, Tags: Tags{}},
+				{pos: Pos{Line: 27, Column: 3}, end: Pos{Line: 27, Column: 31}, Name: "UI", Type: &Int{Unsigned: true}, Ordinal: 7, Tags: Tags{}},
+				{pos: Pos{Line: 28, Column: 3}, end: Pos{Line: 28, Column: 31}, Name: "UI8", Type: &Int{Bits: 8, Unsigned: true}, Ordinal: 8, Tags: Tags{}},
+				{pos: Pos{Line: 29, Column: 3}, end: Pos{Line: 29, Column: 31}, Name: "UI16", Type: &Int{Bits: 16, Unsigned: true}, Ordinal: 9, Tags: Tags{}},
+				{pos: Pos{Line: 30, Column: 3}, end: Pos{Line: 30, Column: 31}, Name: "UI32", Type: &Int{Bits: 32, Unsigned: true}, Ordinal: 10, Tags: Tags{}},
+				{pos: Pos{Line: 31, Column: 3}, end: Pos{Line: 31, Column: 31}, Name: "UI64", Type: &Int{Bits: 64, Unsigned: true}, Ordinal: 11, Tags: Tags{}},
+				{pos: Pos{Line: 32, Column: 3}, end: Pos{Line: 32, Column: 31}, Name: "F32", Type: &Float{Bits: 32}, Ordinal: 12, Tags: Tags{}},
+				{pos: Pos{Line: 33, Column: 3}, end: Pos{Line: 33, Column: 31}, Name: "F64", Type: &Float{Bits: 64}, Ordinal: 13, Tags: Tags{}},
+				{pos: Pos{Line: 34, Column: 3}, end: Pos{Line: 34, Column: 31}, Name: "S", Type: &String{}, Ordinal: 14, Tags: Tags{}},
+				{pos: Pos{Line: 35, Column: 3}, end: Pos{Line: 35, Column: 31}, Name: "Bin", Type: &Bytes{}, Ordinal: 15, Tags: Tags{}},
+				{pos: Pos{Line: 36, Column: 3}, end: Pos{Line: 36, Column: 31}, Name: "Raw", Type: &Raw{}, Ordinal: 16, Tags: Tags{}},
+				{pos: Pos{Line: 37, Column: 3}, end: Pos{Line: 37, Column: 31}, Name: "AI", Type: &Array{Value: &Int{}}, Ordinal: 17, Tags: Tags{}},
+				{pos: Pos{Line: 38, Column: 3}, end: Pos{Line: 38, Column: 31}, Name: "AF", Type: &Array{Value: &Float{Bits: 32}}, Ordinal: 18, Tags: Tags{}},
+				{pos: Pos{Line: 39, Column: 3}, end: Pos{Line: 39, Column: 31}, Name: "AS", Type: &Array{Size: 2, Value: &String{}}, Ordinal: 19, Tags: Tags{}},
+				{pos: Pos{Line: 40, Column: 3}, end: Pos{Line: 40, Column: 31}, Name: "MSS", Type: &Map{Key: &String{}, Value: &String{}}, Ordinal: 20, Tags: Tags{}},
+				{pos: Pos{Line: 41, Column: 3}, end: Pos{Line: 41, Column: 31}, Name: "MFI", Type: &Map{Key: &Float{Bits: 64}, Value: &Int{}}, Ordinal: 21, Tags: Tags{}},
+				{pos: Pos{Line: 42, Column: 3}, end: Pos{Line: 42, Column: 31}, Name: "T", Type: &Time{}, Ordinal: 22, Tags: Tags{}},
+				{pos: Pos{Line: 43, Column: 3}, end: Pos{Line: 43, Column: 31}, Name: "PS", Type: &Pointer{Value: &String{}}, Ordinal: 23, Tags: Tags{}},
+				{pos: Pos{Line: 44, Column: 3}, end: Pos{Line: 44, Column: 31}, Name: "PE", Type: &Pointer{Value: &DefinedType{name: "E", Decl: enums[0]}}, Ordinal: 24, Tags: Tags{}},
+				{pos: Pos{Line: 45, Column: 3}, end: Pos{Line: 45, Column: 31}, Name: "E", Type: &DefinedType{name: "E", Decl: enums[0]}, Ordinal: 25, Tags: Tags{}},
+				{pos: Pos{Line: 46, Column: 3}, end: Pos{Line: 46, Column: 31}, Name: "X1", Type: &DefinedType{pkg: "external1", name: "X", Decl: imports[0]}, Ordinal: 26, Tags: Tags{}},
+				{pos: Pos{Line: 47, Column: 3}, end: Pos{Line: 47, Column: 31}, Name: "X2", Type: &DefinedType{pkg: "ext", name: "X", Decl: imports[1]}, Ordinal: 27, Tags: Tags{}},
 			},
 		},
 	}
@@ -174,13 +181,14 @@ func TestParse(t *testing.T) {
 	unions := [...]*Union{
 		{
 			pos:  Pos{Line: 61, Column: 2},
+			end:  Pos{Line: 61, Column: 9},
 			Doc:  []string{"my union doc comment"},
 			Name: "U",
 			Branches: []Branch{
-				{Type: &DefinedType{name: "S", Decl: structs[0]}, Ordinal: 1, Tags: Tags{}},
-				{Type: &DefinedType{name: "E", Decl: enums[0]}, Ordinal: 2, Tags: Tags{}},
-				{Type: &Array{Value: &DefinedType{name: "S", Decl: structs[0]}}, Ordinal: 3, Tags: Tags{}},
-				{Type: &Map{Key: &String{}, Value: &DefinedType{name: "S", Decl: structs[0]}}, Ordinal: 4, Tags: Tags{}},
+				{pos: Pos{Line: 62, Column: 3}, end: Pos{Line: 62, Column: 19}, Type: &DefinedType{name: "S", Decl: structs[0]}, Ordinal: 1, Tags: Tags{}},
+				{pos: Pos{Line: 63, Column: 3}, end: Pos{Line: 63, Column: 19}, Type: &DefinedType{name: "E", Decl: enums[0]}, Ordinal: 2, Tags: Tags{}},
+				{pos: Pos{Line: 64, Column: 3}, end: Pos{Line: 64, Column: 19}, Type: &Array{Value: &DefinedType{name: "S", Decl: structs[0]}}, Ordinal: 3, Tags: Tags{}},
+				{pos: Pos{Line: 65, Column: 3}, end: Pos{Line: 65, Column: 19}, Type: &Map{Key: &String{}, Value: &DefinedType{name: "S", Decl: structs[0]}}, Ordinal: 4, Tags: Tags{}},
 			},
 		},
 	}
@@ -188,21 +196,23 @@ func TestParse(t *testing.T) {
 	services := [...]*Service{
 		{
 			pos:  Pos{Line: 69, Column: 2},
+			end:  Pos{Line: 69, Column: 13},
 			Doc:  []string{"my service doc comment"},
 			Name: "Svc",
 			Methods: []Method{
-				{Doc: []string{"F1 doc"}, Name: "F1", Args: nil, Return: nil, Ordinal: 1, Tags: Tags{}},
-				{Name: "F2", Args: nil, Return: &Int{}, Ordinal: 2, Tags: Tags{}},
-				{Name: "F3", Args: []Type{&Bool{}}, Return: nil, Ordinal: 3, Tags: Tags{}},
-				{Name: "F4", Args: []Type{&Bytes{}}, Return: &DefinedType{name: "S", Decl: structs[0]}, Ordinal: 4, Tags: Tags{}},
-				{Name: "F5", Args: []Type{&DefinedType{pkg: "ext", name: "X", Decl: imports[1]}, &String{}, &Float{Bits: 32}}, Return: nil, Ordinal: 5, Tags: Tags{}},
-				{Name: "F6", Args: []Type{&Float{Bits: 64}, &Raw{}, &Bytes{}}, Return: &DefinedType{name: "E", Decl: enums[0]}, Ordinal: 6, Tags: Tags{}},
+				{pos: Pos{Line: 71, Column: 3}, end: Pos{Line: 71, Column: 34}, Doc: []string{"F1 doc"}, Name: "F1", Args: nil, Return: nil, Ordinal: 1, Tags: Tags{}},
+				{pos: Pos{Line: 72, Column: 3}, end: Pos{Line: 72, Column: 34}, Name: "F2", Args: nil, Return: &Int{}, Ordinal: 2, Tags: Tags{}},
+				{pos: Pos{Line: 73, Column: 3}, end: Pos{Line: 73, Column: 34}, Name: "F3", Args: []Type{&Bool{}}, Return: nil, Ordinal: 3, Tags: Tags{}},
+				{pos: Pos{Line: 74, Column: 3}, end: Pos{Line: 74, Column: 34}, Name: "F4", Args: []Type{&Bytes{}}, Return: &DefinedType{name: "S", Decl: structs[0]}, Ordinal: 4, Tags: Tags{}},
+				{pos: Pos{Line: 75, Column: 3}, end: Pos{Line: 75, Column: 34}, Name: "F5", Args: []Type{&DefinedType{pkg: "ext", name: "X", Decl: imports[1]}, &String{}, &Float{Bits: 32}}, Return: nil, Ordinal: 5, Tags: Tags{}},
+				{pos: Pos{Line: 76, Column: 3}, end: Pos{Line: 76, Column: 34}, Name: "F6", Args: []Type{&Float{Bits: 64}, &Raw{}, &Bytes{}}, Return: &DefinedType{name: "E", Decl: enums[0]}, Ordinal: 6, Tags: Tags{}},
 			},
 		},
 	}
 
 	expectedPackage := &Package{
 		pos:  Pos{Line: 3, Column: 2},
+		end:  Pos{Line: 3, Column: 13},
 		Name: "foo",
 	}
 
@@ -394,3 +404,44 @@ func TestParserErrors(t *testing.T) {
 		}
 	}
 }
+
+func TestParserUndefinedQualifier(t *testing.T) {
+	const input = "package foo\n\nimport \"bar.mprot\"\n\nstruct S {\n\tA bar.X \"1\"\n\tB baz.Y \"2\"\n}\n"
+
+	var p parser
+	_, err := p.Parse(strings.NewReader(input), "")
+	errs, ok := err.(ErrorList)
+	if !ok || len(errs) != 1 {
+		t.Fatalf("unexpected errors: %v", err)
+	}
+	if errs[0].Code != CodeUndefinedType || errs[0].Text != "undefined type baz.Y" || errs[0].Pos.Line != 7 {
+		t.Errorf("unexpected error: %+v", errs[0])
+	}
+}
+
+func TestParserMemberErrorPos(t *testing.T) {
+	const input = "package foo\n\n" +
+		"enum E {\n\tA \"1\"\n\tA \"2\"\n}\n\n" +
+		"struct S {\n\tF int \"1\"\n\tF string \"2\"\n}\n\n" +
+		"union U {\n\tS \"1\"\n\tS \"2\"\n}\n\n" +
+		"service Svc {\n\tM() \"1\"\n\tM(int) int \"2\"\n}\n"
+
+	expected := []Error{
+		{Pos: Pos{Line: 5, Column: 2}, End: Pos{Line: 5, Column: 7}, Code: CodeDuplicateEnumerator},
+		{Pos: Pos{Line: 10, Column: 2}, End: Pos{Line: 10, Column: 14}, Code: CodeDuplicateField},
+		{Pos: Pos{Line: 15, Column: 2}, End: Pos{Line: 15, Column: 7}, Code: CodeDuplicateBranch},
+		{Pos: Pos{Line: 20, Column: 2}, End: Pos{Line: 20, Column: 16}, Code: CodeDuplicateMethod},
+	}
+
+	var p parser
+	_, err := p.Parse(strings.NewReader(input), "")
+	errs, ok := err.(ErrorList)
+	if !ok || len(errs) != len(expected) {
+		t.Fatalf("unexpected errors: %v", err)
+	}
+	for i, err := range errs {
+		if err.Pos != expected[i].Pos || err.End != expected[i].End || err.Code != expected[i].Code {
+			t.Errorf("unexpected error %d: %+v", i, err)
+		}
+	}
+}
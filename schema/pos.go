@@ -17,3 +17,16 @@ func (p Pos) String() string {
 	}
 	return prefix + fmt.Sprintf("%d:%d", p.Line, p.Column)
 }
+
+// endPos returns the position after the given literal, which starts at pos.
+func endPos(pos Pos, lit string) Pos {
+	for _, ch := range lit {
+		if ch == '\n' {
+			pos.Line++
+			pos.Column = 1
+		} else {
+			pos.Column++
+		}
+	}
+	return pos
+}
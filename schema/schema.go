@@ -0,0 +1,241 @@
+package schema
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/mprot/mprotc/internal/parallel"
+)
+
+// Schema defines a whole mprot schema, including all files.
+type Schema []*File
+
+// Parse parses an mprot schema, which is defined in the files specified
+// by the given glob patterns. The given glob patterns are interpreted as
+// relative to the given root directory.
+//
+// The imports of each file are searched relative to the importing file first
+// and then in the given include directories in order. The first existing file
+// is used. An import, which cannot be found, is reported as an error.
+func Parse(rootDir string, globPatterns []string, includeDirs ...string) (Schema, error) {
+	return Config{IncludeDirs: includeDirs}.Parse(rootDir, globPatterns)
+}
+
+// ParseFS parses an mprot schema from the file system fsys. The schema is
+// defined in the files specified by the given glob patterns, which are
+// interpreted as relative to the root of fsys. Imports are resolved as for
+// Parse, where the include directories are slash-separated paths in fsys.
+func ParseFS(fsys fs.FS, globPatterns []string, includeDirs ...string) (Schema, error) {
+	return Config{IncludeDirs: includeDirs}.ParseFS(fsys, globPatterns)
+}
+
+// Config holds the configuration for parsing a schema. The zero value is a
+// valid configuration.
+type Config struct {
+	// IncludeDirs are searched in order for imported files, which cannot be
+	// found relative to the importing file.
+	IncludeDirs []string
+
+	// Jobs is the maximum number of files parsed concurrently. If it is zero
+	// or negative, runtime.GOMAXPROCS(0) is used.
+	Jobs int
+}
+
+// Parse parses an mprot schema like the Parse function, but with the options
+// of the configuration.
+func (c Config) Parse(rootDir string, globPatterns []string) (Schema, error) {
+	return c.parse(os.DirFS(rootDir), rootDir, globPatterns, newOSImportResolver(rootDir, c.IncludeDirs))
+}
+
+// ParseFS parses an mprot schema like the ParseFS function, but with the
+// options of the configuration.
+func (c Config) ParseFS(fsys fs.FS, globPatterns []string) (Schema, error) {
+	return c.parse(fsys, "", globPatterns, newFSImportResolver(fsys, c.IncludeDirs))
+}
+
+// ParseSources parses an mprot schema from in-memory sources. The sources
+// map the slash-separated file names to the contents of the schema files.
+// Imports are not resolved.
+func ParseSources(sources map[string]string) (Schema, error) {
+	filenames := make([]string, 0, len(sources))
+	for filename := range sources {
+		filenames = append(filenames, filename)
+	}
+	sort.Strings(filenames)
+
+	s := make(Schema, 0, len(filenames))
+	p := parser{}
+	errs := ErrorList{}
+	for _, filename := range filenames {
+		f, err := p.Parse(strings.NewReader(sources[filename]), filename)
+		if err := errs.collect(err); err != nil {
+			return nil, err
+		}
+		s = append(s, f)
+	}
+	errs.sort()
+	return s, errs.err()
+}
+
+// parse parses the files matching the glob patterns concurrently. The files
+// and errors are collected in the order of the sorted filenames, so the result
+// does not depend on the scheduling of the goroutines.
+func (c Config) parse(fsys fs.FS, rootDir string, globPatterns []string, imports *importResolver) (Schema, error) {
+	fset, err := glob(fsys, globPatterns)
+	if err != nil {
+		return nil, err
+	}
+
+	type result struct {
+		file *File
+		errs ErrorList
+		err  error
+	}
+
+	filenames := fset.filenames()
+	results := make([]result, len(filenames))
+	parallel.Each(c.Jobs, len(filenames), func(idx int) {
+		filename := filenames[idx]
+		res := &results[idx]
+
+		p := parser{}
+		res.file, res.err = parseFile(&p, fsys, filename, displayName(rootDir, filename))
+		if res.err = res.errs.collect(res.err); res.err == nil {
+			imports.resolve(res.file, filename, &res.errs)
+			res.file.Name = filepath.FromSlash(filename)
+		}
+	})
+
+	s := make(Schema, 0, len(filenames))
+	errs := ErrorList{}
+	for _, res := range results {
+		if res.err != nil {
+			return nil, res.err
+		}
+		errs = errs.concat(res.errs)
+		s = append(s, res.file)
+	}
+	errs.sort()
+	return s, errs.err()
+}
+
+func parseFile(p *parser, fsys fs.FS, filename string, displayName string) (*File, error) {
+	f, err := fsys.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return p.Parse(f, displayName)
+}
+
+// displayName returns the name of a file, which is used for the reported
+// error positions.
+func displayName(rootDir string, filename string) string {
+	if rootDir == "" {
+		return filename
+	}
+	return filepath.Join(rootDir, filepath.FromSlash(filename))
+}
+
+// RemoveDeprecated removes all declarations which are marked as deprecated.
+func (s Schema) RemoveDeprecated() {
+	for _, file := range s {
+		file.RemoveDeprecated()
+	}
+}
+
+// File holds all the data for an mprot schema definition file.
+// The order of the constants, enums, structs, and unions depends
+// on the parser.
+type File struct {
+	Name    string
+	Doc     []string
+	Package *Package
+	Imports map[string]*Import // name => import
+	Decls   []Decl
+}
+
+// RemoveDeprecated removes all declarations which are marked as deprecated.
+func (f *File) RemoveDeprecated() {
+	usedImports := make(map[string]struct{})
+	for _, decl := range f.Decls {
+		switch decl := decl.(type) {
+		case *Enum:
+			idx := 0
+			for i := 0; i < len(decl.Enumerators); i++ {
+				if !decl.Enumerators[i].Tags.Deprecated() {
+					decl.Enumerators[idx] = decl.Enumerators[i]
+					idx++
+				}
+			}
+			decl.Enumerators = decl.Enumerators[:idx]
+
+		case *Struct:
+			idx := 0
+			for i := 0; i < len(decl.Fields); i++ {
+				if field := decl.Fields[i]; !field.Tags.Deprecated() {
+					decl.Fields[idx] = field
+					idx++
+					if pkg := importName(field.Type); pkg != "" {
+						usedImports[pkg] = struct{}{}
+					}
+				}
+			}
+			decl.Fields = decl.Fields[:idx]
+
+		case *Union:
+			idx := 0
+			for i := 0; i < len(decl.Branches); i++ {
+				if branch := decl.Branches[i]; !branch.Tags.Deprecated() {
+					decl.Branches[idx] = branch
+					idx++
+					if pkg := importName(branch.Type); pkg != "" {
+						usedImports[pkg] = struct{}{}
+					}
+				}
+			}
+			decl.Branches = decl.Branches[:idx]
+
+		case *Service:
+			idx := 0
+			for i := 0; i < len(decl.Methods); i++ {
+				if method := decl.Methods[i]; !method.Tags.Deprecated() {
+					decl.Methods[idx] = method
+					idx++
+					for _, arg := range method.Args {
+						if pkg := importName(arg); pkg != "" {
+							usedImports[pkg] = struct{}{}
+						}
+					}
+					if pkg := importName(method.Return); pkg != "" {
+						usedImports[pkg] = struct{}{}
+					}
+				}
+			}
+			decl.Methods = decl.Methods[:idx]
+		}
+	}
+
+	for importName := range f.Imports {
+		if _, used := usedImports[importName]; !used {
+			delete(f.Imports, importName)
+		}
+	}
+}
+
+func (f *File) validate(r errorReporter) {
+	for _, decl := range f.Decls {
+		decl.validate(r)
+	}
+}
+
+func importName(typ Type) string {
+	if dt, ok := typ.(*DefinedType); ok && dt.Imported() {
+		return dt.pkg
+	}
+	return ""
+}
@@ -0,0 +1,127 @@
+package schema
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+)
+
+func TestParseFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"foo/a.mprot":     {Data: []byte("package foo\nstruct A {\n\tX int \"1\"\n}\n")},
+		"foo/bar/b.mprot": {Data: []byte("package bar\nenum B {\n\tX \"1\"\n}\n")},
+		"foo/c.txt":       {Data: []byte("no schema")},
+	}
+
+	s, err := ParseFS(fsys, []string{"**/*.mprot"})
+	if err != nil {
+		t.Fatalf("unexpected parsing error: %v", err)
+	}
+
+	expected := []string{filepath.FromSlash("foo/a.mprot"), filepath.FromSlash("foo/bar/b.mprot")}
+	if len(s) != len(expected) {
+		t.Fatalf("unexpected number of files: %d", len(s))
+	}
+	for i, f := range s {
+		if f.Name != expected[i] {
+			t.Errorf("unexpected file name: %s (expected %s)", f.Name, expected[i])
+		}
+	}
+}
+
+func TestParseSources(t *testing.T) {
+	s, err := ParseSources(map[string]string{
+		"b.mprot": "package foo\nstruct B {\n\tX Y \"1\"\n}\n",
+		"a.mprot": "package foo\nconst A = 1\n",
+	})
+	if err == nil {
+		t.Fatal("expected parsing error, got none")
+	}
+
+	errs, ok := err.(ErrorList)
+	switch {
+	case !ok:
+		t.Fatalf("unexpected error type: %T", err)
+	case len(errs) != 1:
+		t.Fatalf("unexpected number of errors: %d", len(errs))
+	case errs[0].Error() != "b.mprot:3:4: undefined type Y":
+		t.Errorf("unexpected error: %v", errs[0])
+	}
+
+	if len(s) != 2 || s[0].Name != "a.mprot" || s[1].Name != "b.mprot" {
+		t.Errorf("unexpected files: %v", s)
+	}
+}
+
+func TestParseFSIncludes(t *testing.T) {
+	fsys := fstest.MapFS{
+		"api/a.mprot":            {Data: []byte("package api\nimport \"b.mprot\"\nimport \"shared/c.mprot\"\n")},
+		"api/b.mprot":            {Data: []byte("package api\n")},
+		"api/shared/c.mprot":     {Data: []byte("package shared\n")},
+		"vendor/b.mprot":         {Data: []byte("package vendor\n")},
+		"vendor/shared/c.mprot":  {Data: []byte("package shared\n")},
+		"vendor2/shared/d.mprot": {Data: []byte("package shared\n")},
+	}
+
+	// imports relative to the importing file take precedence
+	s, err := ParseFS(fsys, []string{"api/a.mprot"}, "vendor")
+	if err != nil {
+		t.Fatalf("unexpected parsing error: %v", err)
+	}
+	for _, imp := range s[0].Imports {
+		if imp.IncludeDir != "" {
+			t.Errorf("unexpected include directory for %q: %s", imp.Path, imp.IncludeDir)
+		}
+	}
+
+	// imports which are not found relative to the importing file are searched
+	// in the include directories in order
+	fsys["api/a.mprot"] = &fstest.MapFile{Data: []byte("package api\nimport \"shared/d.mprot\"\n")}
+	s, err = ParseFS(fsys, []string{"api/a.mprot"}, "vendor", "vendor2")
+	if err != nil {
+		t.Fatalf("unexpected parsing error: %v", err)
+	}
+	if imp := s[0].Imports["d"]; imp == nil || imp.IncludeDir != "vendor2" {
+		t.Errorf("unexpected import: %+v", imp)
+	}
+
+	// unresolved imports list all searched locations
+	_, err = ParseFS(fsys, []string{"api/a.mprot"}, "vendor")
+	expected := `api/a.mprot:2:1: import "shared/d.mprot" not found (searched ` +
+		filepath.FromSlash("api/shared/d.mprot") + ", " + filepath.FromSlash("vendor/shared/d.mprot") + ")"
+	if err == nil || err.Error() != expected {
+		t.Errorf("unexpected error: %v (expected %s)", err, expected)
+	}
+}
+
+func TestParseFSJobs(t *testing.T) {
+	fsys := fstest.MapFS{}
+	var expected []string
+	for i := 0; i < 20; i++ {
+		filename := fmt.Sprintf("f%02d.mprot", i)
+		fsys[filename] = &fstest.MapFile{Data: []byte("package foo\nstruct S {\n\tX Y \"1\"\n\tX int \"2\"\n}\n")}
+		expected = append(expected,
+			filename+":3:4: undefined type Y",
+			filename+":4:2: duplicate field X in struct S",
+		)
+	}
+
+	for _, jobs := range []int{1, 4, 20} {
+		for n := 0; n < 10; n++ {
+			_, err := Config{Jobs: jobs}.ParseFS(fsys, []string{"*.mprot"})
+			errs, ok := err.(ErrorList)
+			if !ok {
+				t.Fatalf("unexpected error with %d jobs: %v", jobs, err)
+			}
+			if len(errs) != len(expected) {
+				t.Fatalf("unexpected number of errors with %d jobs: %d", jobs, len(errs))
+			}
+			for i, err := range errs {
+				if err.Error() != expected[i] {
+					t.Fatalf("unexpected error %d with %d jobs: %v (expected %s)", i, jobs, err, expected[i])
+				}
+			}
+		}
+	}
+}
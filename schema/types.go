@@ -210,6 +210,17 @@ func (t *DefinedType) Imported() bool {
 	return t.pkg != ""
 }
 
+// Qualifier returns the import name of an imported type. For local types
+// an empty string will be returned.
+func (t *DefinedType) Qualifier() string {
+	return t.pkg
+}
+
+// LocalName returns the name of the type without the import qualifier.
+func (t *DefinedType) LocalName() string {
+	return t.name
+}
+
 // Name implements the Type interface.
 func (t *DefinedType) Name() string {
 	if t.pkg == "" {
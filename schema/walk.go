@@ -0,0 +1,72 @@
+package schema
+
+// TypeRef describes a reference to a type within a declaration of a schema
+// file. For nested types, the type reference describes the nested type, e.g.
+// the value type of an array.
+type TypeRef struct {
+	File *File
+	Decl Decl
+	Type Type
+}
+
+// Walk traverses all type references of the schema in depth-first order.
+// For each type reference fn will be called. If fn returns false, the
+// types nested in the referenced type are skipped.
+func Walk(s Schema, fn func(ref TypeRef) bool) {
+	for _, f := range s {
+		f.Walk(fn)
+	}
+}
+
+// Walk traverses all type references of the file in depth-first order.
+// See the package function Walk for details.
+func (f *File) Walk(fn func(ref TypeRef) bool) {
+	for _, decl := range f.Decls {
+		walkType := func(t Type) {
+			WalkType(t, func(t Type) bool {
+				return fn(TypeRef{File: f, Decl: decl, Type: t})
+			})
+		}
+
+		switch decl := decl.(type) {
+		case *Const:
+			walkType(decl.Type)
+		case *Struct:
+			for _, field := range decl.Fields {
+				walkType(field.Type)
+			}
+		case *Union:
+			for _, branch := range decl.Branches {
+				walkType(branch.Type)
+			}
+		case *Service:
+			for _, method := range decl.Methods {
+				for _, arg := range method.Args {
+					walkType(arg)
+				}
+				if method.Return != nil {
+					walkType(method.Return)
+				}
+			}
+		}
+	}
+}
+
+// WalkType traverses the given type and all of its nested types in
+// depth-first order. If fn returns false, the nested types of the
+// current type are skipped.
+func WalkType(t Type, fn func(t Type) bool) {
+	if t == nil || !fn(t) {
+		return
+	}
+
+	switch t := t.(type) {
+	case *Array:
+		WalkType(t.Value, fn)
+	case *Map:
+		WalkType(t.Key, fn)
+		WalkType(t.Value, fn)
+	case *Pointer:
+		WalkType(t.Value, fn)
+	}
+}
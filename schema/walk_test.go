@@ -0,0 +1,44 @@
+package schema
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWalk(t *testing.T) {
+	s, err := ParseSources(map[string]string{
+		"a.mprot": `
+			package foo
+			import "ext.mprot"
+			const C = 1
+			struct S {
+				A []*E            "1"
+				B map[string]ext.X "2"
+			}
+			enum E {
+				X "1"
+			}
+			union U {
+				S "1"
+			}
+			service Svc {
+				F(S) E "1"
+			}
+		`,
+	})
+	if err != nil {
+		t.Fatalf("unexpected parsing error: %v", err)
+	}
+
+	var names []string
+	Walk(s, func(ref TypeRef) bool {
+		names = append(names, ref.Type.Name())
+		_, isMap := ref.Type.(*Map)
+		return !isMap
+	})
+
+	expected := []string{"int64", "[]*E", "*E", "E", "map[string]ext.X", "S", "S", "E"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("unexpected type references: %v", names)
+	}
+}